@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// bearerToken returns the bearer token in r's Authorization header, or an
+// empty string if there is none.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return strings.TrimSpace(h[len(prefix):])
+	}
+	return ""
+}
+
+// authenticatedUser returns the user that r is authenticated as, or nil if r
+// is not authenticated.
+func authenticatedUser(r *http.Request) (*thesrc.User, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	return store.Users.Authenticate(token)
+}
+
+var (
+	errAuthRequired = &statusError{http.StatusUnauthorized, thesrc.ErrorCodeUnauthorized, errors.New("authentication required")}
+	errForbidden    = &statusError{http.StatusForbidden, thesrc.ErrorCodeForbidden, errors.New("permission denied")}
+)
+
+// requireUser returns the user that r is authenticated as, or errAuthRequired
+// if r is not authenticated.
+func requireUser(r *http.Request) (*thesrc.User, error) {
+	user, err := authenticatedUser(r)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errAuthRequired
+	}
+	return user, nil
+}
+
+// requireAdmin returns the user that r is authenticated as, or
+// errAuthRequired if r is not authenticated, or errForbidden if the user is not
+// an admin.
+func requireAdmin(r *http.Request) (*thesrc.User, error) {
+	user, err := requireUser(r)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Admin {
+		return nil, errForbidden
+	}
+	return user, nil
+}
+
+// canModifyPost returns whether user may update or delete post.
+func canModifyPost(user *thesrc.User, post *thesrc.Post) bool {
+	return user.Admin || (post.AuthorUserID != 0 && post.AuthorUserID == user.ID)
+}
+
+// canModifyComment returns whether user may delete comment.
+func canModifyComment(user *thesrc.User, comment *thesrc.Comment) bool {
+	return user.Admin || (comment.AuthorUserID != 0 && comment.AuthorUserID == user.ID)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// commentIDs returns the post ID and comment ID in the route variables of r.
+func commentIDs(r *http.Request) (postID, id int, err error) {
+	postID, err = strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return 0, 0, badRequest(err)
+	}
+	id, err = strconv.Atoi(mux.Vars(r)["CommentID"])
+	if err != nil {
+		return 0, 0, badRequest(err)
+	}
+	return postID, id, nil
+}
+
+func serveComment(w http.ResponseWriter, r *http.Request) error {
+	postID, id, err := commentIDs(r)
+	if err != nil {
+		return err
+	}
+
+	comment, err := store.Comments.Get(postID, id)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, comment)
+}
+
+func serveComments(w http.ResponseWriter, r *http.Request) error {
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return badRequest(err)
+	}
+
+	if _, err := store.Posts.Get(postID); err != nil {
+		return err
+	}
+
+	comments, err := store.Comments.List(postID)
+	if err != nil {
+		return err
+	}
+	if comments == nil {
+		comments = []*thesrc.Comment{}
+	}
+
+	return writeJSON(w, comments)
+}
+
+func serveSubmitComment(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return badRequest(err)
+	}
+
+	var comment thesrc.Comment
+	err = json.NewDecoder(r.Body).Decode(&comment)
+	if err != nil {
+		return badRequest(err)
+	}
+	comment.ID = 0
+	comment.PostID = postID
+	comment.AuthorUserID = user.ID
+	comment.AuthorLogin = user.Login
+	comment.SubmittedAt = time.Now()
+
+	if strings.TrimSpace(comment.Body) == "" {
+		return invalid(errors.New("comment body must not be empty"))
+	}
+
+	if _, err := store.Posts.Get(postID); err != nil {
+		return err
+	}
+	if comment.ParentID != 0 {
+		if _, err := store.Comments.Get(postID, comment.ParentID); err == thesrc.ErrCommentNotFound {
+			return invalid(errors.New("parent comment not found on this post"))
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if err := store.Comments.Submit(&comment); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, comment)
+}
+
+func serveDeleteComment(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	postID, id, err := commentIDs(r)
+	if err != nil {
+		return err
+	}
+
+	comment, err := store.Comments.Get(postID, id)
+	if err != nil {
+		return err
+	}
+	if !canModifyComment(user, comment) {
+		return errForbidden
+	}
+
+	if err := store.Comments.Delete(postID, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
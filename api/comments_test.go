@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestComments_List(t *testing.T) {
+	setup()
+
+	wantComments := []*thesrc.Comment{{ID: 2, PostID: 1}, {ID: 3, PostID: 1, ParentID: 2}}
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	calledList := false
+	store.Comments.(*thesrc.MockCommentsService).List_ = func(postID int) ([]*thesrc.Comment, error) {
+		if postID != 1 {
+			t.Errorf("wanted request for post %d but got %d", 1, postID)
+		}
+		calledList = true
+		return wantComments, nil
+	}
+
+	comments, err := apiClient.Comments.List(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantComments, &comments) {
+		t.Errorf("got comments %+v but wanted comments %+v", comments, wantComments)
+	}
+}
+
+func TestComment_notFound(t *testing.T) {
+	setup()
+
+	store.Comments.(*thesrc.MockCommentsService).Get_ = func(postID, id int) (*thesrc.Comment, error) {
+		return nil, thesrc.ErrCommentNotFound
+	}
+
+	_, err := apiClient.Comments.Get(1, 2)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Fatalf("got error %v, want HTTP 404", err)
+	}
+}
+
+func TestComment_Submit(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	store.Comments.(*thesrc.MockCommentsService).Get_ = func(postID, id int) (*thesrc.Comment, error) {
+		return &thesrc.Comment{ID: id, PostID: postID}, nil
+	}
+	calledSubmit := false
+	store.Comments.(*thesrc.MockCommentsService).Submit_ = func(comment *thesrc.Comment) error {
+		if comment.PostID != 1 || comment.ParentID != 2 || comment.AuthorUserID != 7 || comment.Body != "b" {
+			t.Errorf("got comment %+v", comment)
+		}
+		comment.ID = 3
+		calledSubmit = true
+		return nil
+	}
+
+	comment := &thesrc.Comment{PostID: 1, ParentID: 2, AuthorUserID: 123, Body: "b"}
+	if err := authedClient(&thesrc.User{ID: 7, Login: "u"}).Comments.Submit(comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledSubmit {
+		t.Error("!calledSubmit")
+	}
+	if comment.ID != 3 || comment.AuthorLogin != "u" {
+		t.Errorf("got comment %+v, want ID 3 and AuthorLogin u", comment)
+	}
+}
+
+func TestComment_Submit_badParent(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id}, nil
+	}
+	store.Comments.(*thesrc.MockCommentsService).Get_ = func(postID, id int) (*thesrc.Comment, error) {
+		return nil, thesrc.ErrCommentNotFound
+	}
+
+	err := authedClient(&thesrc.User{ID: 7}).Comments.Submit(&thesrc.Comment{PostID: 1, ParentID: 2, Body: "b"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Fatalf("got error %v, want HTTP 422", err)
+	}
+}
+
+func TestComment_Submit_unauthenticated(t *testing.T) {
+	setup()
+
+	err := apiClient.Comments.Submit(&thesrc.Comment{PostID: 1, Body: "b"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Fatalf("got error %v, want HTTP 401", err)
+	}
+}
+
+func TestComment_Delete_forbidden(t *testing.T) {
+	setup()
+
+	store.Comments.(*thesrc.MockCommentsService).Get_ = func(postID, id int) (*thesrc.Comment, error) {
+		return &thesrc.Comment{ID: id, PostID: postID, AuthorUserID: 8}, nil
+	}
+	store.Comments.(*thesrc.MockCommentsService).Delete_ = func(postID, id int) error {
+		t.Error("Delete called for another user's comment")
+		return nil
+	}
+
+	err := authedClient(&thesrc.User{ID: 7}).Comments.Delete(1, 2)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Fatalf("got error %v, want HTTP 403", err)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A statusError is an error that should be reported to API clients with a
+// specific HTTP status code and error code.
+type statusError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+// badRequest wraps err (typically a request decoding error) so that it is
+// reported as an HTTP 400 Bad Request.
+func badRequest(err error) error {
+	return &statusError{http.StatusBadRequest, thesrc.ErrorCodeBadRequest, err}
+}
+
+// invalid wraps err (typically a validation error) so that it is reported as
+// an HTTP 422 Unprocessable Entity.
+func invalid(err error) error {
+	return &statusError{http.StatusUnprocessableEntity, thesrc.ErrorCodeInvalid, err}
+}
+
+// errorStatuses maps known errors returned by the datastore to HTTP status
+// codes and error codes.
+var errorStatuses = map[error]*statusError{
+	thesrc.ErrPostNotFound:    {status: http.StatusNotFound, code: thesrc.ErrorCodeNotFound},
+	thesrc.ErrCommentNotFound: {status: http.StatusNotFound, code: thesrc.ErrorCodeNotFound},
+	thesrc.ErrUserNotFound:    {status: http.StatusNotFound, code: thesrc.ErrorCodeNotFound},
+	thesrc.ErrLoginTaken:      {status: http.StatusConflict, code: thesrc.ErrorCodeConflict},
+	thesrc.ErrBadCredentials:  {status: http.StatusUnauthorized, code: thesrc.ErrorCodeUnauthorized},
+	thesrc.ErrBadToken:        {status: http.StatusUnauthorized, code: thesrc.ErrorCodeUnauthorized},
+
+	thesrc.ErrAPITokenNotFound: {status: http.StatusNotFound, code: thesrc.ErrorCodeNotFound},
+}
+
+// errorStatus returns the HTTP status code and error code that should be
+// reported to API clients for err.
+func errorStatus(err error) (status int, code string) {
+	if e, ok := err.(*statusError); ok {
+		return e.status, e.code
+	}
+	if e, present := errorStatuses[err]; present {
+		return e.status, e.code
+	}
+	return http.StatusInternalServerError, thesrc.ErrorCodeInternal
+}
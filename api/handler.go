@@ -1,26 +1,51 @@
 package api
 
 import (
-	"fmt"
+	"encoding/json"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
+	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/datastore"
 	"sourcegraph.com/sourcegraph/thesrc/router"
 )
 
 var (
-	store         = datastore.NewDatastore(nil)
+	store         *datastore.Datastore
 	schemaDecoder = schema.NewDecoder()
 )
 
+// Handler returns the API's HTTP handler, which accesses the datastore
+// returned by datastore.NewDatastore(nil).
 func Handler() *mux.Router {
+	store = datastore.NewDatastore(nil)
+
 	m := router.API()
 	m.Get(router.Post).Handler(handler(servePost))
 	m.Get(router.SubmitPost).Handler(handler(serveSubmitPost))
 	m.Get(router.Posts).Handler(handler(servePosts))
+	m.Get(router.UpdatePost).Handler(handler(serveUpdatePost))
+	m.Get(router.DeletePost).Handler(handler(serveDeletePost))
+	m.Get(router.VotePost).Handler(handler(serveVotePost))
+	m.Get(router.Tags).Handler(handler(serveTags))
+	m.Get(router.PreviewLink).Handler(handler(servePreviewLink))
+	m.Get(router.Comment).Handler(handler(serveComment))
+	m.Get(router.Comments).Handler(handler(serveComments))
+	m.Get(router.SubmitComment).Handler(handler(serveSubmitComment))
+	m.Get(router.DeleteComment).Handler(handler(serveDeleteComment))
+	m.Get(router.User).Handler(handler(serveUser))
+	m.Get(router.Signup).Handler(handler(serveSignup))
+	m.Get(router.AuthenticatedUser).Handler(handler(serveAuthenticatedUser))
+	m.Get(router.LogIn).Handler(handler(serveLogIn))
+	m.Get(router.LogOut).Handler(handler(serveLogOut))
+	m.Get(router.APIToken).Handler(handler(serveAPIToken))
+	m.Get(router.APITokens).Handler(handler(serveAPITokens))
+	m.Get(router.CreateAPIToken).Handler(handler(serveCreateAPIToken))
+	m.Get(router.RevokeAPIToken).Handler(handler(serveRevokeAPIToken))
+	m.Get(router.ImportStates).Handler(handler(serveImportStates))
+	m.Get(router.UpdateImportState).Handler(handler(serveUpdateImportState))
 	return m
 }
 
@@ -29,8 +54,13 @@ type handler func(http.ResponseWriter, *http.Request) error
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := h(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "error: %s", err)
-		log.Println(err)
+		status, code := errorStatus(err)
+		if status >= 500 {
+			log.Println(err)
+		}
+
+		w.Header().Set("content-type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(&thesrc.ErrorResponse{Message: err.Error(), Code: code})
 	}
 }
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveImportStates(w http.ResponseWriter, r *http.Request) error {
+	if _, err := requireAdmin(r); err != nil {
+		return err
+	}
+
+	var opt thesrc.ImportStateListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return badRequest(err)
+	}
+
+	states, err := store.Imports.List(&opt)
+	if err != nil {
+		return err
+	}
+	if states == nil {
+		states = []*thesrc.ImportState{}
+	}
+
+	return writeJSON(w, states)
+}
+
+func serveUpdateImportState(w http.ResponseWriter, r *http.Request) error {
+	if _, err := requireAdmin(r); err != nil {
+		return err
+	}
+
+	var state thesrc.ImportState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		return badRequest(err)
+	}
+	if state.Site == "" {
+		return invalid(errors.New("site is required"))
+	}
+	if len(state.SeenIDs) > thesrc.MaxSeenIDs {
+		return invalid(fmt.Errorf("too many seen item IDs (at most %d)", thesrc.MaxSeenIDs))
+	}
+
+	if err := store.Imports.Update(&state); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
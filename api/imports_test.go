@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestImportStates_List(t *testing.T) {
+	setup()
+
+	wantStates := []*thesrc.ImportState{{Site: "hn/top", Failures: 1, SeenIDs: []string{"a"}}}
+
+	calledList := false
+	store.Imports.(*thesrc.MockImportsService).List_ = func(opt *thesrc.ImportStateListOptions) ([]*thesrc.ImportState, error) {
+		if want := "hn/top"; opt.Site != want {
+			t.Errorf("got Site %q, want %q", opt.Site, want)
+		}
+		calledList = true
+		return wantStates, nil
+	}
+
+	states, err := authedClient(&thesrc.User{ID: 1, Admin: true}).Imports.List(&thesrc.ImportStateListOptions{Site: "hn/top"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantStates, &states) {
+		t.Errorf("got import states %+v but wanted %+v", states, wantStates)
+	}
+}
+
+func TestImportStates_List_unauthenticated(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Imports.List(nil)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Fatalf("got error %v, want HTTP 401", err)
+	}
+}
+
+func TestImportStates_forbidden(t *testing.T) {
+	setup()
+
+	store.Imports.(*thesrc.MockImportsService).Update_ = func(state *thesrc.ImportState) error {
+		t.Error("Update called by a non-admin")
+		return nil
+	}
+
+	c := authedClient(&thesrc.User{ID: 1})
+	if _, err := c.Imports.List(nil); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("List: got error %v, want HTTP 403", err)
+	}
+	if err := c.Imports.Update(&thesrc.ImportState{Site: "s"}); !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Errorf("Update: got error %v, want HTTP 403", err)
+	}
+}
+
+func TestImportState_Update(t *testing.T) {
+	setup()
+
+	want := &thesrc.ImportState{Site: "/r/golang", ETag: `"1"`, SeenIDs: []string{"a", "b"}}
+
+	calledUpdate := false
+	store.Imports.(*thesrc.MockImportsService).Update_ = func(state *thesrc.ImportState) error {
+		if !normalizeDeepEqual(want, state) {
+			t.Errorf("got import state %+v, want %+v", state, want)
+		}
+		calledUpdate = true
+		return nil
+	}
+
+	if err := authedClient(&thesrc.User{ID: 1, Admin: true}).Imports.Update(want); err != nil {
+		t.Fatal(err)
+	}
+	if !calledUpdate {
+		t.Error("!calledUpdate")
+	}
+}
+
+func TestImportState_Update_invalid(t *testing.T) {
+	setup()
+
+	c := authedClient(&thesrc.User{ID: 1, Admin: true})
+	if err := c.Imports.Update(&thesrc.ImportState{}); !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Errorf("got error %v for missing site, want HTTP 422", err)
+	}
+
+	state := &thesrc.ImportState{Site: "s", SeenIDs: make([]string, thesrc.MaxSeenIDs+1)}
+	if err := c.Imports.Update(state); !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Errorf("got error %v for too many seen IDs, want HTTP 422", err)
+	}
+}
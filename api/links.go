@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+)
+
+// fetchLinkMetadata fetches a link's metadata. Tests replace it to avoid
+// fetching links.
+var fetchLinkMetadata = classifier.FetchMetadata
+
+func servePreviewLink(w http.ResponseWriter, r *http.Request) error {
+	// Only signed-in users (who are about to submit a post) may make the
+	// server fetch links.
+	if _, err := requireUser(r); err != nil {
+		return err
+	}
+
+	linkURL := r.URL.Query().Get("url")
+	if linkURL == "" {
+		return badRequest(errors.New("url parameter is required"))
+	}
+	if err := checkLinkURL(linkURL); err != nil {
+		return invalid(err)
+	}
+
+	md, err := fetchLinkMetadata(linkURL)
+	if err != nil {
+		// The link is broken, or it isn't an HTML page.
+		return invalid(fmt.Errorf("fetching link: %s", err))
+	}
+
+	return writeJSON(w, md)
+}
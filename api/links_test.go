@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestPreviewLink(t *testing.T) {
+	setup()
+
+	want := &thesrc.LinkMetadata{Title: "t", Description: "d", FinalURL: "http://example.com/"}
+	var fetched string
+	origFetch := fetchLinkMetadata
+	defer func() { fetchLinkMetadata = origFetch }()
+	fetchLinkMetadata = func(url string) (*thesrc.LinkMetadata, error) {
+		fetched = url
+		return want, nil
+	}
+
+	md, err := authedClient(&thesrc.User{ID: 1}).Links.Preview("http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched != "http://example.com" {
+		t.Errorf("fetched %q, want %q", fetched, "http://example.com")
+	}
+	md.PublishedAt, md.FetchedAt = want.PublishedAt, want.FetchedAt
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("got metadata %+v, want %+v", md, want)
+	}
+}
+
+func TestPreviewLink_errors(t *testing.T) {
+	setup()
+
+	origFetch := fetchLinkMetadata
+	defer func() { fetchLinkMetadata = origFetch }()
+	fetchLinkMetadata = func(url string) (*thesrc.LinkMetadata, error) {
+		if url == "http://example.com/broken" {
+			return nil, errors.New("non-200 HTTP response status: 404")
+		}
+		t.Errorf("fetched %q", url)
+		return nil, nil
+	}
+
+	if _, err := apiClient.Links.Preview("http://example.com"); !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Errorf("without authentication, got error %v, want HTTP 401", err)
+	}
+
+	c := authedClient(&thesrc.User{ID: 1})
+	for _, linkURL := range []string{"http://127.0.0.1/", "ftp://example.com/", "http://example.com/broken"} {
+		if _, err := c.Links.Preview(linkURL); !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+			t.Errorf("%s: got error %v, want HTTP 422", linkURL, err)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"net"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -11,12 +11,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/safehttp"
 )
 
 func servePost(w http.ResponseWriter, r *http.Request) error {
 	id, err := strconv.Atoi(mux.Vars(r)["ID"])
 	if err != nil {
-		return err
+		return badRequest(err)
 	}
 
 	post, err := store.Posts.Get(id)
@@ -28,30 +29,41 @@ func servePost(w http.ResponseWriter, r *http.Request) error {
 }
 
 func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
-	var post thesrc.Post
-	err := json.NewDecoder(r.Body).Decode(&post)
+	user, err := requireUser(r)
 	if err != nil {
 		return err
 	}
 
-	if post.LinkURL != "" {
-		linkURL, err := url.Parse(post.LinkURL)
-		if err != nil {
-			return err
-		}
-		if linkURL.Scheme != "http" && linkURL.Scheme != "https" {
-			return errors.New("link URL scheme must be http or https")
-		}
-		if host, port, err := net.SplitHostPort(linkURL.Host); err != nil {
-			if !strings.HasPrefix(err.Error(), "missing port") {
-				return err
-			}
-		} else if port != "" {
-			return errors.New("non-standard link URL port is not allowed")
-		} else if !strings.Contains(host, ".") {
-			return errors.New("invalid hostname (must contain dot)")
-		}
-		// TODO(sqs): check for IP addresses or localhost aliases
+	var post thesrc.Post
+	err = json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	if err := checkLinkURL(post.LinkURL); err != nil {
+		return invalid(err)
+	}
+	if post.Tags, err = thesrc.NormalizeTags(post.Tags); err != nil {
+		return invalid(err)
+	}
+	if post.Languages, err = thesrc.NormalizeLanguages(post.Languages); err != nil {
+		return invalid(err)
+	}
+
+	// Clients can't submit posts on other users' behalf or set their own
+	// score (which is computed from votes), comment count, or link metadata
+	// (which is fetched from the link). Only moderators (and the importer
+	// and classifier, which use admins' tokens) can set classifications and
+	// external scores, which decide which posts are listed and how highly.
+	post.AuthorUserID = user.ID
+	post.Score = 0
+	post.CommentCount = 0
+	post.Metadata = nil
+	if !user.Admin {
+		post.Classification = ""
+		post.ClassificationScore = 0
+		post.ClassificationConfirmed = false
+		post.ExternalScore = 0
 	}
 
 	created, err := store.Posts.Submit(&post)
@@ -65,10 +77,125 @@ func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
 	return writeJSON(w, post)
 }
 
+func serveUpdatePost(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return badRequest(err)
+	}
+
+	orig, err := store.Posts.Get(id)
+	if err != nil {
+		return err
+	}
+	if !canModifyPost(user, orig) {
+		return errForbidden
+	}
+
+	// PUT replaces the post, but PATCH only changes the fields in the
+	// request body.
+	var post thesrc.Post
+	if r.Method == "PATCH" {
+		post = *orig
+	}
+	err = json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		return badRequest(err)
+	}
+	post.ID = id
+	post.AuthorUserID = orig.AuthorUserID
+	post.Score = orig.Score
+	post.CommentCount = orig.CommentCount
+	post.Metadata = nil // keep the stored metadata
+	if !user.Admin {
+		post.Classification = orig.Classification
+		post.ClassificationScore = orig.ClassificationScore
+		post.ClassificationConfirmed = orig.ClassificationConfirmed
+		post.ExternalScore = orig.ExternalScore
+	}
+
+	if err := checkLinkURL(post.LinkURL); err != nil {
+		return invalid(err)
+	}
+	if post.Tags, err = thesrc.NormalizeTags(post.Tags); err != nil {
+		return invalid(err)
+	}
+	if post.Languages, err = thesrc.NormalizeLanguages(post.Languages); err != nil {
+		return invalid(err)
+	}
+
+	if err := store.Posts.Update(&post); err != nil {
+		return err
+	}
+
+	return writeJSON(w, post)
+}
+
+func serveDeletePost(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return badRequest(err)
+	}
+
+	post, err := store.Posts.Get(id)
+	if err != nil {
+		return err
+	}
+	if !canModifyPost(user, post) {
+		return errForbidden
+	}
+
+	if err := store.Posts.Delete(id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func serveVotePost(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return badRequest(err)
+	}
+
+	var vote thesrc.Vote
+	err = json.NewDecoder(r.Body).Decode(&vote)
+	if err != nil {
+		return badRequest(err)
+	}
+	vote.PostID = id
+	vote.UserID = user.ID
+
+	if err := store.Posts.Vote(&vote); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func servePosts(w http.ResponseWriter, r *http.Request) error {
 	var opt thesrc.PostListOptions
 	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
-		return err
+		return badRequest(err)
+	}
+	if !thesrc.ValidPostSort(opt.Sort) {
+		return badRequest(fmt.Errorf("invalid sort order %q", opt.Sort))
 	}
 
 	posts, err := store.Posts.List(&opt)
@@ -81,3 +208,45 @@ func servePosts(w http.ResponseWriter, r *http.Request) error {
 
 	return writeJSON(w, posts)
 }
+
+func serveTags(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.ListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return badRequest(err)
+	}
+
+	tags, err := store.Posts.ListTags(&opt)
+	if err != nil {
+		return err
+	}
+	if tags == nil {
+		tags = []*thesrc.TagCount{}
+	}
+
+	return writeJSON(w, tags)
+}
+
+// checkLinkURL returns an error if linkURL is not an acceptable link URL for a
+// post. An empty linkURL is acceptable.
+func checkLinkURL(linkURL string) error {
+	if linkURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(linkURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("link URL scheme must be http or https")
+	}
+	if u.Port() != "" {
+		return errors.New("non-standard link URL port is not allowed")
+	}
+	if !strings.Contains(u.Hostname(), ".") {
+		return errors.New("invalid hostname (must contain dot)")
+	}
+	// Don't accept links that would make the classifier fetch internal
+	// addresses (it checks again when it fetches them).
+	return safehttp.CheckURL(u)
+}
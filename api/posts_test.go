@@ -1,9 +1,14 @@
 package api
 
 import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
 )
 
 func TestPost(t *testing.T) {
@@ -33,10 +38,26 @@ func TestPost(t *testing.T) {
 	}
 }
 
+func TestPost_notFound(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return nil, thesrc.ErrPostNotFound
+	}
+
+	_, err := apiClient.Posts.Get(1)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Fatalf("got error %v, want HTTP 404", err)
+	}
+	if code := err.(*thesrc.ErrorResponse).Code; code != thesrc.ErrorCodeNotFound {
+		t.Errorf("got error code %q, want %q", code, thesrc.ErrorCodeNotFound)
+	}
+}
+
 func TestPost_Submit(t *testing.T) {
 	setup()
 
-	wantPost := &thesrc.Post{ID: 1}
+	wantPost := &thesrc.Post{ID: 1, AuthorUserID: 1}
 
 	calledPost := false
 	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
@@ -47,7 +68,7 @@ func TestPost_Submit(t *testing.T) {
 		return true, nil
 	}
 
-	success, err := apiClient.Posts.Submit(wantPost)
+	success, err := authedClient(&thesrc.User{ID: 1}).Posts.Submit(wantPost)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,6 +81,89 @@ func TestPost_Submit(t *testing.T) {
 	}
 }
 
+func TestPost_Submit_adminOnlyFields(t *testing.T) {
+	setup()
+
+	var submitted *thesrc.Post
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		submitted = post
+		return true, nil
+	}
+
+	post := func() *thesrc.Post {
+		return &thesrc.Post{Classification: thesrc.ClassificationCode, ClassificationScore: 1, ClassificationConfirmed: true, ExternalScore: 1000}
+	}
+
+	if _, err := authedClient(&thesrc.User{ID: 1}).Posts.Submit(post()); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.Post{AuthorUserID: 1}); !normalizeDeepEqual(want, submitted) {
+		t.Errorf("non-admin submitted post %+v, want %+v", submitted, want)
+	}
+
+	if _, err := authedClient(&thesrc.User{ID: 2, Admin: true}).Posts.Submit(post()); err != nil {
+		t.Fatal(err)
+	}
+	want := post()
+	want.AuthorUserID = 2
+	if !normalizeDeepEqual(want, submitted) {
+		t.Errorf("admin submitted post %+v, want %+v", submitted, want)
+	}
+}
+
+func TestPost_Submit_invalidLinkURL(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		t.Error("Submit called for invalid post")
+		return false, nil
+	}
+
+	_, err := authedClient(&thesrc.User{ID: 1}).Posts.Submit(&thesrc.Post{LinkURL: "ftp://example.com"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Fatalf("got error %v, want HTTP 422", err)
+	}
+	if code := err.(*thesrc.ErrorResponse).Code; code != thesrc.ErrorCodeInvalid {
+		t.Errorf("got error code %q, want %q", code, thesrc.ErrorCodeInvalid)
+	}
+}
+
+func TestPost_Submit_internalLinkURL(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		t.Errorf("Submit called for post linking to internal address %q", post.LinkURL)
+		return false, nil
+	}
+
+	for _, linkURL := range []string{"http://127.0.0.1/", "http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/admin", "http://foo.localhost/"} {
+		_, err := authedClient(&thesrc.User{ID: 1}).Posts.Submit(&thesrc.Post{LinkURL: linkURL})
+		if !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+			t.Errorf("%s: got error %v, want HTTP 422", linkURL, err)
+		}
+	}
+}
+
+func TestPost_Submit_malformedJSON(t *testing.T) {
+	setup()
+
+	url, _ := router.API().Get(router.SubmitPost).URL()
+	c := authedClient(&thesrc.User{ID: 1})
+	req, err := c.NewRequest("POST", strings.TrimPrefix(url.String(), "/"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Body = ioutil.NopCloser(strings.NewReader("{"))
+
+	_, err = c.Do(req, nil)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Fatalf("got error %v, want HTTP 400", err)
+	}
+	if code := err.(*thesrc.ErrorResponse).Code; code != thesrc.ErrorCodeBadRequest {
+		t.Errorf("got error code %q, want %q", code, thesrc.ErrorCodeBadRequest)
+	}
+}
+
 func TestPosts_List(t *testing.T) {
 	setup()
 
@@ -88,3 +192,253 @@ func TestPosts_List(t *testing.T) {
 		t.Errorf("got post %+v but wanted post %+v", posts, wantPosts)
 	}
 }
+
+func TestPosts_List_invalidSort(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Posts.List(&thesrc.PostListOptions{Sort: "x"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusBadRequest) {
+		t.Fatalf("got error %v, want HTTP 400", err)
+	}
+}
+
+func TestPost_Submit_unauthenticated(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		t.Error("Submit called for unauthenticated request")
+		return false, nil
+	}
+
+	_, err := apiClient.Posts.Submit(&thesrc.Post{LinkURL: "http://example.com"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Fatalf("got error %v, want HTTP 401", err)
+	}
+}
+
+func TestPost_Update(t *testing.T) {
+	setup()
+
+	wantPost := &thesrc.Post{ID: 1, Title: "t", AuthorUserID: 1}
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1}, nil
+	}
+
+	calledUpdate := false
+	store.Posts.(*thesrc.MockPostsService).Update_ = func(post *thesrc.Post) error {
+		if !normalizeDeepEqual(wantPost, post) {
+			t.Errorf("wanted request for post %+v but got %+v", wantPost, post)
+		}
+		calledUpdate = true
+		return nil
+	}
+
+	if err := authedClient(&thesrc.User{ID: 1}).Posts.Update(wantPost); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledUpdate {
+		t.Error("!calledUpdate")
+	}
+}
+
+func TestPost_Update_patch(t *testing.T) {
+	setup()
+
+	orig := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com", Body: "b", AuthorUserID: 1, Tags: []string{"go"}, Classification: thesrc.ClassificationCode}
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		post := *orig
+		return &post, nil
+	}
+	wantPost := &thesrc.Post{ID: 1, Title: "t2", LinkURL: "http://example.com", Body: "b", AuthorUserID: 1, Tags: []string{"go"}, Classification: thesrc.ClassificationCode}
+	calledUpdate := false
+	store.Posts.(*thesrc.MockPostsService).Update_ = func(post *thesrc.Post) error {
+		if !normalizeDeepEqual(wantPost, post) {
+			t.Errorf("got post %+v, want %+v (only the title changed)", post, wantPost)
+		}
+		calledUpdate = true
+		return nil
+	}
+
+	url, _ := router.API().Get(router.UpdatePost).URL("ID", "1")
+	c := authedClient(&thesrc.User{ID: 1})
+	req, err := c.NewRequest("PATCH", strings.TrimPrefix(url.String(), "/"), map[string]string{"Title": "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledUpdate {
+		t.Error("!calledUpdate")
+	}
+}
+
+func TestPost_Update_forbidden(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 2}, nil
+	}
+	store.Posts.(*thesrc.MockPostsService).Update_ = func(post *thesrc.Post) error {
+		t.Error("Update called for another user's post")
+		return nil
+	}
+
+	err := authedClient(&thesrc.User{ID: 1}).Posts.Update(&thesrc.Post{ID: 1})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusForbidden) {
+		t.Fatalf("got error %v, want HTTP 403", err)
+	}
+
+	// Admins may update any post.
+	store.Posts.(*thesrc.MockPostsService).Update_ = nil
+	if err := authedClient(&thesrc.User{ID: 1, Admin: true}).Posts.Update(&thesrc.Post{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPost_Update_confirmClassification(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1}, nil
+	}
+	var confirmed bool
+	store.Posts.(*thesrc.MockPostsService).Update_ = func(post *thesrc.Post) error {
+		confirmed = post.ClassificationConfirmed
+		return nil
+	}
+
+	confirm := func() *thesrc.Post {
+		return &thesrc.Post{ID: 1, Classification: thesrc.ClassificationCode, ClassificationConfirmed: true}
+	}
+
+	// Authors can't confirm their own posts' classifications.
+	if err := authedClient(&thesrc.User{ID: 1}).Posts.Update(confirm()); err != nil {
+		t.Fatal(err)
+	}
+	if confirmed {
+		t.Error("author confirmed classification, want only admins to be able to")
+	}
+
+	if err := authedClient(&thesrc.User{ID: 2, Admin: true}).Posts.Update(confirm()); err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed {
+		t.Error("admin's classification confirmation was not saved")
+	}
+}
+
+func TestPost_Update_adminOnlyFields(t *testing.T) {
+	setup()
+
+	orig := &thesrc.Post{ID: 1, AuthorUserID: 1, Classification: thesrc.ClassificationNotCode, ClassificationScore: 0.9, ExternalScore: 5}
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		post := *orig
+		return &post, nil
+	}
+	var updated *thesrc.Post
+	store.Posts.(*thesrc.MockPostsService).Update_ = func(post *thesrc.Post) error {
+		updated = post
+		return nil
+	}
+
+	// Authors can't change their posts' classifications or external scores.
+	edit := &thesrc.Post{ID: 1, Title: "t", Classification: thesrc.ClassificationCode, ClassificationScore: 1, ExternalScore: 1000}
+	if err := authedClient(&thesrc.User{ID: 1}).Posts.Update(edit); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&thesrc.Post{ID: 1, Title: "t", AuthorUserID: 1, Classification: thesrc.ClassificationNotCode, ClassificationScore: 0.9, ExternalScore: 5}); !normalizeDeepEqual(want, updated) {
+		t.Errorf("author updated post to %+v, want %+v", updated, want)
+	}
+}
+
+func TestPost_Delete(t *testing.T) {
+	setup()
+
+	store.Posts.(*thesrc.MockPostsService).Get_ = func(id int) (*thesrc.Post, error) {
+		return &thesrc.Post{ID: id, AuthorUserID: 1}, nil
+	}
+
+	calledDelete := false
+	store.Posts.(*thesrc.MockPostsService).Delete_ = func(id int) error {
+		if id != 1 {
+			t.Errorf("wanted request for post %d but got %d", 1, id)
+		}
+		calledDelete = true
+		return nil
+	}
+
+	if err := authedClient(&thesrc.User{ID: 1}).Posts.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledDelete {
+		t.Error("!calledDelete")
+	}
+}
+
+func TestPost_Vote(t *testing.T) {
+	setup()
+
+	calledVote := false
+	store.Posts.(*thesrc.MockPostsService).Vote_ = func(vote *thesrc.Vote) error {
+		if want := (thesrc.Vote{PostID: 1, UserID: 7}); *vote != want {
+			t.Errorf("got vote %+v, want %+v", vote, want)
+		}
+		calledVote = true
+		return nil
+	}
+
+	if err := authedClient(&thesrc.User{ID: 7}).Posts.Vote(&thesrc.Vote{PostID: 1, UserID: 123}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledVote {
+		t.Error("!calledVote")
+	}
+}
+
+func TestPost_Submit_tags(t *testing.T) {
+	setup()
+
+	calledPost := false
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		if want := []string{"go", "postgresql"}; !reflect.DeepEqual(post.Tags, want) {
+			t.Errorf("got tags %q, want %q", post.Tags, want)
+		}
+		calledPost = true
+		return true, nil
+	}
+
+	c := authedClient(&thesrc.User{ID: 1})
+	if _, err := c.Posts.Submit(&thesrc.Post{Tags: []string{"PostgreSQL", "go", "go"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !calledPost {
+		t.Error("!calledPost")
+	}
+
+	_, err := c.Posts.Submit(&thesrc.Post{Tags: []string{"not a tag"}})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Fatalf("got error %v, want HTTP 422", err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	setup()
+
+	want := []*thesrc.TagCount{{Tag: "go", Count: 3}}
+	store.Posts.(*thesrc.MockPostsService).ListTags_ = func(opt *thesrc.ListOptions) ([]*thesrc.TagCount, error) {
+		return want, nil
+	}
+
+	tags, err := apiClient.Posts.ListTags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %+v, want %+v", tags, want)
+	}
+}
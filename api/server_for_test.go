@@ -24,6 +24,18 @@ func setup() {
 	store = datastore.NewMockDatastore()
 }
 
+// authedClient returns an API client whose requests are authenticated as
+// user.
+func authedClient(user *thesrc.User) *thesrc.Client {
+	store.Users.(*thesrc.MockUsersService).Authenticate_ = func(token string) (*thesrc.User, error) {
+		if token != "t" {
+			return nil, thesrc.ErrBadToken
+		}
+		return user, nil
+	}
+	return apiClient.WithToken("t")
+}
+
 type muxTransport http.ServeMux
 
 // RoundTrip is a custom http.RoundTripper for testing API requests/responses.
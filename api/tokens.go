@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// getOwnAPIToken gets the API token whose ID is in r's route vars, returning
+// thesrc.ErrAPITokenNotFound if it does not belong to user.
+func getOwnAPIToken(r *http.Request, user *thesrc.User) (*thesrc.APIToken, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	token, err := store.Tokens.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if token.UserID != user.ID {
+		// Don't reveal the existence of other users' tokens.
+		return nil, thesrc.ErrAPITokenNotFound
+	}
+	return token, nil
+}
+
+func serveAPIToken(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	token, err := getOwnAPIToken(r, user)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, token)
+}
+
+func serveAPITokens(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	var opt thesrc.APITokenListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return badRequest(err)
+	}
+	opt.UserID = user.ID
+
+	tokens, err := store.Tokens.List(&opt)
+	if err != nil {
+		return err
+	}
+	if tokens == nil {
+		tokens = []*thesrc.APIToken{}
+	}
+
+	return writeJSON(w, tokens)
+}
+
+func serveCreateAPIToken(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	var token thesrc.APIToken
+	err = json.NewDecoder(r.Body).Decode(&token)
+	if err != nil {
+		return badRequest(err)
+	}
+	token = thesrc.APIToken{UserID: user.ID, Name: token.Name}
+
+	if err := store.Tokens.Create(&token); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, token)
+}
+
+func serveRevokeAPIToken(w http.ResponseWriter, r *http.Request) error {
+	user, err := requireUser(r)
+	if err != nil {
+		return err
+	}
+
+	token, err := getOwnAPIToken(r, user)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Tokens.Revoke(token.ID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
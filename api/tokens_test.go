@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestAPITokens_List(t *testing.T) {
+	setup()
+
+	wantTokens := []*thesrc.APIToken{{ID: 1, UserID: 1, Name: "n"}}
+
+	calledList := false
+	store.Tokens.(*thesrc.MockTokensService).List_ = func(opt *thesrc.APITokenListOptions) ([]*thesrc.APIToken, error) {
+		if want := 1; opt.UserID != want {
+			t.Errorf("got UserID %d, want %d", opt.UserID, want)
+		}
+		calledList = true
+		return wantTokens, nil
+	}
+
+	// The API lists the authenticated user's tokens, regardless of the
+	// UserID option.
+	tokens, err := authedClient(&thesrc.User{ID: 1}).Tokens.List(&thesrc.APITokenListOptions{UserID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+	if !normalizeDeepEqual(&wantTokens, &tokens) {
+		t.Errorf("got tokens %+v but wanted tokens %+v", tokens, wantTokens)
+	}
+}
+
+func TestAPITokens_List_unauthenticated(t *testing.T) {
+	setup()
+
+	_, err := apiClient.Tokens.List(nil)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Fatalf("got error %v, want HTTP 401", err)
+	}
+}
+
+func TestAPIToken_Create(t *testing.T) {
+	setup()
+
+	calledCreate := false
+	store.Tokens.(*thesrc.MockTokensService).Create_ = func(token *thesrc.APIToken) error {
+		if want := (thesrc.APIToken{UserID: 1, Name: "n"}); *token != want {
+			t.Errorf("got token %+v, want %+v", token, want)
+		}
+		calledCreate = true
+		token.ID = 1
+		token.Token = "secret"
+		return nil
+	}
+
+	token := &thesrc.APIToken{Name: "n", UserID: 2}
+	if err := authedClient(&thesrc.User{ID: 1}).Tokens.Create(token); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledCreate {
+		t.Error("!calledCreate")
+	}
+	if token.Token != "secret" {
+		t.Errorf("got token secret %q, want %q", token.Token, "secret")
+	}
+}
+
+func TestAPIToken_Revoke_otherUser(t *testing.T) {
+	setup()
+
+	store.Tokens.(*thesrc.MockTokensService).Get_ = func(id int) (*thesrc.APIToken, error) {
+		return &thesrc.APIToken{ID: id, UserID: 2}, nil
+	}
+	store.Tokens.(*thesrc.MockTokensService).Revoke_ = func(id int) error {
+		t.Error("Revoke called for another user's token")
+		return nil
+	}
+
+	err := authedClient(&thesrc.User{ID: 1}).Tokens.Revoke(1)
+	if !thesrc.IsHTTPErrorCode(err, http.StatusNotFound) {
+		t.Fatalf("got error %v, want HTTP 404", err)
+	}
+}
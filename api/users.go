@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := store.Users.Get(mux.Vars(r)["Login"])
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, user)
+}
+
+func serveSignup(w http.ResponseWriter, r *http.Request) error {
+	var cred thesrc.Credentials
+	err := json.NewDecoder(r.Body).Decode(&cred)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	if err := checkCredentials(&cred); err != nil {
+		return invalid(err)
+	}
+
+	user := &thesrc.User{Login: cred.Login}
+	if err := store.Users.Signup(user, cred.Password); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, user)
+}
+
+func serveAuthenticatedUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := authenticatedUser(r)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return thesrc.ErrBadToken
+	}
+
+	return writeJSON(w, user)
+}
+
+func serveLogIn(w http.ResponseWriter, r *http.Request) error {
+	var cred thesrc.Credentials
+	err := json.NewDecoder(r.Body).Decode(&cred)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	sess, err := store.Users.Login(&cred)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return writeJSON(w, sess)
+}
+
+func serveLogOut(w http.ResponseWriter, r *http.Request) error {
+	token := bearerToken(r)
+	if token == "" {
+		return thesrc.ErrBadToken
+	}
+
+	if err := store.Users.Logout(token); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+var validLogin = regexp.MustCompile(`^[A-Za-z0-9_-]{1,30}$`)
+
+// minPasswordLen is the minimum length of a new user's password.
+const minPasswordLen = 6
+
+// checkCredentials returns an error if cred is not acceptable for signing up
+// a new user.
+func checkCredentials(cred *thesrc.Credentials) error {
+	if !validLogin.MatchString(cred.Login) {
+		return errors.New("login must be 1-30 letters, digits, underscores, or hyphens")
+	}
+	if len(cred.Password) < minPasswordLen {
+		return errors.New("password is too short")
+	}
+	return nil
+}
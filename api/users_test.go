@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestUser(t *testing.T) {
+	setup()
+
+	wantUser := &thesrc.User{ID: 1, Login: "u"}
+
+	calledGet := false
+	store.Users.(*thesrc.MockUsersService).Get_ = func(login string) (*thesrc.User, error) {
+		if login != wantUser.Login {
+			t.Errorf("wanted request for user %q but got %q", wantUser.Login, login)
+		}
+		calledGet = true
+		return wantUser, nil
+	}
+
+	gotUser, err := apiClient.Users.Get(wantUser.Login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledGet {
+		t.Error("!calledGet")
+	}
+	if !normalizeDeepEqual(wantUser, gotUser) {
+		t.Errorf("got user %+v but wanted user %+v", gotUser, wantUser)
+	}
+}
+
+func TestSignup(t *testing.T) {
+	setup()
+
+	calledSignup := false
+	store.Users.(*thesrc.MockUsersService).Signup_ = func(user *thesrc.User, password string) error {
+		if user.Login != "u" || password != "secret" {
+			t.Errorf("wanted signup for user %q with password %q but got %q, %q", "u", "secret", user.Login, password)
+		}
+		calledSignup = true
+		user.ID = 1
+		return nil
+	}
+
+	user := &thesrc.User{Login: "u"}
+	if err := apiClient.Users.Signup(user, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledSignup {
+		t.Error("!calledSignup")
+	}
+	if user.ID != 1 {
+		t.Errorf("got user ID %d, want %d", user.ID, 1)
+	}
+}
+
+func TestSignup_invalid(t *testing.T) {
+	setup()
+
+	store.Users.(*thesrc.MockUsersService).Signup_ = func(user *thesrc.User, password string) error {
+		t.Error("Signup called for invalid credentials")
+		return nil
+	}
+
+	err := apiClient.Users.Signup(&thesrc.User{Login: "u"}, "short")
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnprocessableEntity) {
+		t.Fatalf("got error %v, want HTTP 422", err)
+	}
+}
+
+func TestLogIn_badCredentials(t *testing.T) {
+	setup()
+
+	store.Users.(*thesrc.MockUsersService).Login_ = func(cred *thesrc.Credentials) (*thesrc.Session, error) {
+		return nil, thesrc.ErrBadCredentials
+	}
+
+	_, err := apiClient.Users.Login(&thesrc.Credentials{Login: "u", Password: "wrong"})
+	if !thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		t.Fatalf("got error %v, want HTTP 401", err)
+	}
+}
+
+func TestAuthenticatedUser(t *testing.T) {
+	setup()
+
+	wantUser := &thesrc.User{ID: 1, Login: "u"}
+
+	store.Users.(*thesrc.MockUsersService).Authenticate_ = func(token string) (*thesrc.User, error) {
+		if token != "t" {
+			t.Errorf("wanted token %q but got %q", "t", token)
+		}
+		return wantUser, nil
+	}
+
+	gotUser, err := apiClient.Users.Authenticate("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !normalizeDeepEqual(wantUser, gotUser) {
+		t.Errorf("got user %+v but wanted user %+v", gotUser, wantUser)
+	}
+}
+
+func TestPost_Submit_authenticated(t *testing.T) {
+	setup()
+
+	c := authedClient(&thesrc.User{ID: 7})
+
+	calledPost := false
+	store.Posts.(*thesrc.MockPostsService).Submit_ = func(post *thesrc.Post) (bool, error) {
+		if want := 7; post.AuthorUserID != want {
+			t.Errorf("got AuthorUserID %d, want %d", post.AuthorUserID, want)
+		}
+		calledPost = true
+		return true, nil
+	}
+
+	if _, err := c.Posts.Submit(&thesrc.Post{AuthorUserID: 123}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !calledPost {
+		t.Error("!calledPost")
+	}
+}
@@ -31,6 +31,10 @@ func getHTML(t *testing.T, uri *url.URL) (*goquery.Document, *httptest.ResponseR
 	if err != nil {
 		t.Fatal(err)
 	}
+	return doHTML(t, req)
+}
+
+func doHTML(t *testing.T, req *http.Request) (*goquery.Document, *httptest.ResponseRecorder) {
 
 	rw := httptest.NewRecorder()
 	rw.Body = new(bytes.Buffer)
@@ -42,3 +46,17 @@ func getHTML(t *testing.T, uri *url.URL) (*goquery.Document, *httptest.ResponseR
 	}
 	return doc, rw
 }
+
+// addSessionCookie adds a session cookie to req, as though a user were logged
+// in.
+func addSessionCookie(req *http.Request) {
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+}
+
+// testCSRFToken returns the CSRF token of the session that addSessionCookie
+// adds.
+func testCSRFToken() string {
+	req, _ := http.NewRequest("GET", "/", nil)
+	addSessionCookie(req)
+	return csrfToken(req)
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A commentNode is a comment in a comment thread, along with its replies.
+type commentNode struct {
+	*thesrc.Comment
+	Replies []*commentNode
+}
+
+// Reply returns a new (unsaved) comment that replies to n, for use in reply
+// forms.
+func (n *commentNode) Reply() *thesrc.Comment {
+	return &thesrc.Comment{PostID: n.PostID, ParentID: n.ID}
+}
+
+// commentTree arranges a post's comments into threads, returning the
+// top-level comments. Replies keep the order they appear in comments.
+// Comments whose parent isn't in comments are treated as top-level
+// comments.
+func commentTree(comments []*thesrc.Comment) []*commentNode {
+	nodes := make(map[int]*commentNode, len(comments))
+	for _, c := range comments {
+		nodes[c.ID] = &commentNode{Comment: c}
+	}
+
+	var roots []*commentNode
+	for _, c := range comments {
+		node := nodes[c.ID]
+		if parent, present := nodes[c.ParentID]; present && c.ParentID != 0 && c.ParentID != c.ID {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+func serveSubmitComment(w http.ResponseWriter, r *http.Request) error {
+	if sessionToken(r) == "" {
+		return redirectToLogIn(w, r)
+	}
+
+	postID, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := checkCSRFToken(r); err != nil {
+		return err
+	}
+
+	var comment thesrc.Comment
+	if err := schemaDecoder.Decode(&comment, r.Form); err != nil {
+		return err
+	}
+	comment.PostID = postID
+
+	if err := apiClient(r).Comments.Submit(&comment); err != nil {
+		return err
+	}
+
+	postURL := urlTo(router.Post, "ID", strconv.Itoa(postID))
+	postURL.Fragment = "comment-" + strconv.Itoa(comment.ID)
+	http.Redirect(w, r, postURL.String(), http.StatusSeeOther)
+	return nil
+}
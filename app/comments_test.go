@@ -0,0 +1,118 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestCommentTree(t *testing.T) {
+	comments := []*thesrc.Comment{
+		{ID: 1},
+		{ID: 2, ParentID: 1},
+		{ID: 3},
+		{ID: 4, ParentID: 2},
+		{ID: 5, ParentID: 1},
+		{ID: 6, ParentID: 99}, // parent missing
+	}
+
+	tree := commentTree(comments)
+
+	var ids func(nodes []*commentNode) string
+	ids = func(nodes []*commentNode) string {
+		var s []string
+		for _, n := range nodes {
+			id := strconv.Itoa(n.ID)
+			if len(n.Replies) > 0 {
+				id += "(" + ids(n.Replies) + ")"
+			}
+			s = append(s, id)
+		}
+		return strings.Join(s, " ")
+	}
+	if got, want := ids(tree), "1(2(4) 5) 3 6"; got != want {
+		t.Errorf("got comment tree %q, want %q", got, want)
+	}
+}
+
+func TestPost_comments(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) { return &thesrc.Post{ID: id}, nil },
+		},
+		Comments: &thesrc.MockCommentsService{
+			List_: func(postID int) ([]*thesrc.Comment, error) {
+				return []*thesrc.Comment{
+					{ID: 2, PostID: postID, Body: "top"},
+					{ID: 3, PostID: postID, ParentID: 2, Body: "reply"},
+				}, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.Post).URL("ID", "1")
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if got := html.Find("#comment-2 #comment-3 .comment-body").Text(); got != "reply" {
+		t.Errorf("got nested reply body %q, want %q", got, "reply")
+	}
+	if got, _ := html.Find("#comment-2 > details input[name=ParentID]").Attr("value"); got != "2" {
+		t.Errorf("got reply form ParentID %q, want %q", got, "2")
+	}
+}
+
+func TestSubmitComment(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Comments: &thesrc.MockCommentsService{
+			Submit_: func(comment *thesrc.Comment) error {
+				if comment.PostID != 1 || comment.ParentID != 2 || comment.Body != "b" {
+					t.Errorf("got comment %+v", comment)
+				}
+				comment.ID = 3
+				called = true
+				return nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(router.SubmitComment).URL("ID", "1")
+	form := url.Values{"ParentID": {"2"}, "Body": {"b"}, "CSRFToken": {testCSRFToken()}}
+	req, err := http.NewRequest("POST", u.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	addSessionCookie(req)
+
+	resp := httptest.NewRecorder()
+	testMux.ServeHTTP(resp, req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if loc, want := resp.Header().Get("location"), "/p/1#comment-3"; loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
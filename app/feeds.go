@@ -0,0 +1,275 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	htmpl "html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/go-querystring/query"
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// BaseURL is the base URL of the app. It is used to construct the absolute
+// URLs in feeds.
+var BaseURL = &url.URL{Scheme: "http", Host: "thesrc.org", Path: "/"}
+
+// absURL returns u resolved against BaseURL.
+func absURL(u *url.URL) string {
+	return BaseURL.ResolveReference(u).String()
+}
+
+// feedURL returns the URL of the feed (whose route is routeName) of the posts
+// matching opt. A feed URL doesn't specify a page, so that it always returns
+// the latest posts.
+func feedURL(routeName string, opt *thesrc.PostListOptions) (*url.URL, error) {
+	u := urlTo(routeName)
+	if opt != nil {
+		opt2 := *opt
+		opt2.Page = 0
+		q, err := query.Values(opt2)
+		if err != nil {
+			return nil, err
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u, nil
+}
+
+// feedPosts returns the posts in the feed requested by r, along with the feed's
+// options. Feeds accept all of the options that PostListOptions does; like
+// the front page, they only include posts classified as code unless the
+// CodeOnly query parameter says otherwise.
+func feedPosts(r *http.Request) (*thesrc.PostListOptions, []*thesrc.Post, error) {
+	q := r.URL.Query()
+	opt := &thesrc.PostListOptions{CodeOnly: true}
+	if err := schemaDecoder.Decode(opt, q); err != nil {
+		return nil, nil, err
+	}
+	if _, present := q["CodeOnly"]; !present {
+		opt.CodeOnly = true
+	}
+	if !thesrc.ValidPostSort(opt.Sort) {
+		return nil, nil, fmt.Errorf("invalid sort order %q", opt.Sort)
+	}
+
+	if opt.PerPage == 0 {
+		opt.PerPage = 30
+	}
+
+	posts, err := APIClient.Posts.List(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return opt, posts, nil
+}
+
+// feedTitle returns a title for the feed of the posts matching opt.
+func feedTitle(opt *thesrc.PostListOptions) string {
+	title := "thesrc"
+	if opt.Tag != "" {
+		title += ": " + opt.Tag
+	}
+	if opt.Language != "" {
+		title += ": " + thesrc.LanguageName(opt.Language)
+	}
+	if opt.Query != "" {
+		title += fmt.Sprintf(": search for %q", opt.Query)
+	}
+	if opt.Sort != "" {
+		title += " (" + opt.Sort + ")"
+	}
+	return title
+}
+
+// feedUpdated returns the time that a feed of posts was last updated, which
+// is the time that the most recent of its posts was submitted.
+func feedUpdated(posts []*thesrc.Post) time.Time {
+	var t time.Time
+	for _, post := range posts {
+		if post.SubmittedAt.After(t) {
+			t = post.SubmittedAt
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t
+}
+
+// postLinks returns the absolute URL of post's page on thesrc and its external
+// link URL (or its page URL, if it has no link URL).
+func postLinks(post *thesrc.Post) (pageURL, linkURL string) {
+	pageURL = absURL(urlTo(router.Post, "ID", strconv.Itoa(post.ID)))
+	linkURL = post.LinkURL
+	if linkURL == "" {
+		linkURL = pageURL
+	}
+	return pageURL, linkURL
+}
+
+var feedEntryTemplate = htmpl.Must(htmpl.New("").Parse(`{{if .Body}}<p>{{.Body}}</p>{{end}}<p><a href="{{.LinkURL}}">{{.LinkURL}}</a> | <a href="{{.PageURL}}">comments</a></p>`))
+
+// feedEntryHTML returns the HTML content of post's feed entry, which links
+// to both the post's link URL and its page on thesrc.
+func feedEntryHTML(post *thesrc.Post) (string, error) {
+	pageURL, linkURL := postLinks(post)
+	var buf bytes.Buffer
+	err := feedEntryTemplate.Execute(&buf, struct {
+		Body             string
+		PageURL, LinkURL string
+	}{post.Body, pageURL, linkURL})
+	return buf.String(), err
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Links     []atomLink `xml:"link"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func serveAtomFeed(w http.ResponseWriter, r *http.Request) error {
+	opt, posts, err := feedPosts(r)
+	if err != nil {
+		return err
+	}
+
+	selfURL, err := feedURL(router.FeedAtom, opt)
+	if err != nil {
+		return err
+	}
+	feed := atomFeed{
+		Title:   feedTitle(opt),
+		ID:      absURL(selfURL),
+		Updated: feedUpdated(posts).UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: absURL(selfURL)},
+			{Rel: "alternate", Type: "text/html", Href: absURL(urlTo(router.Posts))},
+		},
+	}
+	for _, post := range posts {
+		pageURL, linkURL := postLinks(post)
+		content, err := feedEntryHTML(post)
+		if err != nil {
+			return err
+		}
+		submittedAt := post.SubmittedAt.UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     post.Title,
+			ID:        pageURL,
+			Updated:   submittedAt,
+			Published: submittedAt,
+			Links: []atomLink{
+				{Rel: "alternate", Type: "text/html", Href: linkURL},
+				{Rel: "replies", Type: "text/html", Href: pageURL},
+			},
+			Content: atomText{Type: "html", Body: content},
+		})
+	}
+
+	return writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+	Comments    string  `xml:"comments"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func serveRSSFeed(w http.ResponseWriter, r *http.Request) error {
+	opt, posts, err := feedPosts(r)
+	if err != nil {
+		return err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle(opt),
+			Link:          absURL(urlTo(router.Posts)),
+			Description:   "Links for programmers, from thesrc",
+			LastBuildDate: feedUpdated(posts).UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, post := range posts {
+		pageURL, linkURL := postLinks(post)
+		content, err := feedEntryHTML(post)
+		if err != nil {
+			return err
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        linkURL,
+			GUID:        rssGUID{IsPermaLink: true, Value: pageURL},
+			PubDate:     post.SubmittedAt.UTC().Format(time.RFC1123Z),
+			Description: content,
+			Comments:    pageURL,
+		})
+	}
+
+	return writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+// writeXML writes v as an XML document with the given content type.
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	w.Header().Set("content-type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+var feedTestPosts = []*thesrc.Post{
+	{ID: 1, Title: "t", LinkURL: "http://example.com/a", Body: "b", SubmittedAt: time.Date(2014, 6, 1, 12, 0, 0, 0, time.UTC)},
+}
+
+// getFeed requests the feed at the given route with the given query, checking
+// that the post list options passed to the API match wantOpt.
+func getFeed(t *testing.T, routeName string, q url.Values, wantOpt *thesrc.PostListOptions) *httptest.ResponseRecorder {
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if *opt != *wantOpt {
+					t.Errorf("got list options %+v, want %+v", opt, wantOpt)
+				}
+				called = true
+				return feedTestPosts, nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(routeName).URL()
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := httptest.NewRecorder()
+	testMux.ServeHTTP(resp, req)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	return resp
+}
+
+func TestAtomFeed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	q := url.Values{"Tag": {"go"}, "Sort": {"top"}, "Page": {"2"}}
+	wantOpt := &thesrc.PostListOptions{CodeOnly: true, Tag: "go", Sort: "top", ListOptions: thesrc.ListOptions{Page: 2, PerPage: 30}}
+	resp := getFeed(t, router.FeedAtom, q, wantOpt)
+
+	var feed atomFeed
+	if err := xml.Unmarshal(resp.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if want := "http://thesrc.org/p/1"; e.ID != want {
+		t.Errorf("got entry ID %q, want %q", e.ID, want)
+	}
+	if want := "2014-06-01T12:00:00Z"; e.Updated != want {
+		t.Errorf("got entry updated %q, want %q", e.Updated, want)
+	}
+	if feed.Updated != e.Updated {
+		t.Errorf("got feed updated %q, want %q", feed.Updated, e.Updated)
+	}
+	links := map[string]string{}
+	for _, l := range e.Links {
+		links[l.Rel] = l.Href
+	}
+	if want := map[string]string{"alternate": "http://example.com/a", "replies": "http://thesrc.org/p/1"}; links["alternate"] != want["alternate"] || links["replies"] != want["replies"] {
+		t.Errorf("got entry links %v, want %v", links, want)
+	}
+}
+
+func TestRSSFeed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	q := url.Values{"CodeOnly": {"false"}}
+	wantOpt := &thesrc.PostListOptions{ListOptions: thesrc.ListOptions{PerPage: 30}}
+	resp := getFeed(t, router.FeedRSS, q, wantOpt)
+
+	var feed rssFeed
+	if err := xml.Unmarshal(resp.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if want := "http://example.com/a"; item.Link != want {
+		t.Errorf("got item link %q, want %q", item.Link, want)
+	}
+	if want := "http://thesrc.org/p/1"; item.GUID.Value != want || item.Comments != want {
+		t.Errorf("got item GUID %q and comments %q, want %q", item.GUID.Value, item.Comments, want)
+	}
+	if want := "Sun, 01 Jun 2014 12:00:00 +0000"; item.PubDate != want {
+		t.Errorf("got item pubDate %q, want %q", item.PubDate, want)
+	}
+}
+
+func TestFeedAlternateLinks(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{Posts: &thesrc.MockPostsService{}}
+
+	u, _ := router.App().Get(router.TagPosts).URL("Tag", "go")
+	html, _ := getHTML(t, u)
+
+	href, _ := html.Find(`link[rel=alternate][type="application/atom+xml"]`).Attr("href")
+	feedURL, err := url.Parse(href)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/feed.atom"; feedURL.Path != want {
+		t.Errorf("got feed path %q, want %q", feedURL.Path, want)
+	}
+	if got := feedURL.Query().Get("Tag"); got != "go" {
+		t.Errorf("got feed Tag %q, want %q", got, "go")
+	}
+}
@@ -12,3 +12,9 @@ func urlDomain(urlStr string) string {
 	}
 	return strings.TrimPrefix(url.Host, "www.")
 }
+
+// archiveURL returns the URL of the Internet Archive's Wayback Machine page for
+// linkURL, which redirects to the most recent archived copy of the page.
+func archiveURL(linkURL string) string {
+	return "https://web.archive.org/web/" + linkURL
+}
@@ -34,9 +34,25 @@ func Handler() *mux.Router {
 	m.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(StaticDir))))
 	// TODO(sqs): add handlers for /favicon.ico and /robots.txt
 	m.Get(router.Post).Handler(handler(servePost))
-	m.Get(router.Posts).Handler(handler(servePosts))
+	m.Get(router.Posts).Handler(handler(servePosts(thesrc.PostSortHot)))
+	m.Get(router.NewPosts).Handler(handler(servePosts(thesrc.PostSortNew)))
+	m.Get(router.TopPosts).Handler(handler(servePosts(thesrc.PostSortTop)))
+	m.Get(router.SearchPosts).Handler(handler(serveSearchPosts))
+	m.Get(router.TagPosts).Handler(handler(serveTagPosts))
+	m.Get(router.Tags).Handler(handler(serveTags))
+	m.Get(router.LanguagePosts).Handler(handler(serveLanguagePosts))
+	m.Get(router.FeedAtom).Handler(handler(serveAtomFeed))
+	m.Get(router.FeedRSS).Handler(handler(serveRSSFeed))
 	m.Get(router.SubmitPostForm).Handler(handler(serveSubmitPostForm))
 	m.Get(router.SubmitPost).Handler(handler(serveSubmitPost))
+	m.Get(router.VotePost).Handler(handler(serveVotePost))
+	m.Get(router.SubmitComment).Handler(handler(serveSubmitComment))
+	m.Get(router.User).Handler(handler(serveUser))
+	m.Get(router.SignupForm).Handler(handler(serveSignupForm))
+	m.Get(router.Signup).Handler(handler(serveSignup))
+	m.Get(router.LogInForm).Handler(handler(serveLogInForm))
+	m.Get(router.LogIn).Handler(handler(serveLogIn))
+	m.Get(router.LogOut).Handler(handler(serveLogOut))
 	return m
 }
 
@@ -61,7 +77,9 @@ func runHandler(w http.ResponseWriter, r *http.Request, fn func(http.ResponseWri
 	}()
 
 	err = fn(w, r)
-	if err != nil {
+	if err == errBadCSRFToken {
+		handleError(w, r, http.StatusForbidden, err)
+	} else if err != nil {
 		logError(r, err, nil)
 		handleError(w, r, http.StatusInternalServerError, err)
 	}
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"math"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -22,20 +24,38 @@ func servePost(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	return renderTemplate(w, r, "posts/show.html", http.StatusOK, struct {
-		Post *thesrc.Post
+	comments, err := APIClient.Comments.List(id)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/show.html", http.StatusOK, &struct {
+		Post       *thesrc.Post
+		Comments   []*commentNode
+		NewComment *thesrc.Comment
+		templateCommon
 	}{
-		Post: post,
+		Post:       post,
+		Comments:   commentTree(comments),
+		NewComment: &thesrc.Comment{PostID: post.ID},
 	})
 }
 
-func servePosts(w http.ResponseWriter, r *http.Request) error {
+// servePosts returns a handler that lists posts in the given sort order.
+func servePosts(sort string) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return servePostsSorted(w, r, sort)
+	}
+}
+
+func servePostsSorted(w http.ResponseWriter, r *http.Request, sort string) error {
 	var opt thesrc.PostListOptions
 	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
 		return err
 	}
 
 	opt.CodeOnly = true
+	opt.Sort = sort
 
 	if opt.PerPage == 0 {
 		opt.PerPage = 60
@@ -46,31 +66,205 @@ func servePosts(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	return renderTemplate(w, r, "posts/list.html", http.StatusOK, struct {
+	return renderTemplate(w, r, "posts/list.html", http.StatusOK, &struct {
+		Posts []*thesrc.Post
+		templateCommon
+	}{
+		Posts:          posts,
+		templateCommon: templateCommon{FeedOptions: &opt},
+	})
+}
+
+func serveTagPosts(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.PostListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	// Tagged posts were curated by people, so don't also require the
+	// classifier to think that they contain code.
+	opt.Tag = mux.Vars(r)["Tag"]
+	opt.Sort = thesrc.PostSortHot
+
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	posts, err := APIClient.Posts.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/tag.html", http.StatusOK, &struct {
+		Tag   string
+		Posts []*thesrc.Post
+		templateCommon
+	}{
+		Tag:            opt.Tag,
+		Posts:          posts,
+		templateCommon: templateCommon{FeedOptions: &opt},
+	})
+}
+
+func serveLanguagePosts(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.PostListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	// Only posts that the classifier looked at have languages, so there's no
+	// need to also require that it thinks they contain code.
+	opt.Language = mux.Vars(r)["Language"]
+	opt.Sort = thesrc.PostSortHot
+
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	posts, err := APIClient.Posts.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/language.html", http.StatusOK, &struct {
+		Language string
+		Posts    []*thesrc.Post
+		templateCommon
+	}{
+		Language:       opt.Language,
+		Posts:          posts,
+		templateCommon: templateCommon{FeedOptions: &opt},
+	})
+}
+
+// A tagCloudItem is a tag in a tag cloud, with a font size (in ems) that
+// reflects how many posts have the tag.
+type tagCloudItem struct {
+	*thesrc.TagCount
+	Size float64
+}
+
+// tagCloud returns tag cloud items for tags, sorted alphabetically. Font sizes
+// scale logarithmically with the number of posts, between 0.8em (for the
+// least-used tag) and 2.4em (for the most-used tag).
+func tagCloud(tags []*thesrc.TagCount) []*tagCloudItem {
+	const minSize, maxSize = 0.8, 2.4
+
+	if len(tags) == 0 {
+		return nil
+	}
+	min, max := tags[0].Count, tags[0].Count
+	for _, t := range tags {
+		if t.Count < min {
+			min = t.Count
+		}
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+
+	items := make([]*tagCloudItem, len(tags))
+	for i, t := range tags {
+		size := minSize
+		if max > min {
+			frac := math.Log(float64(t.Count-min+1)) / math.Log(float64(max-min+1))
+			size += frac * (maxSize - minSize)
+		}
+		items[i] = &tagCloudItem{TagCount: t, Size: math.Floor(size*100) / 100}
+	}
+	sort.Sort(tagCloudItemsByTag(items))
+	return items
+}
+
+type tagCloudItemsByTag []*tagCloudItem
+
+func (v tagCloudItemsByTag) Len() int           { return len(v) }
+func (v tagCloudItemsByTag) Less(i, j int) bool { return v[i].Tag < v[j].Tag }
+func (v tagCloudItemsByTag) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
+func serveTags(w http.ResponseWriter, r *http.Request) error {
+	tags, err := APIClient.Posts.ListTags(&thesrc.ListOptions{PerPage: 200})
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "posts/tags.html", http.StatusOK, &struct {
+		Tags []*tagCloudItem
+		templateCommon
+	}{
+		Tags: tagCloud(tags),
+	})
+}
+
+func serveSearchPosts(w http.ResponseWriter, r *http.Request) error {
+	var opt thesrc.PostListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+
+	opt.CodeOnly = true
+	opt.Sort = "" // rank by relevance
+
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	var posts []*thesrc.Post
+	if opt.Query != "" {
+		var err error
+		posts, err = APIClient.Posts.List(&opt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return renderTemplate(w, r, "posts/search.html", http.StatusOK, &struct {
+		Query string
 		Posts []*thesrc.Post
+		templateCommon
 	}{
-		Posts: posts,
+		Query:          opt.Query,
+		Posts:          posts,
+		templateCommon: templateCommon{FeedOptions: &opt},
 	})
 }
 
 func serveSubmitPostForm(w http.ResponseWriter, r *http.Request) error {
+	if sessionToken(r) == "" {
+		return redirectToLogIn(w, r)
+	}
+
 	// Populate form from querystring.
 	q := r.URL.Query()
 	post := &thesrc.Post{
 		Title:   getCaseOrLowerCaseQuery(q, "Title"),
 		LinkURL: getCaseOrLowerCaseQuery(q, "LinkURL") + getCaseOrLowerCaseQuery(q, "URL"), // support both
 		Body:    getCaseOrLowerCaseQuery(q, "Body"),
+		Tags:    thesrc.SplitTags(getCaseOrLowerCaseQuery(q, "Tags")),
 	}
 
-	return renderTemplate(w, r, "posts/submit_form.html", http.StatusOK, struct {
+	// Fill in the title from the link's page if the user didn't provide one.
+	// If the page can't be fetched, leave the title for the user to write.
+	if post.Title == "" && post.LinkURL != "" {
+		if md, err := apiClient(r).Links.Preview(post.LinkURL); err == nil && md != nil {
+			post.Title = md.Title
+		}
+	}
+
+	return renderTemplate(w, r, "posts/submit_form.html", http.StatusOK, &struct {
 		Post *thesrc.Post
+		templateCommon
 	}{
 		Post: post,
 	})
 }
 
 func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
-	if err := r.ParseForm(); err != nil {
+	if sessionToken(r) == "" {
+		return redirectToLogIn(w, r)
+	}
+
+	if err := checkCSRFToken(r); err != nil {
 		return err
 	}
 
@@ -78,8 +272,9 @@ func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
 	if err := schemaDecoder.Decode(&post, r.Form); err != nil {
 		return err
 	}
+	post.Tags = thesrc.SplitTags(r.Form.Get("Tags"))
 
-	if _, err := APIClient.Posts.Submit(&post); err != nil {
+	if _, err := apiClient(r).Posts.Submit(&post); err != nil {
 		return err
 	}
 
@@ -88,6 +283,42 @@ func serveSubmitPost(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func serveVotePost(w http.ResponseWriter, r *http.Request) error {
+	if sessionToken(r) == "" {
+		return redirectToLogIn(w, r)
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["ID"])
+	if err != nil {
+		return err
+	}
+
+	if err := checkCSRFToken(r); err != nil {
+		return err
+	}
+
+	vote := &thesrc.Vote{PostID: id, Unvote: r.Form.Get("Unvote") != ""}
+	if err := apiClient(r).Posts.Vote(vote); err != nil {
+		return err
+	}
+
+	// Return to the page the user voted from (but only use its path, so we
+	// never redirect to another site).
+	returnTo := urlTo(router.Post, "ID", strconv.Itoa(id))
+	if ref, err := url.Parse(r.Referer()); err == nil && isLocalPath(ref.Path) {
+		returnTo = &url.URL{Path: ref.Path, RawQuery: ref.RawQuery}
+	}
+	http.Redirect(w, r, returnTo.String(), http.StatusSeeOther)
+	return nil
+}
+
+// isLocalPath returns whether path is an absolute path on this site. Paths
+// that start with "//" or `/\` aren't, because browsers treat them as URLs
+// of other sites.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, `/\`)
+}
+
 func getCaseOrLowerCaseQuery(q url.Values, name string) string {
 	if v, present := q[name]; present {
 		return v[0]
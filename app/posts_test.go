@@ -17,7 +17,7 @@ func TestPost(t *testing.T) {
 	setup()
 	defer teardown()
 
-	post := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com", Body: "b"}
+	post := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com", Body: "b", Metadata: &thesrc.LinkMetadata{Description: "d"}}
 
 	var called bool
 	APIClient = &thesrc.Client{
@@ -30,6 +30,7 @@ func TestPost(t *testing.T) {
 				return post, nil
 			},
 		},
+		Comments: &thesrc.MockCommentsService{},
 	}
 
 	url, _ := router.App().Get(router.Post).URL("ID", strconv.Itoa(post.ID))
@@ -54,6 +55,31 @@ func TestPost(t *testing.T) {
 	if body.Text() != post.Body {
 		t.Errorf("got post body %q, want %q", body.Text(), post.Body)
 	}
+	if got := html.Find("p.link-description").Text(); got != post.Metadata.Description {
+		t.Errorf("got link description %q, want %q", got, post.Metadata.Description)
+	}
+}
+
+func TestPost_deadLink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	post := &thesrc.Post{ID: 1, Title: "t", LinkURL: "http://example.com/a", LinkCheck: &thesrc.LinkCheck{StatusCode: 404, Dead: true}}
+	APIClient = &thesrc.Client{
+		Posts:    &thesrc.MockPostsService{Get_: func(id int) (*thesrc.Post, error) { return post, nil }},
+		Comments: &thesrc.MockCommentsService{},
+	}
+
+	url, _ := router.App().Get(router.Post).URL("ID", strconv.Itoa(post.ID))
+	html, _ := getHTML(t, url)
+
+	status := html.Find("p.link-status.dead")
+	if status.Length() != 1 {
+		t.Fatal("dead link is not marked as dead")
+	}
+	if got, want := status.Find("a").AttrOr("href", ""), "https://web.archive.org/web/http://example.com/a"; got != want {
+		t.Errorf("got archive link %q, want %q", got, want)
+	}
 }
 
 func TestPosts(t *testing.T) {
@@ -66,6 +92,9 @@ func TestPosts(t *testing.T) {
 	APIClient = &thesrc.Client{
 		Posts: &thesrc.MockPostsService{
 			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Sort != thesrc.PostSortHot {
+					t.Errorf("got sort %q, want %q", opt.Sort, thesrc.PostSortHot)
+				}
 				called = true
 				return posts, nil
 			},
@@ -108,9 +137,22 @@ func TestSubmitPostForm(t *testing.T) {
 		Body:    "b",
 	}
 
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(token string) (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "u"}, nil
+			},
+		},
+	}
+
 	url_, _ := router.App().Get(router.SubmitPostForm).URL()
 	url_.RawQuery = url.Values{"Title": []string{want.Title}, "url": []string{want.LinkURL}, "body": []string{want.Body}}.Encode()
-	html, resp := getHTML(t, url_)
+	req, err := http.NewRequest("GET", url_.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSessionCookie(req)
+	html, resp := doHTML(t, req)
 
 	if want := http.StatusOK; resp.Code != want {
 		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
@@ -127,6 +169,47 @@ func TestSubmitPostForm(t *testing.T) {
 	}
 }
 
+func TestSubmitPostForm_prefillTitle(t *testing.T) {
+	setup()
+	defer teardown()
+
+	linkURL := "http://example.com"
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(token string) (*thesrc.User, error) {
+				return &thesrc.User{ID: 1, Login: "u"}, nil
+			},
+		},
+		Links: &thesrc.MockLinksService{
+			Preview_: func(url string) (*thesrc.LinkMetadata, error) {
+				if url != linkURL {
+					t.Errorf("got preview URL %q, want %q", url, linkURL)
+				}
+				called = true
+				return &thesrc.LinkMetadata{Title: "Fetched title"}, nil
+			},
+		},
+	}
+
+	url_, _ := router.App().Get(router.SubmitPostForm).URL()
+	url_.RawQuery = url.Values{"LinkURL": []string{linkURL}}.Encode()
+	req, err := http.NewRequest("GET", url_.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSessionCookie(req)
+	html, _ := doHTML(t, req)
+
+	if !called {
+		t.Error("!called")
+	}
+	if got, _ := html.Find("input[name=Title]").Attr("value"); got != "Fetched title" {
+		t.Errorf("got title %q, want %q", got, "Fetched title")
+	}
+}
+
 func TestSubmitPosts(t *testing.T) {
 	setup()
 	defer teardown()
@@ -148,6 +231,8 @@ func TestSubmitPosts(t *testing.T) {
 		"Title":   []string{post.Title},
 		"LinkURL": []string{post.LinkURL},
 		"Body":    []string{post.Body},
+
+		"CSRFToken": []string{testCSRFToken()},
 	}
 
 	url, _ := router.App().Get(router.SubmitPost).URL()
@@ -155,6 +240,8 @@ func TestSubmitPosts(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addSessionCookie(req)
 
 	resp := httptest.NewRecorder()
 	resp.Body = new(bytes.Buffer)
@@ -172,3 +259,245 @@ func TestSubmitPosts(t *testing.T) {
 		t.Errorf("got Location %q, want %q", loc, want)
 	}
 }
+
+func TestSubmitPostForm_loggedOut(t *testing.T) {
+	setup()
+	defer teardown()
+
+	url, _ := router.App().Get(router.SubmitPostForm).URL()
+	_, resp := getHTML(t, url)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if loc, want := resp.Header().Get("location"), urlTo(router.LogInForm).String(); loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestVotePost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Vote_: func(vote *thesrc.Vote) error {
+				if want := (thesrc.Vote{PostID: 1}); *vote != want {
+					t.Errorf("got vote %+v, want %+v", vote, want)
+				}
+				called = true
+				return nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.VotePost).URL("ID", "1")
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader("CSRFToken="+testCSRFToken()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", "http://evil.example.com/?page=2")
+	addSessionCookie(req)
+
+	resp := httptest.NewRecorder()
+	testMux.ServeHTTP(resp, req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if loc, want := resp.Header().Get("location"), "/?page=2"; loc != want {
+		t.Errorf("got Location %q, want %q", loc, want)
+	}
+}
+
+func TestVotePost_offsiteReferer(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Vote_: func(vote *thesrc.Vote) error { return nil },
+		},
+	}
+
+	url, _ := router.App().Get(router.VotePost).URL("ID", "1")
+	for _, referer := range []string{"http://example.com//evil.example.com/x", "http://example.com/%2Fevil.example.com/x", `http://example.com/\evil.example.com/x`, ""} {
+		req, err := http.NewRequest("POST", url.String(), strings.NewReader("CSRFToken="+testCSRFToken()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", referer)
+		addSessionCookie(req)
+
+		resp := httptest.NewRecorder()
+		testMux.ServeHTTP(resp, req)
+
+		if loc, want := resp.Header().Get("location"), urlTo(router.Post, "ID", "1").String(); loc != want {
+			t.Errorf("referer %q: got Location %q, want %q", referer, loc, want)
+		}
+	}
+}
+
+func TestPosts_sorted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tests := map[string]string{
+		router.NewPosts: thesrc.PostSortNew,
+		router.TopPosts: thesrc.PostSortTop,
+	}
+	for route, wantSort := range tests {
+		var called bool
+		APIClient = &thesrc.Client{
+			Posts: &thesrc.MockPostsService{
+				List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+					if opt.Sort != wantSort {
+						t.Errorf("%s: got sort %q, want %q", route, opt.Sort, wantSort)
+					}
+					called = true
+					return nil, nil
+				},
+			},
+		}
+
+		url, _ := router.App().Get(route).URL()
+		_, resp := getHTML(t, url)
+
+		if want := http.StatusOK; resp.Code != want {
+			t.Errorf("%s: got HTTP status %d, want %d", route, resp.Code, want)
+		}
+		if !called {
+			t.Errorf("%s: !called", route)
+		}
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Query != "q" {
+					t.Errorf("got query %q, want %q", opt.Query, "q")
+				}
+				if opt.Sort != "" {
+					t.Errorf("got sort %q, want relevance ranking", opt.Sort)
+				}
+				called = true
+				return []*thesrc.Post{{ID: 1, Title: "t"}}, nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(router.SearchPosts).URL()
+	u.RawQuery = url.Values{"Query": {"q"}}.Encode()
+	html, resp := getHTML(t, u)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if got := html.Find("a.post-link").Text(); got != "t" {
+		t.Errorf("got link text %q, want %q", got, "t")
+	}
+	if got, _ := html.Find("form.search-posts input[name=Query]").Attr("value"); got != "q" {
+		t.Errorf("got search box value %q, want %q", got, "q")
+	}
+}
+
+func TestTagPosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Tag != "go" {
+					t.Errorf("got tag %q, want %q", opt.Tag, "go")
+				}
+				called = true
+				return []*thesrc.Post{{ID: 1, Title: "t", Tags: []string{"go"}}}, nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(router.TagPosts).URL("Tag", "go")
+	html, resp := getHTML(t, u)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	if got, _ := html.Find(".post-container a.tag").Attr("href"); got != u.String() {
+		t.Errorf("got tag link %q, want %q", got, u.String())
+	}
+}
+
+func TestLanguagePosts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.Language != "go" {
+					t.Errorf("got language %q, want %q", opt.Language, "go")
+				}
+				called = true
+				return []*thesrc.Post{{ID: 1, Title: "t", Languages: []string{"go"}}}, nil
+			},
+		},
+	}
+
+	u, _ := router.App().Get(router.LanguagePosts).URL("Language", "go")
+	html, resp := getHTML(t, u)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+	if !called {
+		t.Error("!called")
+	}
+	badge := html.Find(".post-container a.language")
+	if got, _ := badge.Attr("href"); got != u.String() {
+		t.Errorf("got language link %q, want %q", got, u.String())
+	}
+	if got := badge.Text(); got != "Go" {
+		t.Errorf("got language badge text %q, want %q", got, "Go")
+	}
+}
+
+func TestTagCloud(t *testing.T) {
+	items := tagCloud([]*thesrc.TagCount{{Tag: "go", Count: 10}, {Tag: "c", Count: 1}, {Tag: "sql", Count: 3}})
+
+	var tags []string
+	for _, item := range items {
+		tags = append(tags, item.Tag)
+	}
+	if want := []string{"c", "go", "sql"}; strings.Join(tags, " ") != strings.Join(want, " ") {
+		t.Errorf("got tags %q, want %q", tags, want)
+	}
+	if items[0].Size != 0.8 || items[1].Size != 2.4 {
+		t.Errorf("got sizes %v and %v for least- and most-used tags, want 0.8 and 2.4", items[0].Size, items[1].Size)
+	}
+	if items[2].Size <= items[0].Size || items[2].Size >= items[1].Size {
+		t.Errorf("got size %v for middle tag, want between %v and %v", items[2].Size, items[0].Size, items[1].Size)
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// sessionCookieName is the name of the cookie that holds the session token of
+// a logged-in user.
+const sessionCookieName = "thesrc-session"
+
+// sessionToken returns the session token from r's session cookie, or an empty
+// string if r has no session.
+func sessionToken(r *http.Request) string {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, sess *thesrc.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sess.Token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// isHTTPS returns whether r was made over HTTPS, either directly or to a
+// reverse proxy that set the X-Forwarded-Proto header.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// csrfFieldName is the name of the form field that holds the CSRF token in
+// forms that change state (e.g., by submitting a post or voting).
+const csrfFieldName = "CSRFToken"
+
+// errBadCSRFToken is returned by handlers of forms that were submitted without
+// the session's CSRF token, which means that they may have been submitted by
+// another site on the user's behalf.
+var errBadCSRFToken = errors.New("invalid form token (reload the page and try again)")
+
+// csrfToken returns the CSRF token of r's session, or an empty string if r has
+// no session. It is derived from the session token, which other sites can't
+// read, so they can't forge forms that include it.
+func csrfToken(r *http.Request) string {
+	token := sessionToken(r)
+	if token == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte("csrf:" + token))
+	return hex.EncodeToString(h[:])
+}
+
+// checkCSRFToken parses r's form and returns errBadCSRFToken unless it contains
+// the CSRF token of r's session. It removes the token from r.Form, so that
+// the rest of the form can be decoded.
+func checkCSRFToken(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	got := r.PostForm.Get(csrfFieldName)
+	r.Form.Del(csrfFieldName)
+	r.PostForm.Del(csrfFieldName)
+
+	want := csrfToken(r)
+	if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return errBadCSRFToken
+	}
+	return nil
+}
+
+// apiClient returns the API client to use when handling r. If r has a session,
+// the returned client makes requests on behalf of the session's user.
+func apiClient(r *http.Request) *thesrc.Client {
+	if token := sessionToken(r); token != "" {
+		return APIClient.WithToken(token)
+	}
+	return APIClient
+}
+
+// currentUser returns the user who is logged in to r's session, or nil if
+// there is no logged-in user.
+func currentUser(r *http.Request) (*thesrc.User, error) {
+	token := sessionToken(r)
+	if token == "" {
+		return nil, nil
+	}
+
+	user, err := APIClient.Users.Authenticate(token)
+	if thesrc.IsHTTPErrorCode(err, http.StatusUnauthorized) {
+		// The session expired (sessions last for a fixed time after
+		// logging in) or was logged out.
+		return nil, nil
+	}
+	return user, err
+}
+
+// redirectToLogIn redirects to the login form. It is used by handlers that
+// require a logged-in user.
+func redirectToLogIn(w http.ResponseWriter, r *http.Request) error {
+	http.Redirect(w, r, urlTo(router.LogInForm).String(), http.StatusSeeOther)
+	return nil
+}
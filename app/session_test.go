@@ -0,0 +1,105 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestSetSessionCookie(t *testing.T) {
+	tests := map[string]bool{"": false, "http": false, "https": true}
+	for proto, wantSecure := range tests {
+		req, _ := http.NewRequest("POST", "/login", nil)
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		resp := httptest.NewRecorder()
+		setSessionCookie(resp, req, &thesrc.Session{Token: "t"})
+
+		cookies := (&http.Response{Header: resp.Header()}).Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("got cookies %+v, want 1", cookies)
+		}
+		if c := cookies[0]; c.SameSite != http.SameSiteLaxMode || c.Secure != wantSecure || !c.HttpOnly {
+			t.Errorf("X-Forwarded-Proto %q: got cookie %+v, want SameSite=Lax, Secure=%v, and HttpOnly", proto, c, wantSecure)
+		}
+	}
+}
+
+func TestCSRFToken_forms(t *testing.T) {
+	setup()
+	defer teardown()
+
+	APIClient = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Get_: func(id int) (*thesrc.Post, error) { return &thesrc.Post{ID: id}, nil },
+		},
+		Comments: &thesrc.MockCommentsService{},
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(token string) (*thesrc.User, error) { return &thesrc.User{ID: 1, Login: "u"}, nil },
+		},
+	}
+
+	url, _ := router.App().Get(router.Post).URL("ID", "1")
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSessionCookie(req)
+	html, _ := doHTML(t, req)
+
+	// The vote, comment, and logout forms must include the token.
+	forms := html.Find(`form[method="post"]`)
+	if forms.Length() != 3 {
+		t.Errorf("got %d POST forms, want 3", forms.Length())
+	}
+	for i := range forms.Nodes {
+		form := forms.Eq(i)
+		if got, _ := form.Find(`[name="CSRFToken"]`).Attr("value"); got != testCSRFToken() {
+			action, _ := form.Attr("action")
+			t.Errorf("form %s: got CSRF token %q, want %q", action, got, testCSRFToken())
+		}
+	}
+}
+
+func TestCheckCSRFToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// Only Users is set, so the handlers fail (with HTTP 500) if they get
+	// past the CSRF token check.
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Authenticate_: func(token string) (*thesrc.User, error) { return &thesrc.User{ID: 1}, nil },
+		},
+	}
+
+	urls := []*url.URL{
+		urlTo(router.SubmitPost),
+		urlTo(router.VotePost, "ID", "1"),
+		urlTo(router.SubmitComment, "ID", "1"),
+		urlTo(router.LogOut),
+	}
+	for _, u := range urls {
+		for _, token := range []string{"", "x", testCSRFToken() + "x"} {
+			req, err := http.NewRequest("POST", u.String(), strings.NewReader(url.Values{"CSRFToken": {token}, "Body": {"b"}}.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			addSessionCookie(req)
+
+			resp := httptest.NewRecorder()
+			testMux.ServeHTTP(resp, req)
+
+			if want := http.StatusForbidden; resp.Code != want {
+				t.Errorf("%s with CSRF token %q: got HTTP status %d, want %d", u, token, resp.Code, want)
+			}
+		}
+	}
+}
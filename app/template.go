@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
 )
 
 var (
@@ -22,7 +24,14 @@ func LoadTemplates() {
 	err := parseHTMLTemplates([][]string{
 		{"posts/show.html", "posts/common.html", "common.html", "layout.html"},
 		{"posts/list.html", "posts/common.html", "common.html", "layout.html"},
+		{"posts/search.html", "posts/common.html", "common.html", "layout.html"},
+		{"posts/tag.html", "posts/common.html", "common.html", "layout.html"},
+		{"posts/tags.html", "common.html", "layout.html"},
+		{"posts/language.html", "posts/common.html", "common.html", "layout.html"},
 		{"posts/submit_form.html", "common.html", "layout.html"},
+		{"users/show.html", "posts/common.html", "common.html", "layout.html"},
+		{"users/signup_form.html", "common.html", "layout.html"},
+		{"users/login_form.html", "common.html", "layout.html"},
 		{"error.html", "common.html", "layout.html"},
 	})
 	if err != nil {
@@ -33,10 +42,32 @@ func LoadTemplates() {
 // templateCommon is data that is passed to (and available to) all templates.
 type templateCommon struct {
 	CurrentURL         *url.URL
+	CurrentUser        *thesrc.User
 	PageGenerationTime time.Duration
+
+	// FeedOptions, if set, are the options of the list of posts shown on the
+	// page, so that the page's feed links list the same posts.
+	FeedOptions *thesrc.PostListOptions
 }
 
+func (c *templateCommon) common() *templateCommon { return c }
+
 func renderTemplate(w http.ResponseWriter, r *http.Request, name string, status int, data interface{}) error {
+	// Populate the common template data if data embeds templateCommon.
+	if d, ok := data.(interface {
+		common() *templateCommon
+	}); ok {
+		c := d.common()
+		c.CurrentURL = r.URL
+		user, err := currentUser(r)
+		if err != nil {
+			// Render the page as though the user were logged out instead of
+			// failing entirely (this also lets error pages render).
+			logError(r, fmt.Errorf("getting current user: %s", err), nil)
+		}
+		c.CurrentUser = user
+	}
+
 	w.WriteHeader(status)
 	if ct := w.Header().Get("content-type"); ct == "" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -47,9 +78,16 @@ func renderTemplate(w http.ResponseWriter, r *http.Request, name string, status
 		return fmt.Errorf("Template %s not found", name)
 	}
 
+	// Give forms that change state the CSRF token of r's session.
+	t, err := t.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(htmpl.FuncMap{"csrfToken": func() string { return csrfToken(r) }})
+
 	// Write to a buffer to properly catch errors and avoid partial output written to the http.ResponseWriter
 	var buf bytes.Buffer
-	err := t.Execute(&buf, data)
+	err = t.Execute(&buf, data)
 	if err != nil {
 		return err
 	}
@@ -63,9 +101,16 @@ func parseHTMLTemplates(sets [][]string) error {
 	for _, set := range sets {
 		t := htmpl.New("")
 		t.Funcs(htmpl.FuncMap{
-			"urlDomain": urlDomain,
-			"urlTo":     urlTo,
-			"itoa":      strconv.Itoa,
+			"urlDomain":    urlDomain,
+			"urlTo":        urlTo,
+			"itoa":         strconv.Itoa,
+			"feedURL":      feedURL,
+			"languageName": thesrc.LanguageName,
+			"archiveURL":   archiveURL,
+
+			// Replaced by renderTemplate with a func that returns the
+			// CSRF token of the request's session.
+			"csrfToken": func() string { return "" },
 
 			"googleAnalyticsID": func() string { return os.Getenv("GOOGLE_ANALYTICS_ID") },
 		})
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func serveUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := APIClient.Users.Get(mux.Vars(r)["Login"])
+	if err != nil {
+		return err
+	}
+
+	var opt thesrc.PostListOptions
+	if err := schemaDecoder.Decode(&opt, r.URL.Query()); err != nil {
+		return err
+	}
+	opt.AuthorUserID = user.ID
+
+	if opt.PerPage == 0 {
+		opt.PerPage = 60
+	}
+
+	posts, err := APIClient.Posts.List(&opt)
+	if err != nil {
+		return err
+	}
+
+	return renderTemplate(w, r, "users/show.html", http.StatusOK, &struct {
+		User  *thesrc.User
+		Posts []*thesrc.Post
+		templateCommon
+	}{
+		User:           user,
+		Posts:          posts,
+		templateCommon: templateCommon{FeedOptions: &opt},
+	})
+}
+
+func serveSignupForm(w http.ResponseWriter, r *http.Request) error {
+	return renderCredentialsForm(w, r, "users/signup_form.html", http.StatusOK, &thesrc.Credentials{}, nil)
+}
+
+func serveSignup(w http.ResponseWriter, r *http.Request) error {
+	cred, err := parseCredentialsForm(r)
+	if err != nil {
+		return err
+	}
+
+	user := &thesrc.User{Login: cred.Login}
+	if err := APIClient.Users.Signup(user, cred.Password); err != nil {
+		if status, ok := clientErrorStatus(err); ok {
+			return renderCredentialsForm(w, r, "users/signup_form.html", status, cred, err)
+		}
+		return err
+	}
+
+	return logIn(w, r, cred)
+}
+
+func serveLogInForm(w http.ResponseWriter, r *http.Request) error {
+	return renderCredentialsForm(w, r, "users/login_form.html", http.StatusOK, &thesrc.Credentials{}, nil)
+}
+
+func serveLogIn(w http.ResponseWriter, r *http.Request) error {
+	cred, err := parseCredentialsForm(r)
+	if err != nil {
+		return err
+	}
+	return logIn(w, r, cred)
+}
+
+// logIn logs in with cred, sets the session cookie, and redirects to the
+// homepage.
+func logIn(w http.ResponseWriter, r *http.Request, cred *thesrc.Credentials) error {
+	sess, err := APIClient.Users.Login(cred)
+	if err != nil {
+		if status, ok := clientErrorStatus(err); ok {
+			return renderCredentialsForm(w, r, "users/login_form.html", status, cred, err)
+		}
+		return err
+	}
+
+	setSessionCookie(w, r, sess)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+func serveLogOut(w http.ResponseWriter, r *http.Request) error {
+	if token := sessionToken(r); token != "" {
+		if err := checkCSRFToken(r); err != nil {
+			return err
+		}
+		if err := APIClient.Users.Logout(token); err != nil {
+			return err
+		}
+	}
+
+	clearSessionCookie(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+func parseCredentialsForm(r *http.Request) (*thesrc.Credentials, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	var cred thesrc.Credentials
+	if err := schemaDecoder.Decode(&cred, r.Form); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func renderCredentialsForm(w http.ResponseWriter, r *http.Request, name string, status int, cred *thesrc.Credentials, err error) error {
+	var errMsg string
+	if e, ok := err.(*thesrc.ErrorResponse); ok {
+		errMsg = e.Message
+	} else if err != nil {
+		errMsg = err.Error()
+	}
+
+	return renderTemplate(w, r, name, status, &struct {
+		Login string
+		Error string
+		templateCommon
+	}{
+		Login: cred.Login,
+		Error: errMsg,
+	})
+}
+
+// clientErrorStatus returns the HTTP status code of err if err is an API
+// error caused by bad user input (i.e., a 4xx error).
+func clientErrorStatus(err error) (int, bool) {
+	if e, ok := err.(*thesrc.ErrorResponse); ok && e.Response != nil {
+		if c := e.HTTPStatusCode(); c >= 400 && c < 500 {
+			return c, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	user := &thesrc.User{ID: 1, Login: "u"}
+	posts := []*thesrc.Post{{ID: 1, Title: "t", LinkURL: "http://example.com", AuthorUserID: 1}}
+
+	var calledList bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Get_: func(login string) (*thesrc.User, error) {
+				if login != user.Login {
+					t.Errorf("got login %q, want %q", login, user.Login)
+				}
+				return user, nil
+			},
+		},
+		Posts: &thesrc.MockPostsService{
+			List_: func(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+				if opt.AuthorUserID != user.ID {
+					t.Errorf("got AuthorUserID %d, want %d", opt.AuthorUserID, user.ID)
+				}
+				calledList = true
+				return posts, nil
+			},
+		},
+	}
+
+	url, _ := router.App().Get(router.User).URL("Login", user.Login)
+	html, resp := getHTML(t, url)
+
+	if want := http.StatusOK; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !calledList {
+		t.Error("!calledList")
+	}
+
+	if got := html.Find("h1.user-login").Text(); got != user.Login {
+		t.Errorf("got user login %q, want %q", got, user.Login)
+	}
+	if got := html.Find("a.post-link").Text(); got != posts[0].Title {
+		t.Errorf("got link text %q, want %q", got, posts[0].Title)
+	}
+}
+
+func TestLogIn(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	APIClient = &thesrc.Client{
+		Users: &thesrc.MockUsersService{
+			Login_: func(cred *thesrc.Credentials) (*thesrc.Session, error) {
+				if cred.Login != "u" || cred.Password != "secret" {
+					t.Errorf("got credentials %+v", cred)
+				}
+				called = true
+				return &thesrc.Session{Token: "t", UserID: 1}, nil
+			},
+		},
+	}
+
+	v := url.Values{"Login": []string{"u"}, "Password": []string{"secret"}}
+	url, _ := router.App().Get(router.LogIn).URL()
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp := httptest.NewRecorder()
+	resp.Body = new(bytes.Buffer)
+	testMux.ServeHTTP(resp, req)
+
+	if want := http.StatusSeeOther; resp.Code != want {
+		t.Errorf("got HTTP status %d, want %d", resp.Code, want)
+	}
+
+	if !called {
+		t.Error("!called")
+	}
+
+	if cookie := resp.Header().Get("Set-Cookie"); !strings.HasPrefix(cookie, sessionCookieName+"=t;") {
+		t.Errorf("got Set-Cookie %q, want session cookie with token", cookie)
+	}
+}
@@ -0,0 +1,232 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// NaiveBayes is a multinomial naive Bayes classifier over the tokens of a
+// post's page (see Tokenize). It is trained from posts whose classifications
+// moderators have confirmed (see Train), and it is saved to and loaded from
+// JSON files.
+type NaiveBayes struct {
+	// Docs is the number of training examples with each label.
+	Docs map[string]int
+
+	// Tokens is the number of times that each token occurs in the training
+	// examples with each label.
+	Tokens map[string]map[string]int
+
+	// TokenTotals is the total number of tokens in the training examples
+	// with each label.
+	TokenTotals map[string]int
+
+	// Vocabulary is the number of distinct tokens in the training examples.
+	Vocabulary int
+}
+
+// labels are the labels that NaiveBayes chooses between.
+var labels = []string{thesrc.ClassificationCode, thesrc.ClassificationNotCode}
+
+// An Example is a post whose classification is known, used to train and
+// evaluate classifiers.
+type Example struct {
+	Post *thesrc.Post
+
+	// Page is the HTML document at the post's link.
+	Page *goquery.Document
+
+	// Label is the post's correct classification.
+	Label string
+}
+
+// Train returns a NaiveBayes classifier trained on examples.
+func Train(examples []*Example) *NaiveBayes {
+	nb := &NaiveBayes{
+		Docs:        map[string]int{},
+		Tokens:      map[string]map[string]int{},
+		TokenTotals: map[string]int{},
+	}
+	vocab := map[string]bool{}
+	for _, e := range examples {
+		nb.Docs[e.Label]++
+		counts := nb.Tokens[e.Label]
+		if counts == nil {
+			counts = map[string]int{}
+			nb.Tokens[e.Label] = counts
+		}
+		for _, tok := range Tokenize(e.Post, e.Page) {
+			counts[tok]++
+			nb.TokenTotals[e.Label]++
+			vocab[tok] = true
+		}
+	}
+	nb.Vocabulary = len(vocab)
+	return nb
+}
+
+// Classify implements Classifier. It can't tell if it hasn't been trained
+// on examples with both labels or if post has no page.
+func (nb *NaiveBayes) Classify(post *thesrc.Post, page *goquery.Document) (*Result, error) {
+	if page == nil {
+		return nil, nil
+	}
+	var numDocs int
+	for _, label := range labels {
+		if nb.Docs[label] == 0 {
+			return nil, nil
+		}
+		numDocs += nb.Docs[label]
+	}
+
+	// Compute the log probability of each label given the page's tokens,
+	// with add-one smoothing. Tokens that weren't in any training example
+	// say nothing about the label, so they are skipped.
+	logProbs := map[string]float64{}
+	for _, label := range labels {
+		logProbs[label] = math.Log(float64(nb.Docs[label]) / float64(numDocs))
+	}
+	for _, tok := range Tokenize(post, page) {
+		if !nb.known(tok) {
+			continue
+		}
+		for _, label := range labels {
+			logProbs[label] += math.Log(float64(nb.Tokens[label][tok]+1) / float64(nb.TokenTotals[label]+nb.Vocabulary))
+		}
+	}
+
+	pCode := 1 / (1 + math.Exp(logProbs[thesrc.ClassificationNotCode]-logProbs[thesrc.ClassificationCode]))
+	r := &Result{Features: pageFeatures(post, page)}
+	if pCode > 0.5 {
+		r.Label, r.Confidence = thesrc.ClassificationCode, pCode
+	} else {
+		r.Label, r.Confidence = thesrc.ClassificationNotCode, 1-pCode
+	}
+	return r, nil
+}
+
+// known returns whether tok occurred in any training example.
+func (nb *NaiveBayes) known(tok string) bool {
+	for _, label := range labels {
+		if nb.Tokens[label][tok] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes nb to the file at path.
+func (nb *NaiveBayes) Save(path string) error {
+	data, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadNaiveBayes reads a NaiveBayes classifier from the file at path (which
+// was written by Save).
+func LoadNaiveBayes(path string) (*NaiveBayes, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nb *NaiveBayes
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("invalid classifier model %s: %s", path, err)
+	}
+	return nb, nil
+}
+
+var (
+	loadedModelMu sync.RWMutex
+	loadedModel   *NaiveBayes
+)
+
+// LoadModel loads the NaiveBayes classifier at path (see LoadNaiveBayes)
+// and makes it available as the "bayes" classifier. The default classifier
+// uses it instead of CodeRatio once it is loaded.
+func LoadModel(path string) error {
+	nb, err := LoadNaiveBayes(path)
+	if err != nil {
+		return err
+	}
+	loadedModelMu.Lock()
+	defer loadedModelMu.Unlock()
+	loadedModel = nb
+	return nil
+}
+
+// trained classifies posts with the model loaded by LoadModel, or with
+// fallback if no model is loaded. If fallback is nil and no model is loaded,
+// it can't tell.
+type trained struct{ fallback Classifier }
+
+func (c trained) Classify(post *thesrc.Post, page *goquery.Document) (*Result, error) {
+	loadedModelMu.RLock()
+	nb := loadedModel
+	loadedModelMu.RUnlock()
+	if nb != nil {
+		return nb.Classify(post, page)
+	}
+	if c.fallback != nil {
+		return c.fallback.Classify(post, page)
+	}
+	return nil, nil
+}
+
+// maxTokens is the maximum number of tokens that Tokenize returns for a page
+// (plus the tokens describing the page as a whole), so that long pages don't
+// swamp the model.
+const maxTokens = 5000
+
+var wordPattern = regexp.MustCompile(`[a-z_][a-z0-9_]{1,29}`)
+
+// Tokenize returns the tokens that NaiveBayes uses to classify post, given
+// its page. They are the words in the page's text (with words in code
+// prefixed by "code:"), and tokens describing the link's domain, the page's
+// programming languages, and how much of its text is code.
+func Tokenize(post *thesrc.Post, page *goquery.Document) []string {
+	var toks []string
+	if u, err := url.Parse(post.LinkURL); err == nil && u.Host != "" {
+		toks = append(toks, "domain:"+strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."))
+	}
+	if page == nil {
+		return toks
+	}
+
+	f := pageFeatures(post, page)
+	for _, lang := range f.Languages {
+		toks = append(toks, "lang:"+lang)
+	}
+	var ratio int
+	if f.TotalBytes > 0 {
+		ratio = int(math.Min(10*float64(f.CodeBytes)/float64(f.TotalBytes), 10))
+	}
+	toks = append(toks, fmt.Sprintf("coderatio:%d", ratio))
+
+	n := 0
+	add := func(prefix, text string) {
+		for _, w := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+			if n >= maxTokens {
+				return
+			}
+			toks = append(toks, prefix+w)
+			n++
+		}
+	}
+	add("code:", page.Find("pre, code").Text())
+	body := page.Find("body").Clone()
+	body.Find("pre, code, script, style").Remove()
+	add("", page.Find("title").Text()+" "+body.Text())
+	return toks
+}
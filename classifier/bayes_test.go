@@ -0,0 +1,147 @@
+package classifier
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func trainingExamples(t *testing.T) []*Example {
+	code := []string{
+		`<p>How to parse JSON.</p><pre>func main() { json.Unmarshal(data, &v) }</pre>`,
+		`<p>Implementing a parser.</p><pre>func parse(s string) (*Node, error) { return nil, nil }</pre>`,
+		`<p>A tutorial on goroutines.</p><pre>go func() { ch <- v }()</pre>`,
+	}
+	notCode := []string{
+		`<p>The startup raised money from investors at a high valuation.</p>`,
+		`<p>Investors say the company's valuation is too high.</p>`,
+		`<p>Hiring managers and founders discuss the market.</p>`,
+	}
+	var examples []*Example
+	for i, html := range append(code, notCode...) {
+		label := thesrc.ClassificationCode
+		if i >= len(code) {
+			label = thesrc.ClassificationNotCode
+		}
+		examples = append(examples, &Example{Post: &thesrc.Post{ID: i + 1}, Page: parsePage(t, html), Label: label})
+	}
+	return examples
+}
+
+func TestNaiveBayes(t *testing.T) {
+	nb := Train(trainingExamples(t))
+
+	tests := []struct {
+		html      string
+		wantLabel string
+	}{
+		{`<p>Parsing with a func.</p><pre>func f() error { return json.Unmarshal(nil, nil) }</pre>`, thesrc.ClassificationCode},
+		{`<p>Founders and investors disagree about the valuation.</p>`, thesrc.ClassificationNotCode},
+	}
+	for _, test := range tests {
+		r, err := nb.Classify(&thesrc.Post{}, parsePage(t, test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r == nil || r.Label != test.wantLabel {
+			t.Errorf("%.40q: got result %v, want %s", test.html, r, test.wantLabel)
+			continue
+		}
+		if r.Confidence < 0.5 || r.Confidence > 1 {
+			t.Errorf("%.40q: got confidence %v, want between 0.5 and 1", test.html, r.Confidence)
+		}
+	}
+
+	if r, _ := nb.Classify(&thesrc.Post{}, nil); r != nil {
+		t.Errorf("got result %v for post without page, want nil", r)
+	}
+	if r, _ := Train(nil).Classify(&thesrc.Post{}, parsePage(t, "<p>x</p>")); r != nil {
+		t.Errorf("got result %v from untrained classifier, want nil", r)
+	}
+}
+
+func TestNaiveBayes_SaveLoad(t *testing.T) {
+	nb := Train(trainingExamples(t))
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := nb.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	nb2, err := LoadNaiveBayes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nb2, nb) {
+		t.Errorf("got loaded model %+v, want %+v", nb2, nb)
+	}
+
+	if r, _ := Get("bayes").Classify(&thesrc.Post{}, parsePage(t, "<p>investors</p>")); r != nil {
+		t.Errorf("got result %v from bayes classifier before loading a model, want nil", r)
+	}
+	if err := LoadModel(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { loadedModel = nil }()
+	if r, _ := Get("bayes").Classify(&thesrc.Post{}, parsePage(t, "<p>investors</p>")); r == nil || r.Label != thesrc.ClassificationNotCode {
+		t.Errorf("got result %v from bayes classifier after loading a model, want %s", r, thesrc.ClassificationNotCode)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	page := parsePage(t, `<title>Title</title><p>Some prose</p><pre class="language-go">x := fmt.Sprint(y)</pre><script>ignored()</script>`)
+	toks := Tokenize(&thesrc.Post{LinkURL: "https://www.example.com/a"}, page)
+	got := strings.Join(toks, " ")
+	for _, want := range []string{"domain:example.com", "lang:go", "code:sprint", "title", "prose"} {
+		if !containsString(toks, want) {
+			t.Errorf("got tokens %q, want %q among them", got, want)
+		}
+	}
+	if containsString(toks, "ignored") || containsString(toks, "sprint") {
+		t.Errorf("got tokens %q, want no script or (unprefixed) code words", got)
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, s2 := range ss {
+		if s2 == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHeldOut(t *testing.T) {
+	var n int
+	for id := 1; id <= 1000; id++ {
+		if HeldOut(id, 0.2) != HeldOut(id, 0.2) {
+			t.Fatalf("HeldOut(%d) is not deterministic", id)
+		}
+		if HeldOut(id, 0.2) {
+			n++
+		}
+	}
+	if n < 150 || n > 250 {
+		t.Errorf("got %d of 1000 posts held out, want about 200", n)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	code := constClassifier{&Result{Label: thesrc.ClassificationCode, Confidence: 1}}
+	examples := []*Example{
+		{Label: thesrc.ClassificationCode},
+		{Label: thesrc.ClassificationNotCode},
+		{Label: thesrc.ClassificationNotCode},
+	}
+	e, err := Evaluate(code, examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Evaluation{TruePositives: 1, FalsePositives: 2}); !reflect.DeepEqual(e, want) {
+		t.Errorf("got evaluation %+v, want %+v", e, want)
+	}
+	if p, r := e.Precision(), e.Recall(); p != 1.0/3 || r != 1 {
+		t.Errorf("got precision %v and recall %v, want 1/3 and 1", p, r)
+	}
+}
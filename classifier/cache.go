@@ -0,0 +1,324 @@
+package classifier
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peterbourgon/diskv"
+	"github.com/sourcegraph/httpcache"
+)
+
+// A Cache is an on-disk cache of HTTP responses (an httpcache.Cache) that
+// holds at most MaxSize bytes, evicting the least recently stored responses
+// to stay within that size.
+type Cache struct {
+	// Dir is the directory that the cache stores responses in.
+	Dir string
+
+	// MaxSize is the maximum total size of the cached responses, in bytes.
+	MaxSize int64
+
+	// TTL is how long cached responses are used without checking whether
+	// they have changed. After that, they are revalidated according to
+	// their HTTP caching headers (if any).
+	TTL time.Duration
+
+	d *diskv.Diskv
+
+	mu   sync.Mutex
+	size int64 // total size of cached responses, or -1 if not yet known
+}
+
+// NewCache returns a Cache that stores responses in dir. It doesn't create
+// dir until it stores a response.
+func NewCache(dir string, maxSize int64, ttl time.Duration) *Cache {
+	return &Cache{
+		Dir:     dir,
+		MaxSize: maxSize,
+		TTL:     ttl,
+		d: diskv.New(diskv.Options{
+			BasePath: dir,
+			// Spread responses across subdirectories by the first 2
+			// characters of their keys' hashes.
+			Transform: func(s string) []string { return []string{s[:2]} },
+			PathPerm:  0700,
+			FilePerm:  0600,
+		}),
+		size: -1,
+	}
+}
+
+// diskKey returns the key that the response cached under key is stored
+// under on disk. Cache keys are URLs, which aren't valid filenames.
+func diskKey(key string) string {
+	h := sha1.Sum([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// isDiskKey returns whether name is a disk key (see diskKey).
+func isDiskKey(name string) bool {
+	if len(name) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// path returns the path to the file that the response with the given disk
+// key is stored in.
+func (c *Cache) path(dk string) string {
+	return filepath.Join(c.Dir, dk[:2], dk)
+}
+
+// Get implements httpcache.Cache.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	b, err := c.d.Read(diskKey(key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// getFresh returns the response cached under key if it was stored less than
+// c.TTL ago.
+func (c *Cache) getFresh(key string) ([]byte, bool) {
+	dk := diskKey(key)
+	fi, err := os.Stat(c.path(dk))
+	if err != nil || time.Since(fi.ModTime()) >= c.TTL {
+		return nil, false
+	}
+	b, err := c.d.Read(dk)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// evictFraction is the fraction of MaxSize that Set evicts responses down to
+// when the cache grows larger than MaxSize. Evicting more than is needed to
+// fit the new response means that Set doesn't have to list the cached
+// responses (to find the least recently stored ones) again until the cache
+// grows by a tenth of MaxSize.
+const evictFraction = 0.9
+
+// Set implements httpcache.Cache.
+func (c *Cache) Set(key string, resp []byte) {
+	dk := diskKey(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size == -1 {
+		c.size, _ = c.computeSize()
+	}
+	if fi, err := os.Stat(c.path(dk)); err == nil {
+		c.size -= fi.Size()
+	}
+	if err := c.d.Write(dk, resp); err != nil {
+		return
+	}
+	c.size += int64(len(resp))
+	if c.size > c.MaxSize {
+		c.evict(int64(float64(c.MaxSize) * evictFraction))
+	}
+}
+
+// Delete implements httpcache.Cache.
+func (c *Cache) Delete(key string) {
+	dk := diskKey(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, err := os.Stat(c.path(dk))
+	if err != nil {
+		return
+	}
+	if err := c.d.Erase(dk); err == nil && c.size != -1 {
+		c.size -= fi.Size()
+	}
+}
+
+// A cacheEntry is a file containing a cached response.
+type cacheEntry struct {
+	diskKey string
+	size    int64
+	stored  time.Time
+}
+
+// entries returns the cache's entries, least recently stored first.
+func (c *Cache) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+	for dk := range c.d.Keys(nil) {
+		if !isDiskKey(dk) {
+			continue // not a cached response
+		}
+		fi, err := os.Stat(c.path(dk))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // deleted concurrently
+			}
+			return nil, err
+		}
+		entries = append(entries, cacheEntry{diskKey: dk, size: fi.Size(), stored: fi.ModTime()})
+	}
+	sort.Sort(cacheEntriesByStored(entries))
+	return entries, nil
+}
+
+type cacheEntriesByStored []cacheEntry
+
+func (v cacheEntriesByStored) Len() int           { return len(v) }
+func (v cacheEntriesByStored) Less(i, j int) bool { return v[i].stored.Before(v[j].stored) }
+func (v cacheEntriesByStored) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
+// computeSize returns the total size of the cached responses. The caller
+// must hold c.mu.
+func (c *Cache) computeSize() (int64, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return -1, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	return size, nil
+}
+
+// evict removes the least recently stored responses until the cache holds
+// at most maxSize bytes, returning the number of responses removed. The
+// caller must hold c.mu.
+func (c *Cache) evict(maxSize int64) (int, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	var removed int
+	for _, e := range entries {
+		if size <= maxSize {
+			break
+		}
+		if err := c.d.Erase(e.diskKey); err != nil && !os.IsNotExist(err) {
+			c.size = -1
+			return removed, err
+		}
+		size -= e.size
+		removed++
+	}
+	c.size = size
+	return removed, nil
+}
+
+// CacheStats describes the contents of a Cache.
+type CacheStats struct {
+	// Entries is the number of cached responses.
+	Entries int
+
+	// Size is the total size of the cached responses, in bytes.
+	Size int64
+
+	// Expired is the number of cached responses that are older than the
+	// cache's TTL.
+	Expired int
+
+	// Oldest and Newest are the times that the least and most recently
+	// stored responses were stored.
+	Oldest, Newest time.Time
+}
+
+// Stats returns statistics about the cache's contents.
+func (c *Cache) Stats() (*CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	s := &CacheStats{Entries: len(entries)}
+	for _, e := range entries {
+		s.Size += e.size
+		if time.Since(e.stored) >= c.TTL {
+			s.Expired++
+		}
+	}
+	if len(entries) > 0 {
+		s.Oldest, s.Newest = entries[0].stored, entries[len(entries)-1].stored
+	}
+	return s, nil
+}
+
+// Prune removes the cached responses that are older than the cache's TTL,
+// and then the least recently stored responses until the cache is within
+// its size limit. It returns the number of responses removed.
+func (c *Cache) Prune() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	var removed int
+	for _, e := range entries {
+		if time.Since(e.stored) < c.TTL {
+			break
+		}
+		if err := c.d.Erase(e.diskKey); err != nil && !os.IsNotExist(err) {
+			c.size = -1
+			return removed, err
+		}
+		removed++
+	}
+	n, err := c.evict(c.MaxSize)
+	return removed + n, err
+}
+
+// Clear removes all cached responses. It leaves files in c.Dir that aren't
+// cached responses alone.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.evict(0)
+	return err
+}
+
+// Transport returns an http.RoundTripper that caches the responses to GET
+// requests made with next in c. Responses stored less than c.TTL ago are
+// used without making a request; older responses are revalidated according
+// to their HTTP caching headers (e.g., with If-None-Match requests if they
+// have ETags).
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &ttlTransport{
+		cache: c,
+		next:  &httpcache.Transport{Transport: next, Cache: c, MarkCachedResponses: true},
+	}
+}
+
+// ttlTransport serves requests from the cache without revalidating them
+// while the cached responses are younger than the cache's TTL.
+type ttlTransport struct {
+	cache *Cache
+	next  http.RoundTripper
+}
+
+func (t *ttlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == "GET" && req.Header.Get("Range") == "" {
+		if b, ok := t.cache.getFresh(req.URL.String()); ok {
+			if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req); err == nil {
+				resp.Header.Set(httpcache.XFromCache, "1")
+				return resp, nil
+			}
+		}
+	}
+	return t.next.RoundTrip(req)
+}
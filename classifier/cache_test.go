@@ -0,0 +1,162 @@
+package classifier
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/httpcache"
+)
+
+// age sets the time that the response cached under key was stored to d ago.
+func age(t *testing.T, c *Cache, key string, d time.Duration) {
+	when := time.Now().Add(-d)
+	if err := os.Chtimes(c.path(diskKey(key)), when, when); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache_sizeLimit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := NewCache(dir, 100, time.Hour)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("NewCache created the cache directory (Stat error %v), want it to be created when needed", err)
+	}
+
+	resp := []byte(strings.Repeat("x", 40))
+	c.Set("a", resp)
+	age(t, c, "a", 2*time.Minute)
+	c.Set("b", resp)
+	age(t, c, "b", time.Minute)
+	c.Set("c", resp) // exceeds the size limit, so "a" is evicted
+
+	for key, want := range map[string]bool{"a": false, "b": true, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s: got cached %v, want %v", key, ok, want)
+		}
+	}
+
+	s, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries != 2 || s.Size != 80 {
+		t.Errorf("got stats %+v, want 2 entries of 80 bytes", s)
+	}
+}
+
+func TestCache_sizeLimit_lowWaterMark(t *testing.T) {
+	c := NewCache(t.TempDir(), 100, time.Hour)
+
+	resp := []byte(strings.Repeat("x", 25))
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Set(key, resp)
+		age(t, c, key, time.Duration(5-i)*time.Minute)
+	}
+	// Exceeds the size limit, so responses are evicted until the cache is
+	// within 90% of it ("a" and "b", not just "a").
+	c.Set("e", resp)
+
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true, "d": true, "e": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s: got cached %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestCache_PruneClear(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(dir, 1000, time.Hour)
+	c.Set("old", []byte("x"))
+	age(t, c, "old", 2*time.Hour)
+	c.Set("new", []byte("x"))
+
+	// Files that aren't cached responses are left alone.
+	other := filepath.Join(dir, "other")
+	if err := ioutil.WriteFile(other, []byte("y"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries != 2 || s.Expired != 1 {
+		t.Errorf("got stats %+v, want 2 entries, 1 expired", s)
+	}
+
+	if n, err := c.Prune(); err != nil || n != 1 {
+		t.Errorf("Prune: got %d removed and error %v, want 1 and nil", n, err)
+	}
+	if _, ok := c.Get("old"); ok {
+		t.Error("expired response is still cached after Prune")
+	}
+	if _, ok := c.Get("new"); !ok {
+		t.Error("unexpired response was removed by Prune")
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := c.Stats(); s.Entries != 0 {
+		t.Errorf("got %d entries after Clear, want 0", s.Entries)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Clear removed a file that isn't a cached response: %v", err)
+	}
+}
+
+func TestCache_Transport(t *testing.T) {
+	var hits int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("live"))
+	}))
+	defer s.Close()
+
+	c := NewCache(t.TempDir(), 1<<20, time.Hour)
+	cached := &http.Response{
+		StatusCode:    http.StatusOK,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          ioutil.NopCloser(strings.NewReader("cached")),
+		ContentLength: int64(len("cached")),
+	}
+	b, err := httputil.DumpResponse(cached, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set(s.URL, b)
+
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+	get := func() (string, *http.Response) {
+		resp, err := client.Get(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body), resp
+	}
+
+	// Fresh responses are served from the cache.
+	if body, resp := get(); body != "cached" || resp.Header.Get(httpcache.XFromCache) == "" || hits != 0 {
+		t.Errorf("got body %q (%d requests to server), want cached response", body, hits)
+	}
+
+	// Responses older than the TTL aren't.
+	age(t, c, s.URL, 2*time.Hour)
+	if body, _ := get(); body != "live" || hits != 1 {
+		t.Errorf("got body %q (%d requests to server), want response from server", body, hits)
+	}
+}
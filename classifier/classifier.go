@@ -2,73 +2,176 @@ package classifier
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/peterbourgon/diskv"
-	"github.com/sourcegraph/httpcache/diskcache"
 	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/safehttp"
 )
 
-func Classify(post *thesrc.Post) (string, error) {
+// A Classifier determines whether a post's link contains code.
+type Classifier interface {
+	// Classify classifies post, given page, the HTML document at post's link
+	// URL (or nil if the post has no link). If the classifier can't tell (for
+	// example, if it only looks at link URLs and doesn't recognize post's), it
+	// returns a nil Result.
+	Classify(post *thesrc.Post, page *goquery.Document) (*Result, error)
+}
+
+// A Result is the output of a classifier.
+type Result struct {
+	// Label is thesrc.ClassificationCode or thesrc.ClassificationNotCode.
+	Label string
+
+	// Confidence is the classifier's confidence in Label, from 0.5 (a
+	// guess) to 1 (certain).
+	Confidence float64
+
+	// Features are the properties of the page that the classifier found.
+	Features Features
+}
+
+// Features are properties of a page that classifiers use.
+type Features struct {
+	// CodeBytes is the length of the text in the page's code and pre
+	// elements.
+	CodeBytes int `json:",omitempty"`
+
+	// TotalBytes is the length of the text in the page's body.
+	TotalBytes int `json:",omitempty"`
+
+	// Languages are the programming languages of the code on the page, in
+	// sorted order.
+	Languages []string `json:",omitempty"`
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s (%.0f%% confidence, %d/%d code bytes, languages %v)", r.Label, r.Confidence*100, r.Features.CodeBytes, r.Features.TotalBytes, r.Features.Languages)
+}
+
+// DefaultName is the name of the classifier used if none is specified.
+const DefaultName = "default"
+
+func init() {
+	Register("code-ratio", CodeRatio{})
+	Register("domain", Domain{})
+	Register("bayes", trained{})
+	Register(DefaultName, Combine(trained{fallback: CodeRatio{}}, Domain{}))
+}
+
+var classifiers = map[string]Classifier{}
+
+// Register makes a classifier available by name. It panics if a classifier
+// is already registered with the same name.
+func Register(name string, c Classifier) {
+	if _, dup := classifiers[name]; dup {
+		panic("classifier: Register called twice for " + name)
+	}
+	classifiers[name] = c
+}
+
+// Get returns the classifier registered with the given name, or nil if there
+// is none.
+func Get(name string) Classifier {
+	return classifiers[name]
+}
+
+// Names returns the names of the registered classifiers, in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(classifiers))
+	for name := range classifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Classify fetches post's link and classifies the post with c. It returns a
+// nil Result if post has no link or c can't tell.
+func Classify(post *thesrc.Post, c Classifier) (*Result, error) {
 	if post.LinkURL == "" {
-		return "", nil
+		return nil, nil
 	}
 
-	resp, err := httpClient.Get(post.LinkURL)
+	page, err := FetchPage(post.LinkURL)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return c.Classify(post, page)
+}
+
+// FetchPage fetches and parses the HTML document at url. The document's Url
+// is the URL that it was fetched from after following redirects.
+func FetchPage(url string) (*goquery.Document, error) {
+	resp, err := getHTTPClient().Get(url)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
 	}
 
-	page, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	doc.Url = resp.Request.URL
+	return doc, nil
+}
 
-	allLen := len(page.Find("body").Text())
-	codeLen := len(page.Find("code").Text() + page.Find("pre").Text()) // might double-count
+var (
+	// HTTPCacheDir is the directory that fetched pages are cached in. If
+	// empty, pages aren't cached.
+	HTTPCacheDir = filepath.Join(os.TempDir(), "thesrc-http-cache")
 
-	prop := float64(codeLen) / float64(allLen)
-	summary := fmt.Sprintf("%.1f%% code (%d/%d)", prop, codeLen, allLen)
+	// HTTPCacheMaxSize is the maximum total size of the cached pages, in
+	// bytes.
+	HTTPCacheMaxSize int64 = 1 << 30 // 1 GB
+
+	// HTTPCacheTTL is how long cached pages are used without checking
+	// whether they have changed.
+	HTTPCacheTTL = 24 * time.Hour
+)
 
-	var classif string
-	if prop > 0.07 || codeLen > 300 {
-		classif = "CODE"
-	} else {
-		classif = "NOTCODE"
+// HTTPCache returns the cache of fetched pages configured by HTTPCacheDir,
+// HTTPCacheMaxSize, and HTTPCacheTTL, or nil if caching is disabled.
+func HTTPCache() *Cache {
+	if HTTPCacheDir == "" {
+		return nil
 	}
+	return NewCache(HTTPCacheDir, HTTPCacheMaxSize, HTTPCacheTTL)
+}
 
-	return classif + " " + summary, nil
+// Transport is the HTTP transport that fetches pages. Because posts' links
+// are user-submitted, it only connects to public addresses, and it only
+// reads HTML and plain text responses of limited size.
+var Transport http.RoundTripper = &safehttp.Transport{
+	ContentTypes: []string{"text/html", "application/xhtml+xml", "text/plain"},
 }
 
 var (
-	// httpCacheDir is the directory used for caching HTTP responses. It can be reused
-	// executions (it is not necessary to create a new random temp dir upon
-	// startup).
-	httpCacheDir = "/tmp/thesrc-http-cache"
-
-	localCache = diskcache.NewWithDiskv(diskv.New(diskv.Options{
-		BasePath:     httpCacheDir,
-		CacheSizeMax: 50 * 1024 * 1024 * 1024, // 50 GB
-	}))
-
-	httpClient = &http.Client{
-		//Transport: &httpcache.Transport{Cache: localCache},
-		Timeout: time.Second * 3,
-		// TODO(sqs): add timeout
-	}
+	httpClientOnce sync.Once
+	httpClient     *http.Client
 )
 
-func init() {
-	if err := os.Mkdir(httpCacheDir, 0700); err != nil && !os.IsExist(err) {
-		log.Fatalf("Mkdir(%s) failed: %s", httpCacheDir, err)
-	}
+// getHTTPClient returns the HTTP client that fetches pages. It is created
+// (with Transport and the cache configured by the HTTPCache* vars) when it
+// is first used.
+func getHTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		rt := Transport
+		if c := HTTPCache(); c != nil {
+			rt = c.Transport(rt)
+		}
+		httpClient = safehttp.NewClient(rt)
+	})
+	return httpClient
 }
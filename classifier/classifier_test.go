@@ -0,0 +1,145 @@
+package classifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func parsePage(t *testing.T, html string) *goquery.Document {
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestCodeRatio(t *testing.T) {
+	prose := strings.Repeat("Some words about things. ", 40)
+	tests := []struct {
+		html      string
+		wantLabel string
+		wantLangs []string
+	}{
+		{
+			html:      `<body><p>` + prose + `</p><pre class="language-go"><code>` + strings.Repeat("x := 1\n", 20) + `</code></pre></body>`,
+			wantLabel: thesrc.ClassificationCode,
+			wantLangs: []string{"go"},
+		},
+		{
+			html:      `<body><p>` + prose + `</p><code>x</code></body>`,
+			wantLabel: thesrc.ClassificationNotCode,
+		},
+		{
+			html:      `<body></body>`,
+			wantLabel: thesrc.ClassificationNotCode,
+		},
+	}
+	for _, test := range tests {
+		r, err := CodeRatio{}.Classify(&thesrc.Post{}, parsePage(t, test.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Label != test.wantLabel {
+			t.Errorf("%.40q: got label %q, want %q", test.html, r.Label, test.wantLabel)
+		}
+		if r.Confidence < 0.5 || r.Confidence > 1 {
+			t.Errorf("%.40q: got confidence %v, want between 0.5 and 1", test.html, r.Confidence)
+		}
+		if !reflect.DeepEqual(r.Features.Languages, test.wantLangs) {
+			t.Errorf("%.40q: got languages %q, want %q", test.html, r.Features.Languages, test.wantLangs)
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	r, err := Domain{}.Classify(&thesrc.Post{LinkURL: "https://www.github.com/a/b"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Label != thesrc.ClassificationCode {
+		t.Errorf("got result %v, want %s", r, thesrc.ClassificationCode)
+	}
+
+	r, err = Domain{}.Classify(&thesrc.Post{LinkURL: "http://example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Errorf("got result %v for unknown domain, want nil", r)
+	}
+}
+
+type constClassifier struct{ r *Result }
+
+func (c constClassifier) Classify(*thesrc.Post, *goquery.Document) (*Result, error) { return c.r, nil }
+
+func TestCombine(t *testing.T) {
+	code := constClassifier{&Result{Label: thesrc.ClassificationCode, Confidence: 0.9, Features: Features{Languages: []string{"go"}}}}
+	notCode := constClassifier{&Result{Label: thesrc.ClassificationNotCode, Confidence: 0.6, Features: Features{CodeBytes: 10, TotalBytes: 100, Languages: []string{"c", "go"}}}}
+	unsure := constClassifier{}
+
+	r, err := Combine(code, notCode, unsure).Classify(&thesrc.Post{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The probability of code is (0.9 + (1 - 0.6)) / 2.
+	want := &Result{Label: thesrc.ClassificationCode, Confidence: 0.65, Features: Features{CodeBytes: 10, TotalBytes: 100, Languages: []string{"c", "go"}}}
+	if r.Confidence-want.Confidence > 1e-9 || want.Confidence-r.Confidence > 1e-9 {
+		t.Errorf("got confidence %v, want %v", r.Confidence, want.Confidence)
+	}
+	r.Confidence = want.Confidence
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("got result %+v, want %+v", r, want)
+	}
+
+	if r, _ := Combine(unsure).Classify(&thesrc.Post{}, nil); r != nil {
+		t.Errorf("got result %+v when no classifier could tell, want nil", r)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{DefaultName, "code-ratio", "domain"} {
+		if Get(name) == nil {
+			t.Errorf("no classifier registered as %q (registered: %v)", name, Names())
+		}
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	tests := []struct {
+		linkURL string
+		html    string
+		want    []string
+	}{
+		{linkURL: "https://github.com/a/b/blob/master/src/main.rs", want: []string{"rust"}},
+		{linkURL: "https://gist.github.com/a/123#file-query-sql", want: []string{"sql"}},
+		{linkURL: "http://example.com/index.html", want: nil},
+		{
+			html: `<pre class="language-golang"><code>x</code></pre><div class="highlight-source-python"><pre>y</pre></div>`,
+			want: []string{"go", "python"},
+		},
+		{
+			html: `<pre>SELECT id FROM post WHERE score > 1 ORDER BY score;</pre>` +
+				`<pre>def f(x):
+    if x:
+        return self.y
+</pre>`,
+			want: []string{"python", "sql"},
+		},
+		{html: `<p>Some <code>code</code> in prose.</p><pre>x = 1</pre>`, want: nil},
+	}
+	for _, test := range tests {
+		var page *goquery.Document
+		if test.html != "" {
+			page = parsePage(t, test.html)
+		}
+		langs := DetectLanguages(&thesrc.Post{LinkURL: test.linkURL}, page)
+		if !reflect.DeepEqual(langs, test.want) {
+			t.Errorf("%s %.40q: got languages %q, want %q", test.linkURL, test.html, langs, test.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package classifier
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// HeldOut returns whether the post with the given ID is in the held-out set
+// of examples (which are used to evaluate classifiers and not to train
+// them), given the fraction of examples to hold out. The split depends only
+// on the post's ID, so that "thesrc classify train" and "thesrc classify
+// eval" agree on it.
+func HeldOut(postID int, fraction float64) bool {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(postID)))
+	return float64(h.Sum32()%1000) < fraction*1000
+}
+
+// An Evaluation is the result of evaluating a classifier on examples, with
+// ClassificationCode as the positive label.
+type Evaluation struct {
+	TruePositives, FalsePositives, TrueNegatives, FalseNegatives int
+
+	// Abstained is the number of examples that the classifier couldn't
+	// tell about.
+	Abstained int
+}
+
+// Evaluate classifies each example with c and compares the results to the
+// examples' labels.
+func Evaluate(c Classifier, examples []*Example) (*Evaluation, error) {
+	var e Evaluation
+	for _, ex := range examples {
+		r, err := c.Classify(ex.Post, ex.Page)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			e.Abstained++
+			continue
+		}
+		code, wantCode := r.Label == thesrc.ClassificationCode, ex.Label == thesrc.ClassificationCode
+		switch {
+		case code && wantCode:
+			e.TruePositives++
+		case code && !wantCode:
+			e.FalsePositives++
+		case !code && !wantCode:
+			e.TrueNegatives++
+		default:
+			e.FalseNegatives++
+		}
+	}
+	return &e, nil
+}
+
+// Precision is the proportion of the examples classified as code that are
+// code.
+func (e *Evaluation) Precision() float64 {
+	return ratio(e.TruePositives, e.TruePositives+e.FalsePositives)
+}
+
+// Recall is the proportion of the examples that are code that were
+// classified as code.
+func (e *Evaluation) Recall() float64 {
+	return ratio(e.TruePositives, e.TruePositives+e.FalseNegatives)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+func (e *Evaluation) String() string {
+	return fmt.Sprintf("precision %.1f%%, recall %.1f%% (%d true positives, %d false positives, %d true negatives, %d false negatives, %d abstained)", e.Precision()*100, e.Recall()*100, e.TruePositives, e.FalsePositives, e.TrueNegatives, e.FalseNegatives, e.Abstained)
+}
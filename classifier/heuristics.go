@@ -0,0 +1,146 @@
+package classifier
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// CodeRatio classifies a page as code if enough of its text is in code and
+// pre elements, either as a proportion of all of its text or in total.
+type CodeRatio struct{}
+
+const (
+	// codeRatioThreshold is the proportion of a page's text that must be code
+	// for CodeRatio to classify it as code.
+	codeRatioThreshold = 0.07
+
+	// codeBytesThreshold is the amount of code text (in bytes) that a page
+	// must have for CodeRatio to classify it as code, regardless of the
+	// page's length.
+	codeBytesThreshold = 300
+)
+
+func (CodeRatio) Classify(post *thesrc.Post, page *goquery.Document) (*Result, error) {
+	if page == nil {
+		return nil, nil
+	}
+
+	r := &Result{Features: pageFeatures(post, page)}
+
+	var prop float64
+	if r.Features.TotalBytes > 0 {
+		prop = float64(r.Features.CodeBytes) / float64(r.Features.TotalBytes)
+	}
+
+	// strength is more than 1 if the page exceeds either threshold, and the
+	// further it is from 1, the more confident the classification.
+	strength := math.Max(prop/codeRatioThreshold, float64(r.Features.CodeBytes)/codeBytesThreshold)
+	if strength > 1 {
+		r.Label, r.Confidence = thesrc.ClassificationCode, 1-1/(2*strength)
+	} else {
+		r.Label, r.Confidence = thesrc.ClassificationNotCode, 1-strength/2
+	}
+	return r, nil
+}
+
+// pageFeatures returns the features of post's page.
+func pageFeatures(post *thesrc.Post, page *goquery.Document) Features {
+	return Features{
+		CodeBytes:  len(page.Find("code").Text() + page.Find("pre").Text()), // might double-count
+		TotalBytes: len(page.Find("body").Text()),
+		Languages:  DetectLanguages(post, page),
+	}
+}
+
+// Domain classifies posts whose links are to code hosting and sharing sites
+// as code. It can't tell whether links to other sites contain code.
+type Domain struct{}
+
+// codeDomains are the domains of sites whose pages almost always contain
+// code.
+var codeDomains = map[string]bool{
+	"bitbucket.org":   true,
+	"codepen.io":      true,
+	"gist.github.com": true,
+	"github.com":      true,
+	"gitlab.com":      true,
+	"godoc.org":       true,
+	"jsfiddle.net":    true,
+	"pkg.go.dev":      true,
+	"play.golang.org": true,
+	"sourcegraph.com": true,
+	"git.sr.ht":       true,
+}
+
+func (Domain) Classify(post *thesrc.Post, page *goquery.Document) (*Result, error) {
+	u, err := url.Parse(post.LinkURL)
+	if err != nil {
+		return nil, nil
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if !codeDomains[host] {
+		return nil, nil
+	}
+	return &Result{Label: thesrc.ClassificationCode, Confidence: 0.9}, nil
+}
+
+// Combine returns a classifier that classifies posts with each of cs and
+// averages their results. Each classifier's result counts as its probability
+// that the post is code (its confidence if it labeled the post as code, and 1
+// minus its confidence otherwise). Classifiers that can't tell are ignored.
+func Combine(cs ...Classifier) Classifier { return combined(cs) }
+
+type combined []Classifier
+
+func (cs combined) Classify(post *thesrc.Post, page *goquery.Document) (*Result, error) {
+	var n int
+	var pCode float64
+	var r Result
+	langs := map[string]bool{}
+	for _, c := range cs {
+		cr, err := c.Classify(post, page)
+		if err != nil {
+			return nil, err
+		}
+		if cr == nil {
+			continue
+		}
+
+		n++
+		if cr.Label == thesrc.ClassificationCode {
+			pCode += cr.Confidence
+		} else {
+			pCode += 1 - cr.Confidence
+		}
+
+		if cr.Features.CodeBytes > r.Features.CodeBytes {
+			r.Features.CodeBytes = cr.Features.CodeBytes
+		}
+		if cr.Features.TotalBytes > r.Features.TotalBytes {
+			r.Features.TotalBytes = cr.Features.TotalBytes
+		}
+		for _, lang := range cr.Features.Languages {
+			if !langs[lang] {
+				langs[lang] = true
+				r.Features.Languages = append(r.Features.Languages, lang)
+			}
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	pCode /= float64(n)
+	if pCode > 0.5 {
+		r.Label, r.Confidence = thesrc.ClassificationCode, pCode
+	} else {
+		r.Label, r.Confidence = thesrc.ClassificationNotCode, 1-pCode
+	}
+	sort.Strings(r.Features.Languages)
+	return &r, nil
+}
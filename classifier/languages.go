@@ -0,0 +1,231 @@
+package classifier
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// DetectLanguages returns the programming languages of the code in post's
+// link, in sorted order, given page, the HTML document at the link (or nil).
+// It detects languages from:
+//
+//   - the link URL's file extension (for links to source files, such as
+//     on GitHub or in gists);
+//   - the class names that syntax highlighters and Markdown renderers add to
+//     code blocks (such as "language-go"); and
+//   - the contents of code blocks without such class names.
+func DetectLanguages(post *thesrc.Post, page *goquery.Document) []string {
+	seen := map[string]bool{}
+	if lang := urlLanguage(post.LinkURL); lang != "" {
+		seen[lang] = true
+	}
+
+	if page != nil {
+		page.Find("pre, code").Each(func(_ int, s *goquery.Selection) {
+			if s.Is("code") && s.ParentsFiltered("pre").Length() > 0 {
+				// Handled along with its pre element.
+				return
+			}
+			if lang := classLanguage(s); lang != "" {
+				seen[lang] = true
+			} else if lang := contentLanguage(s.Text()); lang != "" {
+				seen[lang] = true
+			}
+		})
+	}
+
+	var langs []string
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// languageAliases maps alternative names (and file extensions) of languages
+// to the names that thesrc uses. An empty name means that the alias isn't a
+// programming language.
+var languageAliases = map[string]string{
+	"bash":          "shell",
+	"c++":           "cpp",
+	"c#":            "csharp",
+	"cc":            "cpp",
+	"console":       "shell",
+	"cs":            "csharp",
+	"cxx":           "cpp",
+	"ex":            "elixir",
+	"exs":           "elixir",
+	"golang":        "go",
+	"h":             "c",
+	"hpp":           "cpp",
+	"hs":            "haskell",
+	"js":            "javascript",
+	"jsx":           "javascript",
+	"kt":            "kotlin",
+	"mjs":           "javascript",
+	"mysql":         "sql",
+	"nohighlight":   "",
+	"none":          "",
+	"objective-c":   "objc",
+	"objectivec":    "objc",
+	"output":        "",
+	"pgsql":         "sql",
+	"plaintext":     "",
+	"plpgsql":       "sql",
+	"postgres":      "sql",
+	"postgresql":    "sql",
+	"psql":          "sql",
+	"py":            "python",
+	"python3":       "python",
+	"rb":            "ruby",
+	"rs":            "rust",
+	"sh":            "shell",
+	"shell-session": "shell",
+	"sqlite":        "sql",
+	"text":          "",
+	"ts":            "typescript",
+	"tsx":           "typescript",
+	"zsh":           "shell",
+}
+
+// normalizeLanguage returns the name that thesrc uses for the language named
+// (or with the file extension) name, or an empty string if name isn't a
+// programming language.
+func normalizeLanguage(name string) string {
+	name = strings.ToLower(name)
+	if lang, present := languageAliases[name]; present {
+		return lang
+	}
+	return name
+}
+
+// sourceExtensions are the file extensions of source files in each language
+// (in addition to those in languageAliases).
+var sourceExtensions = map[string]bool{
+	"c": true, "clj": true, "cpp": true, "erl": true, "go": true, "java": true,
+	"lua": true, "php": true, "scala": true, "sql": true, "swift": true,
+	"zig": true,
+}
+
+// gistFilePattern matches the fragment of a URL to a file in a gist (such as
+// "file-main-go" for main.go).
+var gistFilePattern = regexp.MustCompile(`^file-.+-([a-z0-9]+)$`)
+
+// urlLanguage returns the language of the source file that rawurl links to,
+// or an empty string if it doesn't link to a source file.
+func urlLanguage(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	ext := strings.TrimPrefix(path.Ext(u.Path), ".")
+	if m := gistFilePattern.FindStringSubmatch(u.Fragment); m != nil && strings.HasSuffix(u.Host, "gist.github.com") {
+		ext = m[1]
+	}
+	ext = strings.ToLower(ext)
+	if _, isAlias := languageAliases[ext]; isAlias || sourceExtensions[ext] {
+		return normalizeLanguage(ext)
+	}
+	return ""
+}
+
+// langClassPattern matches the class names that syntax highlighters and
+// Markdown renderers add to code blocks to indicate their language (such as
+// "language-go" or GitHub's "highlight-source-go").
+var langClassPattern = regexp.MustCompile(`^(?:lang|language|highlight-source|highlight)-([a-z0-9+#]+)$`)
+
+// classLanguage returns the language that the class names of the code block
+// s (or of its code element or parent) indicate, or an empty string if they
+// don't indicate one.
+func classLanguage(s *goquery.Selection) string {
+	for _, sel := range []*goquery.Selection{s, s.ChildrenFiltered("code"), s.Parent()} {
+		class, _ := sel.Attr("class")
+		for _, c := range strings.Fields(strings.ToLower(class)) {
+			if m := langClassPattern.FindStringSubmatch(c); m != nil {
+				return normalizeLanguage(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// contentPatterns are patterns that are common in code in each language.
+var contentPatterns = map[string][]*regexp.Regexp{
+	"go": {
+		regexp.MustCompile(`(?m)^package \w+$`),
+		regexp.MustCompile(`\bfunc (\(\w+ \*?\w+\) )?\w+\(`),
+		regexp.MustCompile(`\w := `),
+		regexp.MustCompile(`\bfmt\.\w+\(`),
+		regexp.MustCompile(`\bif err != nil\b`),
+	},
+	"rust": {
+		regexp.MustCompile(`\bfn \w+(<[^>]*>)?\(`),
+		regexp.MustCompile(`\blet mut \w+`),
+		regexp.MustCompile(`(?m)^\s*(pub )?(impl|struct|enum|trait|use)\b`),
+		regexp.MustCompile(`\b(println|vec|format|panic)!`),
+		regexp.MustCompile(`&(mut )?self\b`),
+	},
+	"sql": {
+		regexp.MustCompile(`(?i)\bSELECT\b[\s\S]+\bFROM\b`),
+		regexp.MustCompile(`(?i)\bCREATE (TABLE|INDEX|UNIQUE INDEX)\b`),
+		regexp.MustCompile(`(?i)\bINSERT INTO\b`),
+		regexp.MustCompile(`(?i)\bWHERE \w+ ?(=|<|>|IN\b|LIKE\b)`),
+		regexp.MustCompile(`(?i)\b(GROUP|ORDER) BY\b`),
+	},
+	"python": {
+		regexp.MustCompile(`(?m)^\s*def \w+\(.*\):\s*$`),
+		regexp.MustCompile(`(?m)^(from [\w.]+ )?import \w+`),
+		regexp.MustCompile(`\bself\.\w+`),
+		regexp.MustCompile(`(?m)^\s*(elif|except|class \w+(\(.*\))?:)`),
+		regexp.MustCompile(`(?m)^\s*(if|for|while) .+:\s*$`),
+	},
+	"javascript": {
+		regexp.MustCompile(`\bfunction\s*\w*\(`),
+		regexp.MustCompile(`\b(const|var) \w+ = `),
+		regexp.MustCompile(`=> `),
+		regexp.MustCompile(`\bconsole\.log\(`),
+		regexp.MustCompile(`\b(require\(['"]|document\.|window\.)`),
+	},
+	"shell": {
+		regexp.MustCompile(`(?m)^\$ \w+`),
+		regexp.MustCompile(`(?m)^#!/(usr/)?bin/(env )?(ba|z)?sh`),
+		regexp.MustCompile(`\b(apt-get|brew|npm|pip|go|cargo) (install|get|build)\b`),
+		regexp.MustCompile(`(?m)^\s*(export \w+=|echo |cd |sudo )`),
+	},
+}
+
+// minContentScore is the number of a language's contentPatterns that code
+// must match for contentLanguage to detect it.
+const minContentScore = 2
+
+// contentLanguage returns the language of code (the text of a code block)
+// that it seems to be in, or an empty string if code doesn't match enough of
+// any language's contentPatterns or matches two languages' equally well.
+func contentLanguage(code string) string {
+	var best string
+	var bestScore, bestCount int
+	for lang, patterns := range contentPatterns {
+		score := 0
+		for _, p := range patterns {
+			if p.MatchString(code) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, bestCount = lang, score, 1
+		case score == bestScore:
+			bestCount++
+		}
+	}
+	if bestScore < minContentScore || bestCount > 1 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,132 @@
+package classifier
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// maxMetadataLen is the maximum length (in characters) of each of the text
+// fields extracted by ExtractMetadata. Pages sometimes stuff entire articles
+// into their description meta tags.
+const maxMetadataLen = 500
+
+// FetchMetadata fetches the page at url and extracts its metadata.
+func FetchMetadata(url string) (*thesrc.LinkMetadata, error) {
+	page, err := FetchPage(url)
+	if err != nil {
+		return nil, err
+	}
+	md := ExtractMetadata(page)
+	md.FetchedAt = time.Now().UTC()
+	return md, nil
+}
+
+// ExtractMetadata returns the metadata in page's OpenGraph and other meta
+// tags, preferring OpenGraph tags when a page has both. Relative URLs are
+// resolved against page.Url, which is also returned as the FinalURL. It
+// doesn't set FetchedAt.
+func ExtractMetadata(page *goquery.Document) *thesrc.LinkMetadata {
+	md := &thesrc.LinkMetadata{
+		Title:        metaContent(page, `meta[property="og:title"]`, `meta[name="twitter:title"]`),
+		Description:  metaContent(page, `meta[property="og:description"]`, `meta[name="description"]`, `meta[name="twitter:description"]`),
+		ImageURL:     metaContent(page, `meta[property="og:image"]`, `meta[property="og:image:url"]`, `meta[name="twitter:image"]`),
+		CanonicalURL: attr(page, `link[rel="canonical"]`, "href"),
+		Author:       metaContent(page, `meta[name="author"]`, `meta[property="article:author"]`),
+	}
+	if md.Title == "" {
+		md.Title = cleanText(page.Find("title").First().Text())
+	}
+	if md.CanonicalURL == "" {
+		md.CanonicalURL = metaContent(page, `meta[property="og:url"]`)
+	}
+
+	published := metaContent(page, `meta[property="article:published_time"]`, `meta[itemprop="datePublished"]`, `meta[name="date"]`)
+	if published == "" {
+		published = attr(page, `time[itemprop="datePublished"]`, "datetime")
+	}
+	md.PublishedAt = parseTime(published)
+
+	if page.Url != nil {
+		md.FinalURL = page.Url.String()
+	}
+	md.ImageURL = resolveURL(page.Url, md.ImageURL)
+	md.CanonicalURL = resolveURL(page.Url, md.CanonicalURL)
+	return md
+}
+
+// metaContent returns the content attribute of the first element matching
+// any of the selectors (in order) that has a non-empty one.
+func metaContent(page *goquery.Document, selectors ...string) string {
+	for _, sel := range selectors {
+		if s := attr(page, sel, "content"); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// attr returns the (cleaned-up) value of the attribute of the first element
+// matching selector that has a non-empty one.
+func attr(page *goquery.Document, selector, name string) string {
+	var val string
+	page.Find(selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		v, _ := s.Attr(name)
+		val = cleanText(v)
+		return val == ""
+	})
+	return val
+}
+
+// cleanText collapses runs of whitespace in s and truncates it to
+// maxMetadataLen characters.
+func cleanText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > maxMetadataLen {
+		s = strings.TrimSpace(string([]rune(s)[:maxMetadataLen-1])) + "…"
+	}
+	return s
+}
+
+// resolveURL resolves ref against base, returning "" if ref isn't a valid
+// http or https URL.
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// timeLayouts are the formats of the publication times in pages' meta tags.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses a publication time, returning the zero time if s isn't in
+// one of the timeLayouts.
+func parseTime(s string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
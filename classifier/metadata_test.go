@@ -0,0 +1,73 @@
+package classifier
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		html string
+		want *thesrc.LinkMetadata
+	}{
+		{
+			html: `<head>
+<title>Page title</title>
+<meta property="og:title" content="OG  title">
+<meta name="description" content="Meta description">
+<meta property="og:description" content="OG description">
+<meta property="og:image" content="/img/a.png">
+<link rel="canonical" href="https://example.com/canonical">
+<meta name="author" content="Alice">
+<meta property="article:published_time" content="2014-03-01T12:00:00-05:00">
+</head>`,
+			want: &thesrc.LinkMetadata{
+				Title:        "OG title",
+				Description:  "OG description",
+				ImageURL:     "http://example.com/img/a.png",
+				CanonicalURL: "https://example.com/canonical",
+				Author:       "Alice",
+				PublishedAt:  time.Date(2014, 3, 1, 17, 0, 0, 0, time.UTC),
+				FinalURL:     "http://example.com/a/b",
+			},
+		},
+		{
+			html: `<head><title> Page
+  title </title><meta name="description" content="Meta description"><meta property="og:url" content="http://example.com/og"></head>`,
+			want: &thesrc.LinkMetadata{
+				Title:        "Page title",
+				Description:  "Meta description",
+				CanonicalURL: "http://example.com/og",
+				FinalURL:     "http://example.com/a/b",
+			},
+		},
+		{
+			html: `<body><time itemprop="datePublished" datetime="2014-03-01">March 1</time><meta property="og:image" content="javascript:alert(1)"></body>`,
+			want: &thesrc.LinkMetadata{
+				PublishedAt: time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC),
+				FinalURL:    "http://example.com/a/b",
+			},
+		},
+	}
+	for _, test := range tests {
+		page := parsePage(t, test.html)
+		page.Url, _ = url.Parse("http://example.com/a/b")
+		md := ExtractMetadata(page)
+		if !reflect.DeepEqual(md, test.want) {
+			t.Errorf("%.40q: got %+v, want %+v", test.html, md, test.want)
+		}
+	}
+}
+
+func TestExtractMetadata_longDescription(t *testing.T) {
+	page := parsePage(t, `<meta name="description" content="`+strings.Repeat("a ", 1000)+`">`)
+	md := ExtractMetadata(page)
+	if n := len([]rune(md.Description)); n != maxMetadataLen {
+		t.Errorf("got description of length %d, want %d", n, maxMetadataLen)
+	}
+}
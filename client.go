@@ -16,7 +16,12 @@ import (
 
 // A Client communicates with thesrc's HTTP API.
 type Client struct {
-	Posts PostsService
+	Posts    PostsService
+	Comments CommentsService
+	Users    UsersService
+	Tokens   TokensService
+	Links    LinksService
+	Imports  ImportsService
 
 	// BaseURL for HTTP requests to thesrc's API.
 	BaseURL *url.URL
@@ -24,6 +29,10 @@ type Client struct {
 	//UserAgent used for HTTP requests to thesrc's API.
 	UserAgent string
 
+	// Token, if non-empty, is sent as a bearer token in the Authorization
+	// header to authenticate HTTP requests to thesrc's API.
+	Token string
+
 	httpClient *http.Client
 }
 
@@ -44,10 +53,31 @@ func NewClient(httpClient *http.Client) *Client {
 		UserAgent:  userAgent,
 		httpClient: httpClient,
 	}
-	c.Posts = &postsService{c}
+	c.setServices()
 	return c
 }
 
+func (c *Client) setServices() {
+	c.Posts = &postsService{c}
+	c.Comments = &commentsService{c}
+	c.Users = &usersService{c}
+	c.Tokens = &tokensService{c}
+	c.Links = &linksService{c}
+	c.Imports = &importsService{c}
+}
+
+// WithToken returns a copy of c that authenticates its requests with token.
+// If c was not created by NewClient (e.g., if its services are mocks), the
+// copy shares c's services.
+func (c *Client) WithToken(token string) *Client {
+	c2 := *c
+	c2.Token = token
+	if c.httpClient != nil {
+		c2.setServices()
+	}
+	return &c2
+}
+
 // ListOptions specifies general pagination options for fetching a list of
 // results.
 type ListOptions struct {
@@ -139,6 +169,9 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 	}
 
 	req.Header.Add("User-Agent", c.UserAgent)
+	if c.Token != "" {
+		setBearerToken(req, c.Token)
+	}
 	return req, nil
 }
 
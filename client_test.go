@@ -9,6 +9,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
 )
 
 var (
@@ -63,6 +65,12 @@ func testMethod(t *testing.T, r *http.Request, want string) {
 	}
 }
 
+func testHeader(t *testing.T, r *http.Request, header string, want string) {
+	if got := r.Header.Get(header); got != want {
+		t.Errorf("Header %s = %q, want %q", header, got, want)
+	}
+}
+
 type values map[string]string
 
 func testFormValues(t *testing.T, r *http.Request, values values) {
@@ -91,3 +99,27 @@ func testBody(t *testing.T, r *http.Request, want string) {
 func normalizeTime(t *time.Time) {
 	*t = t.In(time.UTC)
 }
+
+func TestClient_WithToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Post, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testHeader(t, r, "Authorization", "Bearer t")
+
+		writeJSON(w, &Post{ID: 1})
+	})
+
+	if _, err := client.WithToken("t").Posts.Get(1); err != nil {
+		t.Errorf("Posts.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+	if client.Token != "" {
+		t.Errorf("WithToken modified original client's token to %q", client.Token)
+	}
+}
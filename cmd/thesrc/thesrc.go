@@ -7,9 +7,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 	"sourcegraph.com/sourcegraph/thesrc/api"
@@ -17,12 +21,24 @@ import (
 	"sourcegraph.com/sourcegraph/thesrc/classifier"
 	"sourcegraph.com/sourcegraph/thesrc/datastore"
 	"sourcegraph.com/sourcegraph/thesrc/importer"
+	"sourcegraph.com/sourcegraph/thesrc/linkcheck"
 	"sourcegraph.com/sourcegraph/thesrc/router"
+	"sourcegraph.com/sourcegraph/thesrc/worker"
 )
 
 var (
 	baseURLStr = flag.String("url", "http://thesrc.org", "base URL of thesrc")
 	baseURL    *url.URL
+
+	token = flag.String("token", os.Getenv("THESRC_TOKEN"), "API token to authenticate with (default: $THESRC_TOKEN)")
+
+	modelFile = flag.String("model", "thesrc-model.json", "trained classifier model file (written by \"classify train\", and used by \"classify\" and \"serve\" if it exists)")
+
+	httpCacheDir  = flag.String("http-cache-dir", classifier.HTTPCacheDir, "directory to cache pages fetched by the classifier in (empty to disable caching)")
+	httpCacheSize = flag.Int64("http-cache-size", classifier.HTTPCacheMaxSize>>20, "maximum size of the page cache (in MB)")
+	httpCacheTTL  = flag.Duration("http-cache-ttl", classifier.HTTPCacheTTL, "how long to use cached pages before checking whether they have changed")
+
+	dbDSN = flag.String("db", "", "database to use (postgres://... or sqlite:///path/to/thesrc.db) (default: PostgreSQL database given by PG* env vars)")
 )
 
 func init() {
@@ -62,8 +78,13 @@ func main() {
 		log.Fatal(err)
 	}
 	apiclient.BaseURL = baseURL.ResolveReference(&url.URL{Path: "/api/"})
+	apiclient.Token = *token
 	app.APIClient = apiclient
 	importer.Store = apiclient
+	datastore.DSN = *dbDSN
+	classifier.HTTPCacheDir = *httpCacheDir
+	classifier.HTTPCacheMaxSize = *httpCacheSize << 20
+	classifier.HTTPCacheTTL = *httpCacheTTL
 
 	subcmd := flag.Arg(0)
 	for _, c := range subcmds {
@@ -86,10 +107,17 @@ type subcmd struct {
 
 var subcmds = []subcmd{
 	{"post", "submit a post", postCmd},
+	{"search", "search posts", searchCmd},
 	{"import", "import posts from other sites", importCmd},
 	{"classify", "classify posts", classifyCmd},
 	{"serve", "start web server", serveCmd},
+	{"worker", "run background jobs", workerCmd},
+	{"cache", "show and prune the cache of pages fetched by the classifier", cacheCmd},
+	{"linkcheck", "check for dead links", linkCheckCmd},
+	{"dedupe", "merge duplicate posts (with the same canonical link URL)", dedupeCmd},
 	{"createdb", "create the database schema", createDBCmd},
+	{"migrate", "apply, revert, and list database schema migrations", migrateCmd},
+	{"token", "create, list, and revoke API tokens", tokenCmd},
 }
 
 var apiclient = thesrc.NewClient(nil)
@@ -145,14 +173,74 @@ The options are:
 	fmt.Println(baseURL.ResolveReference(url))
 }
 
+func searchCmd(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	codeOnly := fs.Bool("code", false, "only show posts classified as code")
+	n := fs.Int("n", 20, "max number of results")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc search [options] query...
+
+Searches posts' titles, bodies, and link URL domains, printing the best matches
+first.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+
+	posts, err := apiclient.Posts.List(&thesrc.PostListOptions{
+		Query:       strings.Join(fs.Args(), " "),
+		CodeOnly:    *codeOnly,
+		ListOptions: thesrc.ListOptions{PerPage: *n},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, post := range posts {
+		url, err := router.App().Get(router.Post).URL("ID", strconv.Itoa(post.ID))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%-50s %s\n", post.Title, baseURL.ResolveReference(url))
+		if post.LinkURL != "" {
+			fmt.Printf("%-50s %s\n", "", post.LinkURL)
+		}
+	}
+}
+
 func importCmd(args []string) {
 	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	sources := fs.String("sources", "", "JSON file listing RSS, Atom, and JSON Feed feeds to import from (in addition to the built-in sites)")
+	status := fs.Bool("status", false, "show when posts were last imported from each site, and errors, instead of importing")
+	daemon := fs.Bool("daemon", false, "keep running, importing from each site periodically")
+	interval := fs.Duration("interval", time.Hour, "how often to import from each site (with -daemon), unless the -sources file sets the site's interval")
+	statusAddr := fs.String("status-http", "localhost:5002", "HTTP address to serve the status of imports from each site on, as JSON (with -daemon; empty to disable)")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc import [options]
 
-Imports posts from other sites.
+Imports posts from other sites. Each site's import state (when it was last
+imported, the validators of its listing, and the posts seen recently) is
+stored in the database, so unchanged listings and posts that were already
+imported are skipped. It requires an admin's API token.
+
+With -daemon, it keeps running until interrupted (or sent SIGTERM), importing
+from each site every -interval (or the site's own interval), with some random
+jitter. After a failed import from a site, it waits twice as long (and then
+four times, and so on) before trying again.
+
+The -sources file is a JSON array of feeds, each with a URL, a site name
+(which defaults to the URL's host), and tags to give the imported posts:
+
+  [{"url": "https://blog.golang.org/feed.atom", "site": "go-blog", "tags": ["go"], "interval": "6h"}]
 
-The available sites are:
+The built-in sites are:
 `)
 		for _, f := range importer.Fetchers {
 			fmt.Fprintln(os.Stderr, "  ", f.Site())
@@ -170,7 +258,20 @@ The options are:
 		fs.Usage()
 	}
 
-	var numTotal, numCreated int
+	if *sources != "" {
+		fetchers, err := importer.LoadSources(*sources)
+		if err != nil {
+			log.Fatal(err)
+		}
+		importer.Fetchers = append(importer.Fetchers, fetchers...)
+	}
+
+	if *status {
+		importStatus()
+		return
+	}
+
+	var numTotal, numCreated, numSkipped int
 	var mu sync.Mutex
 	importer.Imported = func(site string, post *thesrc.Post, created bool) {
 		mu.Lock()
@@ -182,8 +283,17 @@ The options are:
 		fmt.Printf("%-12s  %-50s\n              %-60s\n", site, post.Title, post.LinkURL)
 		numCreated++
 	}
+	importer.Skipped = func(site string, post *thesrc.Post) {
+		mu.Lock()
+		defer mu.Unlock()
+		numSkipped++
+	}
+
+	if *daemon {
+		importDaemon(*interval, *statusAddr)
+		return
+	}
 
-	datastore.Connect()
 	var failed bool
 	var wg sync.WaitGroup
 	for _, f_ := range importer.Fetchers {
@@ -201,19 +311,125 @@ The options are:
 	}
 	wg.Wait()
 
-	log.Printf("# import: %d new posts, %d already existed", numCreated, numTotal-numCreated)
+	log.Printf("# import: %d new posts, %d already existed, %d seen in recent imports", numCreated, numTotal-numCreated, numSkipped)
 	if failed {
 		os.Exit(1)
 	}
 }
 
+// importDaemon imports posts from each site periodically until it receives
+// SIGINT or SIGTERM, serving the status of the imports on statusAddr.
+func importDaemon(interval time.Duration, statusAddr string) {
+	sched := &importer.Scheduler{Fetchers: importer.Fetchers, Interval: interval, Jitter: 0.1}
+
+	var srv *http.Server
+	if statusAddr != "" {
+		srv = &http.Server{Addr: statusAddr, Handler: sched}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal("ListenAndServe:", err)
+			}
+		}()
+		log.Printf("Serving import status on %s", statusAddr)
+	}
+
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Println("Stopping after running imports finish...")
+		close(stop)
+	}()
+
+	sched.Run(stop)
+	if srv != nil {
+		srv.Close()
+	}
+}
+
+// importStatus prints the import state of each site.
+func importStatus() {
+	states, err := importer.Store.Imports.List(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bySite := make(map[string]*thesrc.ImportState, len(states))
+	for _, state := range states {
+		bySite[state.Site] = state
+	}
+
+	// List the sites that will be imported from, then any others that
+	// were imported from before (such as feeds from other -sources files).
+	var sites []string
+	for _, f := range importer.Fetchers {
+		sites = append(sites, f.Site())
+	}
+	for _, state := range states {
+		if !importerHasSite(state.Site) {
+			sites = append(sites, state.Site)
+		}
+	}
+
+	fmt.Printf("%-24s %-12s %-12s %-8s %s\n", "SITE", "LAST RUN", "LAST SUCCESS", "FAILURES", "LAST ERROR")
+	for _, site := range sites {
+		state, present := bySite[site]
+		if !present {
+			fmt.Printf("%-24s %s\n", site, "never")
+			continue
+		}
+		fmt.Printf("%-24s %-12s %-12s %-8d %s\n", site, ago(state.LastRunAt), ago(state.LastSuccessAt), state.Failures, state.LastError)
+	}
+}
+
+// importerHasSite returns whether importer.Fetchers has a fetcher for site.
+func importerHasSite(site string) bool {
+	for _, f := range importer.Fetchers {
+		if f.Site() == site {
+			return true
+		}
+	}
+	return false
+}
+
+// ago returns how long ago t was, in a short, human-readable form.
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+}
+
 func classifyCmd(args []string) {
 	fs := flag.NewFlagSet("classify", flag.ExitOnError)
 	concurrency := fs.Int("c", 10, "concurrent classifiers")
+	classifierName := fs.String("classifier", classifier.DefaultName, "classifier to use (one of: "+strings.Join(classifier.Names(), ", ")+")")
+	holdout := fs.Float64("holdout", 0.2, "fraction of confirmed posts to hold out from training and evaluate classifiers on (train and eval)")
 	fs.Usage = func() {
-		fmt.Fprintln(os.Stderr, `usage: thesrc classify [options]
+		fmt.Fprintln(os.Stderr, `usage: thesrc classify [options] [train|eval|confirm LABEL ID...]
+
+Classifies posts that moderators haven't confirmed the classifications of.
+
+The train command trains a naive Bayes classifier on posts whose
+classifications moderators have confirmed (except for those held out) and
+saves it to the file given by -model. Once the file exists, the "bayes" and
+default classifiers use it.
 
-Classifies posts.
+The eval command reports the precision and recall of the classifier given by
+-classifier on the held-out confirmed posts.
+
+The confirm command sets the classification of the posts with the given IDs
+to LABEL (CODE or NOTCODE) and marks it as confirmed. It requires an admin's
+API token.
 
 The options are:
 `)
@@ -222,46 +438,132 @@ The options are:
 	}
 	fs.Parse(args)
 
-	if fs.NArg() != 0 {
+	switch fs.Arg(0) {
+	case "":
+		loadModel()
+		c := classifier.Get(*classifierName)
+		if c == nil {
+			log.Fatalf("Unknown -classifier %q.", *classifierName)
+		}
+		classifyPosts(c, *concurrency)
+
+	case "train":
+		if fs.NArg() != 1 {
+			fs.Usage()
+		}
+		train, heldOut := confirmedExamples(*holdout, *concurrency)
+		log.Printf("# training on %d posts", len(train))
+		nb := classifier.Train(train)
+		if err := nb.Save(*modelFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("# saved model to %s", *modelFile)
+		eval, err := classifier.Evaluate(nb, heldOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d held-out posts: %s\n", len(heldOut), eval)
+
+	case "eval":
+		if fs.NArg() != 1 {
+			fs.Usage()
+		}
+		loadModel()
+		c := classifier.Get(*classifierName)
+		if c == nil {
+			log.Fatalf("Unknown -classifier %q.", *classifierName)
+		}
+		_, heldOut := confirmedExamples(*holdout, *concurrency)
+		eval, err := classifier.Evaluate(c, heldOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d held-out posts: %s\n", len(heldOut), eval)
+
+	case "confirm":
+		if fs.NArg() < 3 {
+			fs.Usage()
+		}
+		label := strings.ToUpper(fs.Arg(1))
+		if label != thesrc.ClassificationCode && label != thesrc.ClassificationNotCode {
+			log.Fatalf("Invalid label %q (must be %s or %s).", label, thesrc.ClassificationCode, thesrc.ClassificationNotCode)
+		}
+		for _, idStr := range fs.Args()[2:] {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			post, err := apiclient.Posts.Get(id)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fields := map[string]interface{}{"ClassificationConfirmed": true}
+			if post.Classification != label {
+				fields["Classification"], fields["ClassificationScore"] = label, 1
+			}
+			if err := patchPost(id, fields); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+	default:
 		fs.Usage()
 	}
+}
 
+// loadModel loads the classifier model given by -model, if it exists.
+func loadModel() {
+	if err := classifier.LoadModel(*modelFile); err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		log.Fatal(err)
+	}
+	log.Printf("# loaded classifier model %s", *modelFile)
+}
+
+// classifyPosts classifies all posts that moderators haven't confirmed the
+// classifications of with c, updating those whose classifications changed.
+func classifyPosts(c classifier.Classifier, concurrency int) {
 	var mu sync.Mutex
 	summary := map[string]int{}
 
 	workChan := make(chan *thesrc.Post)
-	quitChan := make(chan struct{})
-	for i := 0; i < *concurrency; i++ {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
 		go func() {
-			for {
-				select {
-				case post := <-workChan:
-					c, err := classifier.Classify(post)
-					if err != nil {
-						log.Printf("Error classifying %q: %s. (Continuing...)", post.LinkURL, err)
-						continue
+			defer wg.Done()
+			for post := range workChan {
+				res, err := classifier.Classify(post, c)
+				if err != nil {
+					log.Printf("Error classifying %q: %s. (Continuing...)", post.LinkURL, err)
+					continue
+				}
+				if res == nil {
+					continue
+				}
+				changed := res.Label != post.Classification || res.Confidence != post.ClassificationScore || !reflect.DeepEqual(res.Features.Languages, post.Languages)
+				if changed {
+					// Only update the classification, because the post may
+					// have been edited since it was listed.
+					fields := map[string]interface{}{
+						"Classification":      res.Label,
+						"ClassificationScore": res.Confidence,
+						"Languages":           res.Features.Languages,
 					}
-					changed := firstWord(c) != firstWord(post.Classification)
-					if changed {
-						post.Classification = c
-						// TODO(sqs): add post update endpoint so we can run
-						// `thesrc` against the HTTP API
-						if _, err := datastore.DBH.Update(post); err != nil {
-							log.Fatal(err)
-						}
-						mu.Lock()
-						summary[firstWord(post.Classification)]++
-						mu.Unlock()
+					if err := patchPost(post.ID, fields); err != nil {
+						log.Fatal(err)
 					}
-					fmt.Printf("%v %-20s %s\n", changed, post.Classification, post.LinkURL)
-				case <-quitChan:
-					return
+					mu.Lock()
+					summary[res.Label]++
+					mu.Unlock()
 				}
+				fmt.Printf("%v %s %s\n", changed, res, post.LinkURL)
 			}
 		}()
 	}
 
-	datastore.Connect()
 	perPage := 100
 	for pg := 1; true; pg++ {
 		log.Println("Fetching more posts...")
@@ -274,21 +576,84 @@ The options are:
 		}
 
 		for _, post := range posts {
+			if post.ClassificationConfirmed {
+				continue
+			}
 			workChan <- post
 		}
 	}
 
-	close(quitChan)
+	close(workChan)
+	wg.Wait()
 
 	fmt.Fprintf(os.Stderr, "# classified posts: %v\n", summary)
 }
 
-func firstWord(s string) string {
-	i := strings.Index(s, " ")
-	if i == -1 {
-		return ""
+// patchPost changes only the given fields of the post with the given ID,
+// leaving the rest as they are in the datastore (unlike Posts.Update, which
+// replaces the whole post).
+func patchPost(id int, fields map[string]interface{}) error {
+	url, err := router.API().Get(router.UpdatePost).URL("ID", strconv.Itoa(id))
+	if err != nil {
+		return err
+	}
+	req, err := apiclient.NewRequest("PATCH", strings.TrimPrefix(url.String(), "/"), fields)
+	if err != nil {
+		return err
+	}
+	_, err = apiclient.Do(req, nil)
+	return err
+}
+
+// confirmedExamples fetches the pages of the posts whose classifications
+// moderators have confirmed, returning them as examples for training and for
+// evaluation (those held out; see classifier.HeldOut).
+func confirmedExamples(holdout float64, concurrency int) (train, heldOut []*classifier.Example) {
+	var posts []*thesrc.Post
+	for pg := 1; true; pg++ {
+		page, err := apiclient.Posts.List(&thesrc.PostListOptions{ClassificationConfirmed: true, ListOptions: thesrc.ListOptions{PerPage: 100, Page: pg}})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		posts = append(posts, page...)
 	}
-	return s[:i]
+	log.Printf("# fetching pages of %d posts with confirmed classifications...", len(posts))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, post := range posts {
+		if post.LinkURL == "" {
+			continue
+		}
+		post := post
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			page, err := classifier.FetchPage(post.LinkURL)
+			if err != nil {
+				log.Printf("Error fetching %q: %s. (Skipping...)", post.LinkURL, err)
+				return
+			}
+			e := &classifier.Example{Post: post, Page: page, Label: post.Classification}
+			mu.Lock()
+			defer mu.Unlock()
+			if classifier.HeldOut(post.ID, holdout) {
+				heldOut = append(heldOut, e)
+			} else {
+				train = append(train, e)
+			}
+		}()
+	}
+	wg.Wait()
+	return train, heldOut
 }
 
 func serveCmd(args []string) {
@@ -297,6 +662,9 @@ func serveCmd(args []string) {
 	templateDir := fs.String("tmpl-dir", app.TemplateDir, "template directory")
 	staticDir := fs.String("static-dir", app.StaticDir, "static assets directory")
 	reload := flag.Bool("reload", true, "reload templates on each request (dev mode)")
+	store := fs.String("store", "db", "where to store data: db (the database given by -db) or memory (data is lost on exit)")
+	workers := fs.Int("workers", 2, "number of background jobs (such as classifying new posts) to run concurrently in the server (0 to run them with \"thesrc worker\" instead)")
+	linkCheckInterval := fs.Duration("linkcheck-interval", 7*24*time.Hour, "how often to check posts' links for dead links (0 to disable)")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc serve [options] 
 
@@ -316,9 +684,23 @@ The options are:
 	app.StaticDir = *staticDir
 	app.TemplateDir = *templateDir
 	app.ReloadTemplates = *reload
+	app.BaseURL = baseURL
 	app.LoadTemplates()
+	loadModel()
 
-	datastore.Connect()
+	switch *store {
+	case "db":
+		datastore.Connect()
+	case "memory":
+		datastore.InMemory = true
+	default:
+		log.Fatalf("Unknown -store %q (must be db or memory).", *store)
+	}
+
+	if *workers > 0 {
+		pool := &worker.Pool{Store: datastore.NewDatastore(nil), Workers: *workers, LinkCheckInterval: *linkCheckInterval}
+		go pool.Run(nil)
+	}
 
 	m := http.NewServeMux()
 	m.Handle("/api/", http.StripPrefix("/api", api.Handler()))
@@ -331,13 +713,226 @@ The options are:
 	}
 }
 
+func workerCmd(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	n := fs.Int("n", 2, "number of jobs to run concurrently")
+	linkCheckInterval := fs.Duration("linkcheck-interval", 7*24*time.Hour, "how often to check posts' links for dead links (0 to disable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc worker [options]
+
+Runs background jobs (such as classifying newly submitted posts) from the job
+queue in the database given by -db, until interrupted. Use it to run jobs
+outside of the web server (with "thesrc serve -workers=0"). Several workers
+may run at once.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+	loadModel()
+
+	stop := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Println("Stopping after running jobs finish...")
+		close(stop)
+	}()
+
+	pool := &worker.Pool{Store: datastore.NewDatastore(nil), Workers: *n, LinkCheckInterval: *linkCheckInterval}
+	pool.Run(stop)
+}
+
+func linkCheckCmd(args []string) {
+	fs := flag.NewFlagSet("linkcheck", flag.ExitOnError)
+	n := fs.Int("n", 8, "number of links to check concurrently")
+	age := fs.Duration("age", 24*time.Hour, "only check links that haven't been checked in this long (0 to check all links)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc linkcheck [options]
+
+Checks whether the links of the posts in the database given by -db still work
+and records the results, printing each link's status. Links that return HTTP
+404 or 410, or that fail several checks in a row, are marked as dead.
+
+("thesrc serve" and "thesrc worker" also check links periodically, according
+to their -linkcheck-interval flags.)
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+	store := datastore.NewDatastore(nil)
+
+	summary := map[string]int{}
+	checkedBefore := time.Now().Add(-*age)
+	for {
+		posts, err := store.LinkChecks.ListDue(checkedBefore, 100)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(posts) == 0 {
+			break
+		}
+		linkcheck.CheckAll(posts, *n, func(post *thesrc.Post, check *thesrc.LinkCheck) {
+			if err := store.LinkChecks.Record(check); err != nil {
+				log.Fatal(err)
+			}
+
+			status := "ok"
+			if check.Dead {
+				status = "dead"
+			} else if check.Failures > 0 {
+				status = "failing"
+			}
+			summary[status]++
+
+			var detail string
+			if check.Error != "" {
+				detail = " (" + check.Error + ")"
+			} else if check.Redirected(post.LinkURL) {
+				detail = " -> " + check.FinalURL
+			}
+			fmt.Printf("%-7s %3d %s%s\n", status, check.StatusCode, post.LinkURL, detail)
+		})
+	}
+	log.Printf("# %d ok, %d failing, %d dead", summary["ok"], summary["failing"], summary["dead"])
+}
+
+func dedupeCmd(args []string) {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	dryRun := fs.Bool("n", false, "dry run (print the merges without changing the database)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc dedupe [options]
+
+Sets the canonical URLs of the links of the posts in the database given by -db
+and merges posts whose links have the same canonical URL (such as the http and
+https, or www and non-www, URLs of a page), printing each merge. The post with
+the highest score is kept, and the others' comments, votes, and tags are moved
+to it.
+
+New posts are checked for duplicates when they are submitted, so this is only
+needed for posts submitted before canonical URLs were introduced.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+	tx, err := datastore.DB.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	merges, err := datastore.DedupePosts(tx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var merged int
+	for _, m := range merges {
+		fmt.Printf("%d %s\n", m.Kept.ID, m.Kept.CanonicalURL)
+		for _, post := range m.Merged {
+			fmt.Printf("\t<- %d %s\n", post.ID, post.LinkURL)
+		}
+		merged += len(m.Merged)
+	}
+
+	if *dryRun {
+		log.Printf("# would merge %d duplicate posts into %d posts (dry run)", merged, len(merges))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("# merged %d duplicate posts into %d posts", merged, len(merges))
+}
+
+func cacheCmd(args []string) {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc cache stats|prune|clear
+
+Shows statistics about the cache of pages fetched by the classifier (stats),
+removes pages older than -http-cache-ttl and then the oldest pages until the
+cache is smaller than -http-cache-size (prune), or removes all cached pages
+(clear). The cache is in the directory given by -http-cache-dir.
+`)
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+
+	c := classifier.HTTPCache()
+	if c == nil {
+		log.Fatal("Caching is disabled (-http-cache-dir is empty).")
+	}
+
+	switch fs.Arg(0) {
+	case "stats":
+		s, err := c.Stats()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("directory:  %s\n", c.Dir)
+		fmt.Printf("pages:      %d (%d older than %s)\n", s.Entries, s.Expired, c.TTL)
+		fmt.Printf("size:       %.1f MB (of %d MB)\n", float64(s.Size)/(1<<20), c.MaxSize>>20)
+		if s.Entries > 0 {
+			fmt.Printf("oldest:     %s\n", s.Oldest.Format(time.RFC3339))
+			fmt.Printf("newest:     %s\n", s.Newest.Format(time.RFC3339))
+		}
+
+	case "prune":
+		n, err := c.Prune()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("# removed %d cached pages", n)
+
+	case "clear":
+		if err := c.Clear(); err != nil {
+			log.Fatal(err)
+		}
+
+	default:
+		fs.Usage()
+	}
+}
+
 func createDBCmd(args []string) {
 	fs := flag.NewFlagSet("createdb", flag.ExitOnError)
 	drop := fs.Bool("drop", false, "drop DB before creating")
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, `usage: thesrc createdb [options] 
 
-Creates the necessary DB tables and indexes.
+Creates the necessary DB tables and indexes by applying all pending schema
+migrations (like "thesrc migrate up").
 
 The options are:
 `)
@@ -356,3 +951,136 @@ The options are:
 	}
 	datastore.Create()
 }
+
+func migrateCmd(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	n := fs.Int("n", 0, "number of migrations to apply or revert (default: all pending migrations for up, 1 for down)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc migrate [options] up|down|status
+
+Applies pending database schema migrations (up), reverts the most recently
+applied migrations (down), or lists migrations and whether they have been
+applied (status). Applied migrations are recorded in the schema_migrations
+table, so it is safe to run "thesrc migrate up" repeatedly.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+
+	datastore.Connect()
+
+	switch fs.Arg(0) {
+	case "up":
+		if err := datastore.MigrateUp(*n); err != nil {
+			log.Fatal(err)
+		}
+
+	case "down":
+		if *n <= 0 {
+			*n = 1
+		}
+		if err := datastore.MigrateDown(*n); err != nil {
+			log.Fatal(err)
+		}
+
+	case "status":
+	default:
+		fs.Usage()
+	}
+
+	statuses, err := datastore.MigrationStatuses()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied() {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-6d %-40s %s\n", s.Version, s.Name, applied)
+	}
+}
+
+func tokenCmd(args []string) {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	login := fs.String("login", "", "log in as this user (instead of using -token) to create a token; the password is read from $THESRC_PASSWORD")
+	name := fs.String("name", "thesrc", "name of token to create")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: thesrc token [options] create|list|revoke [ID]
+
+Creates, lists, and revokes API tokens for the authenticated user.
+
+To create your first token, run:
+
+        THESRC_PASSWORD=... thesrc token -login=USER create
+
+Then pass the printed token to other commands with -token=TOKEN or the
+THESRC_TOKEN environment variable.
+
+The options are:
+`)
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+
+	c := apiclient
+	if *login != "" {
+		sess, err := apiclient.Users.Login(&thesrc.Credentials{Login: *login, Password: os.Getenv("THESRC_PASSWORD")})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer apiclient.Users.Logout(sess.Token)
+		c = apiclient.WithToken(sess.Token)
+	}
+
+	switch fs.Arg(0) {
+	case "create":
+		if fs.NArg() != 1 {
+			fs.Usage()
+		}
+		tok := &thesrc.APIToken{Name: *name}
+		if err := c.Tokens.Create(tok); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(tok.Token)
+
+	case "list":
+		if fs.NArg() != 1 {
+			fs.Usage()
+		}
+		tokens, err := c.Tokens.List(&thesrc.APITokenListOptions{ListOptions: thesrc.ListOptions{PerPage: 100}})
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, tok := range tokens {
+			fmt.Printf("%-6d %-30s %s\n", tok.ID, tok.Name, tok.CreatedAt.Format("2006-01-02 15:04"))
+		}
+
+	case "revoke":
+		if fs.NArg() != 2 {
+			fs.Usage()
+		}
+		id, err := strconv.Atoi(fs.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := c.Tokens.Revoke(id); err != nil {
+			log.Fatal(err)
+		}
+
+	default:
+		fs.Usage()
+	}
+}
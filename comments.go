@@ -0,0 +1,166 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A Comment is a comment on a post. Comments are threaded: a comment may be a
+// reply to another comment on the same post.
+type Comment struct {
+	// ID a unique identifier for this comment.
+	ID int `json:",omitempty"`
+
+	// PostID is the ID of the post that this comment is on.
+	PostID int
+
+	// ParentID is the ID of the comment that this comment is a reply to, or 0
+	// if this is a top-level comment on the post.
+	ParentID int `json:",omitempty"`
+
+	// AuthorUserID is the user ID of this comment's author.
+	AuthorUserID int
+
+	// AuthorLogin is the login of this comment's author. It is not stored;
+	// it is filled in when comments are fetched.
+	AuthorLogin string `json:",omitempty"`
+
+	// Body of the comment.
+	Body string
+
+	// SubmittedAt is when the comment was submitted.
+	SubmittedAt time.Time
+}
+
+// CommentsService interacts with the comment-related endpoints in thesrc's
+// API.
+type CommentsService interface {
+	// Get a comment on a post.
+	Get(postID, id int) (*Comment, error)
+
+	// List all comments on a post, oldest first.
+	List(postID int) ([]*Comment, error)
+
+	// Submit a comment on comment.PostID. On success, comment.ID is set to
+	// the new comment's ID.
+	Submit(comment *Comment) error
+
+	// Delete a comment on a post, along with all replies to it.
+	Delete(postID, id int) error
+}
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+type commentsService struct{ client *Client }
+
+func (s *commentsService) Get(postID, id int) (*Comment, error) {
+	url, err := s.client.url(router.Comment, map[string]string{"ID": strconv.Itoa(postID), "CommentID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment *Comment
+	_, err = s.client.Do(req, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentsService) List(postID int) ([]*Comment, error) {
+	url, err := s.client.url(router.Comments, map[string]string{"ID": strconv.Itoa(postID)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []*Comment
+	_, err = s.client.Do(req, &comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (s *commentsService) Submit(comment *Comment) error {
+	url, err := s.client.url(router.SubmitComment, map[string]string{"ID": strconv.Itoa(comment.PostID)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), comment)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &comment)
+	return err
+}
+
+func (s *commentsService) Delete(postID, id int) error {
+	url, err := s.client.url(router.DeleteComment, map[string]string{"ID": strconv.Itoa(postID), "CommentID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockCommentsService struct {
+	Get_    func(postID, id int) (*Comment, error)
+	List_   func(postID int) ([]*Comment, error)
+	Submit_ func(comment *Comment) error
+	Delete_ func(postID, id int) error
+}
+
+var _ CommentsService = &MockCommentsService{}
+
+func (s *MockCommentsService) Get(postID, id int) (*Comment, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(postID, id)
+}
+
+func (s *MockCommentsService) List(postID int) ([]*Comment, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(postID)
+}
+
+func (s *MockCommentsService) Submit(comment *Comment) error {
+	if s.Submit_ == nil {
+		return nil
+	}
+	return s.Submit_(comment)
+}
+
+func (s *MockCommentsService) Delete(postID, id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(postID, id)
+}
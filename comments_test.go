@@ -0,0 +1,123 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestCommentsService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Comment{ID: 2, PostID: 1}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Comment, map[string]string{"ID": "1", "CommentID": "2"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	comment, err := client.Comments.Get(1, 2)
+	if err != nil {
+		t.Errorf("Comments.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(comment, want) {
+		t.Errorf("Comments.Get returned %+v, want %+v", comment, want)
+	}
+}
+
+func TestCommentsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*Comment{{ID: 2, PostID: 1}, {ID: 3, PostID: 1, ParentID: 2}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Comments, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	comments, err := client.Comments.List(1)
+	if err != nil {
+		t.Errorf("Comments.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, c := range want {
+		normalizeTime(&c.SubmittedAt)
+	}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("Comments.List returned %+v, want %+v", comments, want)
+	}
+}
+
+func TestCommentsService_Submit(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Comment{ID: 3, PostID: 1, ParentID: 2, Body: "b"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.SubmitComment, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"PostID":1,"ParentID":2,"AuthorUserID":0,"Body":"b","SubmittedAt":"0001-01-01T00:00:00Z"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	comment := &Comment{PostID: 1, ParentID: 2, Body: "b"}
+	err := client.Comments.Submit(comment)
+	if err != nil {
+		t.Errorf("Comments.Submit returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(comment, want) {
+		t.Errorf("Comments.Submit returned %+v, want %+v", comment, want)
+	}
+}
+
+func TestCommentsService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DeleteComment, map[string]string{"ID": "1", "CommentID": "2"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Comments.Delete(1, 2)
+	if err != nil {
+		t.Errorf("Comments.Delete returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
@@ -0,0 +1,57 @@
+package datastore
+
+import (
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// ClassificationsStore stores the results of fetching and classifying posts'
+// links.
+type ClassificationsStore interface {
+	// Record stores post's Metadata and (unless its stored classification
+	// has been confirmed) its Classification, ClassificationScore, and
+	// Languages. Unlike PostsService.Update, it leaves the post's other
+	// fields alone, so that changes made while its link was being fetched
+	// (such as votes) aren't overwritten.
+	Record(post *thesrc.Post) error
+}
+
+type classificationsStore struct{ *Datastore }
+
+func (s *classificationsStore) Record(post *thesrc.Post) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var confirmed []bool
+		if err := tx.Select(&confirmed, `SELECT classificationconfirmed FROM post WHERE id=$1;`, post.ID); err != nil {
+			return err
+		}
+		if len(confirmed) == 0 {
+			return thesrc.ErrPostNotFound
+		}
+
+		if !confirmed[0] {
+			if _, err := tx.Exec(`UPDATE post SET classification=$1, classificationscore=$2 WHERE id=$3;`, post.Classification, post.ClassificationScore, post.ID); err != nil {
+				return err
+			}
+			if err := setLanguages(tx, post.ID, post.Languages); err != nil {
+				return err
+			}
+		}
+		if post.Metadata != nil {
+			return setMetadata(tx, post.ID, post.Metadata)
+		}
+		return nil
+	})
+}
+
+type MockClassificationsStore struct {
+	Record_ func(post *thesrc.Post) error
+}
+
+var _ ClassificationsStore = &MockClassificationsStore{}
+
+func (s *MockClassificationsStore) Record(post *thesrc.Post) error {
+	if s.Record_ == nil {
+		return nil
+	}
+	return s.Record_(post)
+}
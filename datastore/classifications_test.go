@@ -0,0 +1,85 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// testClassificationsStore tests d's classifications (d must have no posts).
+func testClassificationsStore(t *testing.T, d *Datastore) {
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	// Votes while the post's link is being classified aren't lost.
+	stale, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Posts.Vote(&thesrc.Vote{PostID: post.ID, UserID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	stale.Classification = thesrc.ClassificationCode
+	stale.ClassificationScore = 0.9
+	stale.Languages = []string{"go"}
+	stale.Metadata = &thesrc.LinkMetadata{Description: "d"}
+	if err := d.Classifications.Record(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	post, err = d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Score != 1 {
+		t.Errorf("got score %d, want 1 (the vote during classification)", post.Score)
+	}
+	if post.Classification != thesrc.ClassificationCode || post.ClassificationScore != 0.9 || !reflect.DeepEqual(post.Languages, []string{"go"}) {
+		t.Errorf("got classification %q (score %v, languages %q), want the recorded classification", post.Classification, post.ClassificationScore, post.Languages)
+	}
+	if post.Metadata == nil || post.Metadata.Description != "d" {
+		t.Errorf("got metadata %+v, want the recorded metadata", post.Metadata)
+	}
+
+	// Confirmed classifications aren't replaced, but metadata is.
+	post.Classification = thesrc.ClassificationNotCode
+	post.ClassificationConfirmed = true
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	stale.Metadata = &thesrc.LinkMetadata{Description: "d2"}
+	if err := d.Classifications.Record(stale); err != nil {
+		t.Fatal(err)
+	}
+	post, err = d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Classification != thesrc.ClassificationNotCode {
+		t.Errorf("got classification %q, want the confirmed classification %q", post.Classification, thesrc.ClassificationNotCode)
+	}
+	if post.Metadata == nil || post.Metadata.Description != "d2" {
+		t.Errorf("got metadata %+v, want the newly recorded metadata", post.Metadata)
+	}
+
+	if err := d.Classifications.Record(&thesrc.Post{ID: post.ID + 1}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestClassificationsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM vote;`) // test on a clean DB
+	tx.Exec(`DELETE FROM job;`)
+	tx.Exec(`DELETE FROM post;`)
+
+	testClassificationsStore(t, NewDatastore(tx))
+}
+
+func TestMemoryClassificationsStore(t *testing.T) {
+	testClassificationsStore(t, NewMemoryDatastore())
+}
@@ -0,0 +1,89 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	t := DB.AddTableWithName(thesrc.Comment{}, "comment").SetKeys(true, "ID")
+	t.ColMap("AuthorLogin").SetTransient(true)
+}
+
+type commentsStore struct{ *Datastore }
+
+// selectCommentsSQL selects comments along with their authors' logins.
+const selectCommentsSQL = `SELECT comment.*, coalesce(users.login, '') AS authorlogin FROM comment LEFT JOIN users ON users.id=comment.authoruserid`
+
+func (s *commentsStore) Get(postID, id int) (*thesrc.Comment, error) {
+	var comments []*thesrc.Comment
+	if err := s.dbh.Select(&comments, selectCommentsSQL+` WHERE comment.postid=$1 AND comment.id=$2;`, postID, id); err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, thesrc.ErrCommentNotFound
+	}
+	return comments[0], nil
+}
+
+func (s *commentsStore) List(postID int) ([]*thesrc.Comment, error) {
+	var comments []*thesrc.Comment
+	if err := s.dbh.Select(&comments, selectCommentsSQL+` WHERE comment.postid=$1 ORDER BY comment.submittedat ASC, comment.id ASC;`, postID); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *commentsStore) Submit(comment *thesrc.Comment) error {
+	if comment.SubmittedAt.IsZero() {
+		comment.SubmittedAt = time.Now()
+	}
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		d := NewDatastore(tx)
+		if _, err := d.Posts.Get(comment.PostID); err != nil {
+			return err
+		}
+		if comment.ParentID != 0 {
+			if _, err := d.Comments.Get(comment.PostID, comment.ParentID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Insert(comment); err != nil {
+			return err
+		}
+		return updateCommentCount(tx, comment.PostID)
+	})
+}
+
+func (s *commentsStore) Delete(postID, id int) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		// Delete the comment and all of its replies (recursively).
+		res, err := tx.Exec(`WITH RECURSIVE thread(id) AS (
+  SELECT id FROM comment WHERE postid=$1 AND id=$2
+  UNION ALL
+  SELECT comment.id FROM comment JOIN thread ON comment.parentid=thread.id
+)
+DELETE FROM comment WHERE id IN (SELECT id FROM thread);`, postID, id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return thesrc.ErrCommentNotFound
+		}
+		return updateCommentCount(tx, postID)
+	})
+}
+
+// updateCommentCount recomputes the CommentCount of the post with the given
+// ID.
+func updateCommentCount(dbh modl.SqlExecutor, postID int) error {
+	_, err := dbh.Exec(`UPDATE post SET commentcount=(SELECT count(*) FROM comment WHERE postid=$1) WHERE id=$1;`, postID)
+	return err
+}
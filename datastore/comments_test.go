@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestCommentsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM comment;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+	tx.Exec(`DELETE FROM users;`)
+	post, user := &thesrc.Post{}, &thesrc.User{Login: "alice"}
+	if err := tx.Insert(post, user); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	checkCommentCount := func(want int) {
+		got, err := d.Posts.Get(post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.CommentCount != want {
+			t.Errorf("got comment count %d, want %d", got.CommentCount, want)
+		}
+	}
+
+	top := &thesrc.Comment{PostID: post.ID, AuthorUserID: user.ID, Body: "a"}
+	if err := d.Comments.Submit(top); err != nil {
+		t.Fatal(err)
+	}
+	reply := &thesrc.Comment{PostID: post.ID, ParentID: top.ID, AuthorUserID: user.ID, Body: "b"}
+	if err := d.Comments.Submit(reply); err != nil {
+		t.Fatal(err)
+	}
+	checkCommentCount(2)
+
+	comments, err := d.Comments.List(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].ID != top.ID || comments[1].ParentID != top.ID {
+		t.Errorf("got comments %+v, want top-level comment then reply", comments)
+	}
+	if comments[0].AuthorLogin != "alice" {
+		t.Errorf("got author login %q, want %q", comments[0].AuthorLogin, "alice")
+	}
+
+	if err := d.Comments.Submit(&thesrc.Comment{PostID: post.ID, ParentID: 12345, Body: "c"}); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrCommentNotFound)
+	}
+	if err := d.Comments.Submit(&thesrc.Comment{PostID: post.ID + 1, Body: "c"}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	// Deleting a comment also deletes its replies.
+	if err := d.Comments.Delete(post.ID, top.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Comments.Get(post.ID, reply.ID); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrCommentNotFound)
+	}
+	checkCommentCount(0)
+
+	if err := d.Comments.Delete(post.ID, top.ID); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrCommentNotFound)
+	}
+}
@@ -5,27 +5,55 @@ import (
 	"sourcegraph.com/sourcegraph/thesrc"
 )
 
-// A Datastore accesses the datastore (in PostgreSQL).
+// A Datastore accesses the datastore (in PostgreSQL, or in memory if InMemory
+// is set).
 type Datastore struct {
-	Posts thesrc.PostsService
+	Posts    thesrc.PostsService
+	Comments thesrc.CommentsService
+	Users    thesrc.UsersService
+	Tokens   thesrc.TokensService
+	Imports  thesrc.ImportsService
+	Jobs     JobsStore
+
+	LinkChecks      LinkChecksStore
+	Classifications ClassificationsStore
 
 	dbh modl.SqlExecutor
 }
 
 // NewDatastore creates a new client for accessing the datastore (in
-// PostgreSQL). If dbh is nil, it uses the global DB handle.
+// PostgreSQL). If dbh is nil, it uses the global DB handle, or, if InMemory
+// is set, the process-wide in-memory datastore.
 func NewDatastore(dbh modl.SqlExecutor) *Datastore {
 	if dbh == nil {
+		if InMemory {
+			return sharedMemoryDatastore()
+		}
 		dbh = DBH
 	}
 
 	d := &Datastore{dbh: dbh}
 	d.Posts = &postsStore{d}
+	d.Comments = &commentsStore{d}
+	d.Users = &usersStore{d}
+	d.Tokens = &tokensStore{d}
+	d.Imports = &importsStore{d}
+	d.Jobs = &jobsStore{d}
+	d.LinkChecks = &linkChecksStore{d}
+	d.Classifications = &classificationsStore{d}
 	return d
 }
 
 func NewMockDatastore() *Datastore {
 	return &Datastore{
-		Posts: &thesrc.MockPostsService{},
+		Posts:    &thesrc.MockPostsService{},
+		Comments: &thesrc.MockCommentsService{},
+		Users:    &thesrc.MockUsersService{},
+		Tokens:   &thesrc.MockTokensService{},
+		Imports:  &thesrc.MockImportsService{},
+		Jobs:     &MockJobsStore{},
+
+		LinkChecks:      &MockLinkChecksStore{},
+		Classifications: &MockClassificationsStore{},
 	}
 }
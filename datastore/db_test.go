@@ -1,12 +1,46 @@
 package datastore
 
 import (
+	"flag"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"testing"
 )
 
-func init() {
+// TestMain runs the tests against each database in testDSNs, resetting the
+// database first.
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	status := 0
+	for _, dsn := range testDSNs() {
+		if dsn == "" {
+			log.Printf("Running datastore tests against PostgreSQL database %q.", os.Getenv("PGDATABASE"))
+		} else {
+			log.Printf("Running datastore tests against %q.", dsn)
+		}
+		if err := open(dsn); err != nil {
+			log.Fatal(err)
+		}
+
+		// Reset DB.
+		Drop()
+		Create()
+
+		if code := m.Run(); code != 0 {
+			status = code
+		}
+		DB.Db.Close()
+	}
+	os.Exit(status)
+}
+
+// testDSNs returns the DSNs of the databases to run the tests against: the
+// PostgreSQL database specified by the PG* environment variables and a SQLite
+// database in a temporary file.
+func testDSNs() []string {
 	// Make sure we don't run the tests, which clobber data, on the main DB.
 	dbname := os.Getenv("PGDATABASE")
 	if dbname == "" {
@@ -19,8 +53,12 @@ func init() {
 		log.Fatal(err)
 	}
 
-	// Reset DB.
-	Connect()
-	Drop()
-	Create()
+	sqliteFile := filepath.Join(os.TempDir(), "thesrctest.db")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(sqliteFile + suffix); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+	}
+
+	return []string{"", "sqlite://" + sqliteFile}
 }
@@ -0,0 +1,107 @@
+package datastore
+
+import (
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A PostMerge describes duplicate posts (posts whose links have the same
+// canonical URL) that were merged into one post.
+type PostMerge struct {
+	// Kept is the post that the duplicates were merged into.
+	Kept *thesrc.Post
+
+	// Merged are the duplicate posts, which were deleted.
+	Merged []*thesrc.Post
+}
+
+// DedupePosts sets the canonical URLs of all posts with links and merges
+// posts whose links have the same canonical URL, returning the merges. The
+// kept post of each set of duplicates is the one with the highest score (then
+// external score), or the earliest submitted. The duplicates' comments, votes,
+// and tags are moved to the kept post before they are deleted.
+//
+// Posts submitted before canonical URLs were introduced may have duplicates
+// (new posts are checked for duplicates when they are submitted).
+func DedupePosts(dbh modl.SqlExecutor) ([]*PostMerge, error) {
+	var merges []*PostMerge
+	err := transact(dbh, func(tx modl.SqlExecutor) error {
+		var posts []*thesrc.Post
+		if err := tx.Select(&posts, `SELECT `+postColumns+` FROM post WHERE linkurl <> '' ORDER BY score DESC, externalscore DESC, submittedat, id;`); err != nil {
+			return err
+		}
+
+		// Group the posts by canonical URL. The first post in each group is
+		// the one to keep.
+		var canonicalURLs []string
+		groups := map[string][]*thesrc.Post{}
+		for _, post := range posts {
+			canonicalURL := thesrc.CanonicalizeURL(post.LinkURL)
+			if _, present := groups[canonicalURL]; !present {
+				canonicalURLs = append(canonicalURLs, canonicalURL)
+			}
+			groups[canonicalURL] = append(groups[canonicalURL], post)
+		}
+
+		for _, canonicalURL := range canonicalURLs {
+			group := groups[canonicalURL]
+			kept := group[0]
+			if len(group) > 1 {
+				if err := mergePosts(tx, kept, group[1:]); err != nil {
+					return err
+				}
+				merges = append(merges, &PostMerge{Kept: kept, Merged: group[1:]})
+			}
+
+			// Set the canonical URL after deleting the duplicates, one of
+			// which may have it already.
+			if kept.CanonicalURL != canonicalURL {
+				if _, err := tx.Exec(`UPDATE post SET canonicalurl=$1 WHERE id=$2;`, canonicalURL, kept.ID); err != nil {
+					return err
+				}
+				kept.CanonicalURL = canonicalURL
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merges, nil
+}
+
+// mergePosts moves the comments, votes, and tags of dups to kept, deletes
+// dups, and updates kept's score, external score, and comment count.
+func mergePosts(tx modl.SqlExecutor, kept *thesrc.Post, dups []*thesrc.Post) error {
+	for _, dup := range dups {
+		if _, err := tx.Exec(`UPDATE comment SET postid=$1 WHERE postid=$2;`, kept.ID, dup.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO vote(postid, userid) SELECT $1, userid FROM vote WHERE postid=$2 AND userid NOT IN (SELECT userid FROM vote WHERE postid=$1);`, kept.ID, dup.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO post_tags(postid, tag) SELECT $1, tag FROM post_tags WHERE postid=$2 AND tag NOT IN (SELECT tag FROM post_tags WHERE postid=$1);`, kept.ID, dup.ID); err != nil {
+			return err
+		}
+		if err := NewDatastore(tx).Posts.Delete(dup.ID); err != nil {
+			return err
+		}
+		if dup.ExternalScore > kept.ExternalScore {
+			kept.ExternalScore = dup.ExternalScore
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE post SET score=(SELECT count(*) FROM vote WHERE postid=$1), externalscore=$2 WHERE id=$1;`, kept.ID, kept.ExternalScore); err != nil {
+		return err
+	}
+	if err := updateCommentCount(tx, kept.ID); err != nil {
+		return err
+	}
+
+	post, err := NewDatastore(tx).Posts.Get(kept.ID)
+	if err != nil {
+		return err
+	}
+	*kept = *post
+	return nil
+}
@@ -0,0 +1,125 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestDedupePosts_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM comment;`) // test on a clean DB
+	tx.Exec(`DELETE FROM vote;`)
+	tx.Exec(`DELETE FROM post_tags;`)
+	tx.Exec(`DELETE FROM job;`)
+	tx.Exec(`DELETE FROM post;`)
+
+	// Posts submitted before canonical URLs were introduced have their link
+	// URLs as their canonical URLs.
+	var posts []*thesrc.Post
+	for _, post := range []*thesrc.Post{
+		{LinkURL: "http://example.com/a", ExternalScore: 5},
+		{LinkURL: "https://www.example.com/a/?utm_source=x", ExternalScore: 7},
+		{LinkURL: "https://example.com/a#top"},
+		{LinkURL: "http://example.com/b"},
+	} {
+		post.CanonicalURL = post.LinkURL
+		if err := tx.Insert(post); err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+
+	d := NewDatastore(tx)
+	for _, vote := range []*thesrc.Vote{{PostID: posts[1].ID, UserID: 1}, {PostID: posts[1].ID, UserID: 2}, {PostID: posts[0].ID, UserID: 1}, {PostID: posts[2].ID, UserID: 3}} {
+		if err := d.Posts.Vote(vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []*thesrc.Comment{{PostID: posts[0].ID, Body: "a"}, {PostID: posts[2].ID, Body: "b"}} {
+		if err := d.Comments.Submit(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := setTags(tx, posts[0].ID, []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setTags(tx, posts[1].ID, []string{"go", "web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	merges, err := DedupePosts(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// posts[1] has the highest score.
+	if len(merges) != 1 {
+		t.Fatalf("got %d merges, want 1", len(merges))
+	}
+	kept := merges[0].Kept
+	if kept.ID != posts[1].ID {
+		t.Errorf("got kept post %d, want %d", kept.ID, posts[1].ID)
+	}
+	var mergedIDs []int
+	for _, post := range merges[0].Merged {
+		mergedIDs = append(mergedIDs, post.ID)
+	}
+	if want := []int{posts[0].ID, posts[2].ID}; !reflect.DeepEqual(mergedIDs, want) {
+		t.Errorf("got merged posts %v, want %v", mergedIDs, want)
+	}
+
+	kept, err = d.Posts.Get(posts[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/a"; kept.CanonicalURL != want {
+		t.Errorf("got canonical URL %q, want %q", kept.CanonicalURL, want)
+	}
+	if kept.Score != 3 || kept.ExternalScore != 7 || kept.CommentCount != 2 {
+		t.Errorf("got score=%d externalscore=%d commentcount=%d, want 3, 7, 2", kept.Score, kept.ExternalScore, kept.CommentCount)
+	}
+	if want := []string{"go", "web"}; !reflect.DeepEqual(kept.Tags, want) {
+		t.Errorf("got tags %v, want %v", kept.Tags, want)
+	}
+	comments, err := d.Comments.List(kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Errorf("got %d comments on the kept post, want 2", len(comments))
+	}
+	for _, post := range merges[0].Merged {
+		if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+			t.Errorf("got error %v for merged post %d, want %v", err, post.ID, thesrc.ErrPostNotFound)
+		}
+	}
+
+	// Posts without duplicates get canonical URLs too.
+	other, err := d.Posts.Get(posts[3].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/b"; other.CanonicalURL != want {
+		t.Errorf("got canonical URL %q, want %q", other.CanonicalURL, want)
+	}
+
+	// New submissions of the same link are recognized as duplicates.
+	dup := &thesrc.Post{LinkURL: "http://www.example.com/a"}
+	created, err := d.Posts.Submit(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || dup.ID != kept.ID {
+		t.Errorf("got created=%v ID=%d, want existing post %d", created, dup.ID, kept.ID)
+	}
+
+	// Deduping again does nothing.
+	if merges, err := DedupePosts(tx); err != nil {
+		t.Fatal(err)
+	} else if len(merges) != 0 {
+		t.Errorf("got %d merges after deduping, want none", len(merges))
+	}
+}
@@ -0,0 +1,101 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.ImportState{}, "import_state").SetKeys(false, "Site")
+	DB.AddTableWithName(importSeenItem{}, "import_seen_item").SetKeys(false, "Site", "Position")
+}
+
+// An importSeenItem is a row in the import_seen_item table, which stores the
+// SeenIDs of import states. Position is the item ID's index in SeenIDs.
+type importSeenItem struct {
+	Site     string
+	Position int
+	ItemID   string
+}
+
+type importsStore struct{ *Datastore }
+
+func (s *importsStore) List(opt *thesrc.ImportStateListOptions) ([]*thesrc.ImportState, error) {
+	if opt == nil {
+		opt = &thesrc.ImportStateListOptions{}
+	}
+
+	var states []*thesrc.ImportState
+	var err error
+	if opt.Site != "" {
+		err = s.dbh.Select(&states, `SELECT * FROM import_state WHERE site=$1;`, opt.Site)
+	} else {
+		err = s.dbh.Select(&states, `SELECT * FROM import_state ORDER BY site;`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := loadSeenIDs(s.dbh, states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (s *importsStore) Update(state *thesrc.ImportState) error {
+	state.LastRunAt = state.LastRunAt.UTC()
+	state.LastSuccessAt = state.LastSuccessAt.UTC()
+	state.LastFailureAt = state.LastFailureAt.UTC()
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		if _, err := tx.Exec(`DELETE FROM import_state WHERE site=$1;`, state.Site); err != nil {
+			return err
+		}
+		if err := tx.Insert(state); err != nil {
+			return err
+		}
+		return setSeenIDs(tx, state.Site, state.SeenIDs)
+	})
+}
+
+// loadSeenIDs sets the SeenIDs field of each import state.
+func loadSeenIDs(dbh modl.SqlExecutor, states []*thesrc.ImportState) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	bySite := make(map[string]*thesrc.ImportState, len(states))
+	var args []interface{}
+	var placeholders []string
+	for _, state := range states {
+		state.SeenIDs = nil
+		bySite[state.Site] = state
+		args = append(args, state.Site)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var items []*importSeenItem
+	if err := dbh.Select(&items, `SELECT * FROM import_seen_item WHERE site IN (`+strings.Join(placeholders, ",")+`) ORDER BY site, position;`, args...); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if state, present := bySite[item.Site]; present {
+			state.SeenIDs = append(state.SeenIDs, item.ItemID)
+		}
+	}
+	return nil
+}
+
+// setSeenIDs replaces the seen item IDs of the import state of site.
+func setSeenIDs(dbh modl.SqlExecutor, site string, ids []string) error {
+	if _, err := dbh.Exec(`DELETE FROM import_seen_item WHERE site=$1;`, site); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if err := dbh.Insert(&importSeenItem{Site: site, Position: i, ItemID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
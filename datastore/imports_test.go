@@ -0,0 +1,81 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// testImportsStore tests d's import states (d must have none).
+func testImportsStore(t *testing.T, d *Datastore) {
+	listStates := func(opt *thesrc.ImportStateListOptions) []*thesrc.ImportState {
+		states, err := d.Imports.List(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return states
+	}
+
+	if states := listStates(nil); len(states) != 0 {
+		t.Errorf("got import states %+v, want none", states)
+	}
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	want := &thesrc.ImportState{Site: "hn/top", LastRunAt: now, LastSuccessAt: now, ETag: `"abc"`, LastModified: "Thu, 01 Oct 2026 12:00:00 GMT", SeenIDs: []string{"b", "a"}}
+	for _, state := range []*thesrc.ImportState{want, {Site: "/r/golang", LastRunAt: now, LastFailureAt: now, LastError: "x", Failures: 1}} {
+		if err := d.Imports.Update(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	states := listStates(nil)
+	if len(states) != 2 || states[0].Site != "/r/golang" || states[1].Site != "hn/top" {
+		t.Fatalf("got import states %+v, want /r/golang and hn/top", states)
+	}
+	states = listStates(&thesrc.ImportStateListOptions{Site: "hn/top"})
+	if len(states) != 1 {
+		t.Fatalf("got %d import states, want 1", len(states))
+	}
+	normalizeImportState(states[0])
+	if !reflect.DeepEqual(states[0], want) {
+		t.Errorf("got import state %+v, want %+v", states[0], want)
+	}
+
+	// Updating replaces the state, including the seen item IDs.
+	want.Failures = 2
+	want.SeenIDs = []string{"c"}
+	if err := d.Imports.Update(want); err != nil {
+		t.Fatal(err)
+	}
+	states = listStates(&thesrc.ImportStateListOptions{Site: "hn/top"})
+	if len(states) != 1 {
+		t.Fatalf("got %d import states, want 1", len(states))
+	}
+	normalizeImportState(states[0])
+	if !reflect.DeepEqual(states[0], want) {
+		t.Errorf("got import state %+v after updating, want %+v", states[0], want)
+	}
+}
+
+// normalizeImportState sets the time zones of state's times to UTC (which
+// the database may not preserve).
+func normalizeImportState(state *thesrc.ImportState) {
+	state.LastRunAt = state.LastRunAt.UTC()
+	state.LastSuccessAt = state.LastSuccessAt.UTC()
+	state.LastFailureAt = state.LastFailureAt.UTC()
+}
+
+func TestImportsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM import_seen_item;`) // test on a clean DB
+	tx.Exec(`DELETE FROM import_state;`)
+
+	testImportsStore(t, NewDatastore(tx))
+}
+
+func TestMemoryImportsStore(t *testing.T) {
+	testImportsStore(t, NewMemoryDatastore())
+}
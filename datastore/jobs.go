@@ -0,0 +1,207 @@
+package datastore
+
+import (
+	"time"
+
+	"github.com/jmoiron/modl"
+)
+
+func init() {
+	DB.AddTableWithName(Job{}, "job").SetKeys(true, "ID")
+}
+
+// A Job is a unit of background work, such as classifying a newly submitted
+// post. Jobs are stored in the job table until a worker completes them (or
+// gives up on them).
+type Job struct {
+	ID int
+
+	// Kind is the kind of work to do (such as JobClassifyPost).
+	Kind string
+
+	// PostID is the ID of the post that the job operates on.
+	PostID int
+
+	// Attempts is the number of times that a worker has claimed the job.
+	Attempts int
+
+	// RunAt is the earliest time that a worker may claim the job.
+	RunAt time.Time
+
+	// LockedUntil is the time until which the worker that last claimed the
+	// job has exclusive access to it. If the worker dies before completing
+	// the job, another worker may claim it after this time.
+	LockedUntil time.Time
+
+	// LastError is the error from the job's last failed attempt.
+	LastError string
+
+	// Failed is whether workers have given up on the job.
+	Failed bool
+
+	CreatedAt time.Time
+}
+
+// Kinds of jobs.
+const (
+	// JobClassifyPost fetches a post's link, stores its metadata, and
+	// classifies it (and detects its languages).
+	JobClassifyPost = "classify-post"
+
+	// JobCheckLink checks whether a post's link still works.
+	JobCheckLink = "check-link"
+)
+
+// JobsStore is a queue of background jobs.
+type JobsStore interface {
+	// Enqueue adds a job to the queue, to be run as soon as possible (or at
+	// job.RunAt, if set).
+	Enqueue(job *Job) error
+
+	// Claim claims the job that has been waiting the longest to run, locking
+	// it for the given duration so that other workers don't also claim it.
+	// It returns nil if no jobs are ready to run.
+	Claim(lockFor time.Duration) (*Job, error)
+
+	// Complete removes a job that succeeded from the queue.
+	Complete(id int) error
+
+	// Retry records a job's failed attempt and schedules it to run again at
+	// runAt.
+	Retry(id int, err error, runAt time.Time) error
+
+	// Fail records a job's failed attempt and gives up on it. Failed jobs
+	// stay in the job table (for inspection) but are never claimed.
+	Fail(id int, err error) error
+
+	// List lists the jobs in the queue, including failed jobs, in the order
+	// they will be claimed.
+	List() ([]*Job, error)
+}
+
+type jobsStore struct{ *Datastore }
+
+const jobColumns = `id, kind, postid, attempts, runat, lockeduntil, lasterror, failed, createdat`
+
+func (s *jobsStore) Enqueue(job *Job) error {
+	return enqueueJob(s.dbh, job)
+}
+
+// enqueueJob adds job to the queue (see JobsStore.Enqueue) using dbh, so that
+// it can be enqueued in the same transaction as the change that required it.
+func enqueueJob(dbh modl.SqlExecutor, job *Job) error {
+	job.CreatedAt = time.Now().UTC()
+	if job.RunAt.IsZero() {
+		job.RunAt = job.CreatedAt
+	}
+	job.RunAt = job.RunAt.UTC()
+	return dbh.Insert(job)
+}
+
+func (s *jobsStore) Claim(lockFor time.Duration) (*Job, error) {
+	var job *Job
+	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		now := time.Now().UTC()
+
+		// In PostgreSQL, skip jobs that other workers are in the middle of
+		// claiming instead of waiting for them. SQLite doesn't need to
+		// (and can't): its transactions that write are serialized.
+		sql := `SELECT ` + jobColumns + ` FROM job WHERE NOT failed AND runat <= $1 AND lockeduntil <= $1 ORDER BY runat, id LIMIT 1`
+		if !isSQLite() {
+			sql += ` FOR UPDATE SKIP LOCKED`
+		}
+		var jobs []*Job
+		if err := tx.Select(&jobs, sql+`;`, now); err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		job = jobs[0]
+		job.Attempts++
+		job.LockedUntil = now.Add(lockFor)
+		_, err := tx.Exec(`UPDATE job SET attempts=$1, lockeduntil=$2 WHERE id=$3;`, job.Attempts, job.LockedUntil, job.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *jobsStore) Complete(id int) error {
+	_, err := s.dbh.Exec(`DELETE FROM job WHERE id=$1;`, id)
+	return err
+}
+
+func (s *jobsStore) Retry(id int, jobErr error, runAt time.Time) error {
+	_, err := s.dbh.Exec(`UPDATE job SET runat=$1, lockeduntil=$2, lasterror=$3 WHERE id=$4;`, runAt.UTC(), time.Time{}, jobErr.Error(), id)
+	return err
+}
+
+func (s *jobsStore) Fail(id int, jobErr error) error {
+	_, err := s.dbh.Exec(`UPDATE job SET failed=$1, lockeduntil=$2, lasterror=$3 WHERE id=$4;`, true, time.Time{}, jobErr.Error(), id)
+	return err
+}
+
+func (s *jobsStore) List() ([]*Job, error) {
+	var jobs []*Job
+	if err := s.dbh.Select(&jobs, `SELECT `+jobColumns+` FROM job ORDER BY failed, runat, id;`); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+type MockJobsStore struct {
+	Enqueue_  func(job *Job) error
+	Claim_    func(lockFor time.Duration) (*Job, error)
+	Complete_ func(id int) error
+	Retry_    func(id int, err error, runAt time.Time) error
+	Fail_     func(id int, err error) error
+	List_     func() ([]*Job, error)
+}
+
+var _ JobsStore = &MockJobsStore{}
+
+func (s *MockJobsStore) Enqueue(job *Job) error {
+	if s.Enqueue_ == nil {
+		return nil
+	}
+	return s.Enqueue_(job)
+}
+
+func (s *MockJobsStore) Claim(lockFor time.Duration) (*Job, error) {
+	if s.Claim_ == nil {
+		return nil, nil
+	}
+	return s.Claim_(lockFor)
+}
+
+func (s *MockJobsStore) Complete(id int) error {
+	if s.Complete_ == nil {
+		return nil
+	}
+	return s.Complete_(id)
+}
+
+func (s *MockJobsStore) Retry(id int, err error, runAt time.Time) error {
+	if s.Retry_ == nil {
+		return nil
+	}
+	return s.Retry_(id, err, runAt)
+}
+
+func (s *MockJobsStore) Fail(id int, err error) error {
+	if s.Fail_ == nil {
+		return nil
+	}
+	return s.Fail_(id, err)
+}
+
+func (s *MockJobsStore) List() ([]*Job, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_()
+}
@@ -0,0 +1,102 @@
+package datastore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// testJobsStore tests d's job queue (which must be empty).
+func testJobsStore(t *testing.T, d *Datastore) {
+	// Submitting a post enqueues a job to classify it.
+	post := &thesrc.Post{LinkURL: "http://example.com/jobs"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := d.Jobs.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Kind != JobClassifyPost || jobs[0].PostID != post.ID {
+		t.Fatalf("got jobs %+v, want a %s job for post %d", jobs, JobClassifyPost, post.ID)
+	}
+
+	claim := func(label string, wantAttempts int) *Job {
+		job, err := d.Jobs.Claim(time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case wantAttempts == 0 && job != nil:
+			t.Errorf("%s: claimed job %+v, want none", label, job)
+		case wantAttempts != 0 && job == nil:
+			t.Errorf("%s: claimed no job, want one", label)
+		case wantAttempts != 0 && job.Attempts != wantAttempts:
+			t.Errorf("%s: got attempts %d, want %d", label, job.Attempts, wantAttempts)
+		}
+		return job
+	}
+
+	job := claim("first", 1)
+	if job == nil {
+		return
+	}
+	claim("while locked", 0)
+
+	jobErr := errors.New("x")
+	if err := d.Jobs.Retry(job.ID, jobErr, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	claim("before retry time", 0)
+	if err := d.Jobs.Retry(job.ID, jobErr, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	claim("after retry time", 2)
+
+	if err := d.Jobs.Fail(job.ID, jobErr); err != nil {
+		t.Fatal(err)
+	}
+	claim("after failing", 0)
+
+	other := &Job{Kind: "other", PostID: post.ID}
+	if err := d.Jobs.Enqueue(other); err != nil {
+		t.Fatal(err)
+	}
+	if job := claim("other", 1); job != nil && job.ID != other.ID {
+		t.Errorf("claimed job %d, want %d", job.ID, other.ID)
+	}
+	if err := d.Jobs.Complete(other.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err = d.Jobs.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || !jobs[0].Failed || jobs[0].LastError != "x" {
+		t.Errorf("got jobs %+v, want only the failed job", jobs)
+	}
+
+	// Deleting a post deletes its jobs.
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _ := d.Jobs.List(); len(jobs) != 0 {
+		t.Errorf("got jobs %+v after deleting post, want none", jobs)
+	}
+}
+
+func TestJobsStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM job;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+
+	testJobsStore(t, NewDatastore(tx))
+}
+
+func TestMemoryJobsStore(t *testing.T) {
+	testJobsStore(t, NewMemoryDatastore())
+}
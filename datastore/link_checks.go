@@ -0,0 +1,105 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/modl"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.LinkCheck{}, "link_check").SetKeys(false, "PostID")
+}
+
+// LinkChecksStore stores the results of checking whether posts' links still
+// work.
+type LinkChecksStore interface {
+	// Record stores the result of checking a post's link, replacing the
+	// result of the previous check (if any).
+	Record(check *thesrc.LinkCheck) error
+
+	// ListDue lists up to limit posts with links that haven't been checked
+	// since checkedBefore (or ever), with their LinkCheck fields set to the
+	// previous check's result. Posts with pending JobCheckLink jobs are
+	// omitted, because they are about to be checked.
+	ListDue(checkedBefore time.Time, limit int) ([]*thesrc.Post, error)
+}
+
+type linkChecksStore struct{ *Datastore }
+
+func (s *linkChecksStore) Record(check *thesrc.LinkCheck) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		if _, err := tx.Exec(`DELETE FROM link_check WHERE postid=$1;`, check.PostID); err != nil {
+			return err
+		}
+		check.CheckedAt = check.CheckedAt.UTC()
+		return tx.Insert(check)
+	})
+}
+
+func (s *linkChecksStore) ListDue(checkedBefore time.Time, limit int) ([]*thesrc.Post, error) {
+	var posts []*thesrc.Post
+	sql := `SELECT ` + postColumns + ` FROM post WHERE linkurl <> '' ` +
+		`AND id NOT IN (SELECT postid FROM link_check WHERE checkedat >= $1) ` +
+		`AND id NOT IN (SELECT postid FROM job WHERE kind=$2 AND NOT failed) ` +
+		`ORDER BY id LIMIT $3;`
+	if err := s.dbh.Select(&posts, sql, checkedBefore.UTC(), JobCheckLink, limit); err != nil {
+		return nil, err
+	}
+	if err := loadLinkChecks(s.dbh, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// loadLinkChecks sets the LinkCheck field of each post to the result of the
+// last check of the post's link, if any.
+func loadLinkChecks(dbh modl.SqlExecutor, posts []*thesrc.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*thesrc.Post, len(posts))
+	var args []interface{}
+	var placeholders []string
+	for _, post := range posts {
+		post.LinkCheck = nil
+		byID[post.ID] = post
+		args = append(args, post.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var checks []*thesrc.LinkCheck
+	if err := dbh.Select(&checks, `SELECT * FROM link_check WHERE postid IN (`+strings.Join(placeholders, ",")+`);`, args...); err != nil {
+		return err
+	}
+	for _, c := range checks {
+		if post, present := byID[c.PostID]; present {
+			post.LinkCheck = c
+		}
+	}
+	return nil
+}
+
+type MockLinkChecksStore struct {
+	Record_  func(check *thesrc.LinkCheck) error
+	ListDue_ func(checkedBefore time.Time, limit int) ([]*thesrc.Post, error)
+}
+
+var _ LinkChecksStore = &MockLinkChecksStore{}
+
+func (s *MockLinkChecksStore) Record(check *thesrc.LinkCheck) error {
+	if s.Record_ == nil {
+		return nil
+	}
+	return s.Record_(check)
+}
+
+func (s *MockLinkChecksStore) ListDue(checkedBefore time.Time, limit int) ([]*thesrc.Post, error) {
+	if s.ListDue_ == nil {
+		return nil, nil
+	}
+	return s.ListDue_(checkedBefore, limit)
+}
@@ -0,0 +1,100 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// testLinkChecksStore tests d's link checks (d must have no posts).
+func testLinkChecksStore(t *testing.T, d *Datastore) {
+	var posts []*thesrc.Post
+	for _, linkURL := range []string{"http://example.com/1", "http://example.com/2", ""} {
+		post := &thesrc.Post{LinkURL: linkURL}
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+	// Remove the classification jobs enqueued by Submit.
+	jobs, _ := d.Jobs.List()
+	for _, job := range jobs {
+		d.Jobs.Complete(job.ID)
+	}
+
+	listDue := func(label string, checkedBefore time.Time, wantIDs ...int) []*thesrc.Post {
+		due, err := d.LinkChecks.ListDue(checkedBefore, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, post := range due {
+			ids = append(ids, post.ID)
+		}
+		if !reflect.DeepEqual(ids, wantIDs) {
+			t.Errorf("%s: got due posts %v, want %v", label, ids, wantIDs)
+		}
+		return due
+	}
+
+	// Posts without links are never due.
+	now := time.Now()
+	listDue("unchecked", now, posts[0].ID, posts[1].ID)
+
+	check := &thesrc.LinkCheck{PostID: posts[0].ID, StatusCode: 404, FinalURL: posts[0].LinkURL, Failures: 1, Dead: true, CheckedAt: now}
+	if err := d.LinkChecks.Record(check); err != nil {
+		t.Fatal(err)
+	}
+	listDue("after checking post 1", now.Add(-time.Hour), posts[1].ID)
+	due := listDue("after checking post 1, including recent checks", now.Add(time.Hour), posts[0].ID, posts[1].ID)
+	if len(due) > 0 && (due[0].LinkCheck == nil || due[0].LinkCheck.Failures != 1) {
+		t.Errorf("got due post with link check %+v, want the last check", due[0].LinkCheck)
+	}
+
+	// Posts about to be checked aren't due.
+	if err := d.Jobs.Enqueue(&Job{Kind: JobCheckLink, PostID: posts[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	listDue("with pending check job", now.Add(-time.Hour))
+
+	post, err := d.Posts.Get(posts[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.LinkCheck == nil || !post.LinkCheck.Dead || post.LinkCheck.StatusCode != 404 {
+		t.Errorf("got link check %+v, want dead with HTTP 404", post.LinkCheck)
+	}
+
+	dead, err := d.Posts.List(&thesrc.PostListOptions{Dead: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != posts[0].ID {
+		t.Errorf("got dead posts %+v, want only post %d", dead, posts[0].ID)
+	}
+
+	// A newer check replaces the previous one.
+	check = &thesrc.LinkCheck{PostID: posts[0].ID, StatusCode: 200, FinalURL: posts[0].LinkURL, CheckedAt: now}
+	if err := d.LinkChecks.Record(check); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ := d.Posts.List(&thesrc.PostListOptions{Dead: true}); len(dead) != 0 {
+		t.Errorf("got dead posts %+v after the link was fixed, want none", dead)
+	}
+}
+
+func TestLinkChecksStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM link_check;`) // test on a clean DB
+	tx.Exec(`DELETE FROM job;`)
+	tx.Exec(`DELETE FROM post;`)
+
+	testLinkChecksStore(t, NewDatastore(tx))
+}
+
+func TestMemoryLinkChecksStore(t *testing.T) {
+	testLinkChecksStore(t, NewMemoryDatastore())
+}
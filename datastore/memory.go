@@ -0,0 +1,387 @@
+package datastore
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// InMemory is whether NewDatastore(nil) returns a datastore that keeps all of
+// its data in memory (shared by all such datastores in the process) instead
+// of in PostgreSQL. It is intended for tests and local development; the data
+// is lost when the process exits.
+var InMemory bool
+
+var (
+	memDatastoreOnce sync.Once
+	memDatastore     *Datastore
+)
+
+// sharedMemoryDatastore returns the process-wide in-memory datastore.
+func sharedMemoryDatastore() *Datastore {
+	memDatastoreOnce.Do(func() { memDatastore = NewMemoryDatastore() })
+	return memDatastore
+}
+
+// NewMemoryDatastore creates a new, empty datastore that keeps all of its data
+// in memory. It is safe for concurrent use.
+func NewMemoryDatastore() *Datastore {
+	m := &memoryStore{
+		posts:    map[int]*thesrc.Post{},
+		votes:    map[int]map[int]struct{}{},
+		comments: map[int]*thesrc.Comment{},
+		users:    map[int]*thesrc.User{},
+		sessions: map[string]*thesrc.Session{},
+		tokens:   map[int]*thesrc.APIToken{},
+		imports:  map[string]*thesrc.ImportState{},
+		jobs:     map[int]*Job{},
+
+		linkChecks: map[int]*thesrc.LinkCheck{},
+	}
+	return &Datastore{
+		Posts:    &memoryPostsStore{m},
+		Comments: &memoryCommentsStore{m},
+		Users:    &memoryUsersStore{m},
+		Tokens:   &memoryTokensStore{m},
+		Imports:  &memoryImportsStore{m},
+		Jobs:     &memoryJobsStore{m},
+
+		LinkChecks:      &memoryLinkChecksStore{m},
+		Classifications: &memoryClassificationsStore{m},
+	}
+}
+
+// memoryStore holds the data of an in-memory datastore. All access to its
+// fields must be guarded by mu. Values are copied into and out of the store so
+// that callers can't modify stored data without holding mu.
+type memoryStore struct {
+	mu sync.Mutex
+
+	posts      map[int]*thesrc.Post
+	lastPostID int
+	votes      map[int]map[int]struct{} // post ID -> set of voter user IDs
+
+	comments      map[int]*thesrc.Comment
+	lastCommentID int
+
+	users      map[int]*thesrc.User
+	lastUserID int
+	sessions   map[string]*thesrc.Session // token hash -> session
+
+	tokens      map[int]*thesrc.APIToken
+	lastTokenID int
+
+	imports map[string]*thesrc.ImportState // site -> import state
+
+	jobs      map[int]*Job
+	lastJobID int
+
+	linkChecks map[int]*thesrc.LinkCheck // post ID -> last check
+}
+
+// paginate returns the indexes of the slice (of length n) that make up the
+// page of results specified by opt.
+func paginate(n int, opt thesrc.ListOptions) (start, end int) {
+	start = opt.Offset()
+	if start > n {
+		start = n
+	}
+	end = start + opt.PerPageOrDefault()
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+type memoryCommentsStore struct{ *memoryStore }
+
+func (s *memoryCommentsStore) Get(postID, id int) (*thesrc.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, present := s.comments[id]
+	if !present || c.PostID != postID {
+		return nil, thesrc.ErrCommentNotFound
+	}
+	return s.commentWithAuthor(c), nil
+}
+
+func (s *memoryCommentsStore) List(postID int) ([]*thesrc.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []*thesrc.Comment
+	for _, c := range s.comments {
+		if c.PostID == postID {
+			comments = append(comments, s.commentWithAuthor(c))
+		}
+	}
+	sort.Sort(commentsByTime(comments))
+	return comments, nil
+}
+
+// commentWithAuthor returns a copy of c with its AuthorLogin set. The caller
+// must hold s.mu.
+func (s *memoryCommentsStore) commentWithAuthor(c *thesrc.Comment) *thesrc.Comment {
+	c2 := *c
+	c2.AuthorLogin = ""
+	if user, present := s.users[c.AuthorUserID]; present {
+		c2.AuthorLogin = user.Login
+	}
+	return &c2
+}
+
+type commentsByTime []*thesrc.Comment
+
+func (v commentsByTime) Len() int { return len(v) }
+func (v commentsByTime) Less(i, j int) bool {
+	if !v[i].SubmittedAt.Equal(v[j].SubmittedAt) {
+		return v[i].SubmittedAt.Before(v[j].SubmittedAt)
+	}
+	return v[i].ID < v[j].ID
+}
+func (v commentsByTime) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+
+func (s *memoryCommentsStore) Submit(comment *thesrc.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, present := s.posts[comment.PostID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	if comment.ParentID != 0 {
+		if parent, present := s.comments[comment.ParentID]; !present || parent.PostID != comment.PostID {
+			return thesrc.ErrCommentNotFound
+		}
+	}
+
+	if comment.SubmittedAt.IsZero() {
+		comment.SubmittedAt = time.Now()
+	}
+	s.lastCommentID++
+	comment.ID = s.lastCommentID
+	c := *comment
+	c.AuthorLogin = ""
+	s.comments[c.ID] = &c
+	post.CommentCount++
+	return nil
+}
+
+func (s *memoryCommentsStore) Delete(postID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, present := s.comments[id]; !present || c.PostID != postID {
+		return thesrc.ErrCommentNotFound
+	}
+
+	// Delete the comment and all of its replies (recursively).
+	thread := map[int]struct{}{id: struct{}{}}
+	for grew := true; grew; {
+		grew = false
+		for _, c := range s.comments {
+			if _, inThread := thread[c.ID]; inThread {
+				continue
+			}
+			if _, parentInThread := thread[c.ParentID]; parentInThread && c.ParentID != 0 {
+				thread[c.ID] = struct{}{}
+				grew = true
+			}
+		}
+	}
+	for id := range thread {
+		delete(s.comments, id)
+	}
+	if post, present := s.posts[postID]; present {
+		post.CommentCount -= len(thread)
+	}
+	return nil
+}
+
+type memoryUsersStore struct{ *memoryStore }
+
+// userByLogin returns the user with the given login. The caller must hold
+// s.mu.
+func (s *memoryUsersStore) userByLogin(login string) *thesrc.User {
+	for _, user := range s.users {
+		if user.Login == login {
+			return user
+		}
+	}
+	return nil
+}
+
+func (s *memoryUsersStore) Get(login string) (*thesrc.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := s.userByLogin(login)
+	if user == nil {
+		return nil, thesrc.ErrUserNotFound
+	}
+	user2 := *user
+	return &user2, nil
+}
+
+func (s *memoryUsersStore) Signup(user *thesrc.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userByLogin(user.Login) != nil {
+		return thesrc.ErrLoginTaken
+	}
+	user.PasswordHash = hash
+	if user.RegisteredAt.IsZero() {
+		user.RegisteredAt = time.Now()
+	}
+	s.lastUserID++
+	user.ID = s.lastUserID
+	user2 := *user
+	s.users[user.ID] = &user2
+	return nil
+}
+
+func (s *memoryUsersStore) Login(cred *thesrc.Credentials) (*thesrc.Session, error) {
+	s.mu.Lock()
+	user := s.userByLogin(cred.Login)
+	s.mu.Unlock()
+	if user == nil {
+		return nil, thesrc.ErrBadCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(cred.Password)); err != nil {
+		return nil, thesrc.ErrBadCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	sess := &thesrc.Session{Token: token, TokenHash: hashToken(token), UserID: user.ID, CreatedAt: time.Now().UTC()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess2 := *sess
+	sess2.Token = ""
+	s.sessions[sess.TokenHash] = &sess2
+	for hash, sess := range s.sessions {
+		if sessionExpired(sess) {
+			delete(s.sessions, hash)
+		}
+	}
+	return sess, nil
+}
+
+// sessionExpired returns whether sess is older than SessionMaxAge.
+func sessionExpired(sess *thesrc.Session) bool {
+	return time.Since(sess.CreatedAt) >= SessionMaxAge
+}
+
+func (s *memoryUsersStore) Logout(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, hashToken(token))
+	return nil
+}
+
+func (s *memoryUsersStore) Authenticate(token string) (*thesrc.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID := 0
+	hash := hashToken(token)
+	if sess, present := s.sessions[hash]; present && !sessionExpired(sess) {
+		userID = sess.UserID
+	} else {
+		// Not a session token, so try it as an API token.
+		for _, tok := range s.tokens {
+			if tok.TokenHash == hash {
+				userID = tok.UserID
+				break
+			}
+		}
+	}
+
+	user, present := s.users[userID]
+	if !present {
+		return nil, thesrc.ErrBadToken
+	}
+	user2 := *user
+	return &user2, nil
+}
+
+type memoryTokensStore struct{ *memoryStore }
+
+func (s *memoryTokensStore) Get(id int) (*thesrc.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok, present := s.tokens[id]
+	if !present {
+		return nil, thesrc.ErrAPITokenNotFound
+	}
+	tok2 := *tok
+	return &tok2, nil
+}
+
+func (s *memoryTokensStore) List(opt *thesrc.APITokenListOptions) ([]*thesrc.APIToken, error) {
+	if opt == nil {
+		opt = &thesrc.APITokenListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []*thesrc.APIToken
+	for _, tok := range s.tokens {
+		if tok.UserID == opt.UserID {
+			tok2 := *tok
+			tokens = append(tokens, &tok2)
+		}
+	}
+	sort.Sort(tokensByNewest(tokens))
+	start, end := paginate(len(tokens), opt.ListOptions)
+	return tokens[start:end], nil
+}
+
+type tokensByNewest []*thesrc.APIToken
+
+func (v tokensByNewest) Len() int { return len(v) }
+func (v tokensByNewest) Less(i, j int) bool {
+	if !v[i].CreatedAt.Equal(v[j].CreatedAt) {
+		return v[i].CreatedAt.After(v[j].CreatedAt)
+	}
+	return v[i].ID > v[j].ID
+}
+func (v tokensByNewest) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+
+func (s *memoryTokensStore) Create(token *thesrc.APIToken) error {
+	secret, err := newToken()
+	if err != nil {
+		return err
+	}
+	token.Token = secret
+	token.TokenHash = hashToken(secret)
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastTokenID++
+	token.ID = s.lastTokenID
+	tok := *token
+	tok.Token = "" // only the hash is stored
+	s.tokens[tok.ID] = &tok
+	return nil
+}
+
+func (s *memoryTokensStore) Revoke(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, present := s.tokens[id]; !present {
+		return thesrc.ErrAPITokenNotFound
+	}
+	delete(s.tokens, id)
+	return nil
+}
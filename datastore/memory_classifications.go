@@ -0,0 +1,28 @@
+package datastore
+
+import "sourcegraph.com/sourcegraph/thesrc"
+
+// memoryClassificationsStore is an in-memory implementation of
+// ClassificationsStore that behaves like classificationsStore.
+type memoryClassificationsStore struct{ *memoryStore }
+
+func (s *memoryClassificationsStore) Record(post *thesrc.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, present := s.posts[post.ID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+
+	if !stored.ClassificationConfirmed {
+		stored.Classification = post.Classification
+		stored.ClassificationScore = post.ClassificationScore
+		stored.Languages = append([]string(nil), post.Languages...)
+	}
+	if post.Metadata != nil {
+		post.Metadata.PostID = post.ID
+		md := *post.Metadata
+		stored.Metadata = &md
+	}
+	return nil
+}
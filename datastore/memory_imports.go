@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"sort"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// memoryImportsStore is an in-memory implementation of thesrc.ImportsService
+// that behaves like importsStore.
+type memoryImportsStore struct{ *memoryStore }
+
+func (s *memoryImportsStore) List(opt *thesrc.ImportStateListOptions) ([]*thesrc.ImportState, error) {
+	if opt == nil {
+		opt = &thesrc.ImportStateListOptions{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var states []*thesrc.ImportState
+	for site, state := range s.imports {
+		if opt.Site == "" || opt.Site == site {
+			states = append(states, copyImportState(state))
+		}
+	}
+	sort.Sort(importStatesBySite(states))
+	return states, nil
+}
+
+func (s *memoryImportsStore) Update(state *thesrc.ImportState) error {
+	state.LastRunAt = state.LastRunAt.UTC()
+	state.LastSuccessAt = state.LastSuccessAt.UTC()
+	state.LastFailureAt = state.LastFailureAt.UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.imports[state.Site] = copyImportState(state)
+	return nil
+}
+
+// copyImportState returns a copy of state that shares no memory with it.
+func copyImportState(state *thesrc.ImportState) *thesrc.ImportState {
+	state2 := *state
+	if state.SeenIDs != nil {
+		state2.SeenIDs = append([]string(nil), state.SeenIDs...)
+	}
+	return &state2
+}
+
+type importStatesBySite []*thesrc.ImportState
+
+func (v importStatesBySite) Len() int           { return len(v) }
+func (v importStatesBySite) Less(i, j int) bool { return v[i].Site < v[j].Site }
+func (v importStatesBySite) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
@@ -0,0 +1,106 @@
+package datastore
+
+import (
+	"sort"
+	"time"
+)
+
+// memoryJobsStore is an in-memory implementation of JobsStore that behaves
+// like jobsStore.
+type memoryJobsStore struct{ *memoryStore }
+
+func (s *memoryJobsStore) Enqueue(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueueJob(job)
+	return nil
+}
+
+// enqueueJob adds job to the queue. The caller must hold s.mu.
+func (s *memoryStore) enqueueJob(job *Job) {
+	job.CreatedAt = time.Now().UTC()
+	if job.RunAt.IsZero() {
+		job.RunAt = job.CreatedAt
+	}
+	s.lastJobID++
+	job.ID = s.lastJobID
+	job2 := *job
+	s.jobs[job.ID] = &job2
+}
+
+// sortedJobs returns the jobs in the order they will be claimed (like
+// jobsStore.List). The caller must hold s.mu.
+func (s *memoryJobsStore) sortedJobs() []*Job {
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Sort(jobsByRunAt(jobs))
+	return jobs
+}
+
+type jobsByRunAt []*Job
+
+func (v jobsByRunAt) Len() int { return len(v) }
+func (v jobsByRunAt) Less(i, j int) bool {
+	if v[i].Failed != v[j].Failed {
+		return !v[i].Failed
+	}
+	if !v[i].RunAt.Equal(v[j].RunAt) {
+		return v[i].RunAt.Before(v[j].RunAt)
+	}
+	return v[i].ID < v[j].ID
+}
+func (v jobsByRunAt) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+
+func (s *memoryJobsStore) Claim(lockFor time.Duration) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	for _, job := range s.sortedJobs() {
+		if job.Failed || job.RunAt.After(now) || job.LockedUntil.After(now) {
+			continue
+		}
+		job.Attempts++
+		job.LockedUntil = now.Add(lockFor)
+		job2 := *job
+		return &job2, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryJobsStore) Complete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *memoryJobsStore) Retry(id int, err error, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, present := s.jobs[id]; present {
+		job.RunAt, job.LockedUntil, job.LastError = runAt.UTC(), time.Time{}, err.Error()
+	}
+	return nil
+}
+
+func (s *memoryJobsStore) Fail(id int, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, present := s.jobs[id]; present {
+		job.Failed, job.LockedUntil, job.LastError = true, time.Time{}, err.Error()
+	}
+	return nil
+}
+
+func (s *memoryJobsStore) List() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := s.sortedJobs()
+	for i, job := range jobs {
+		job2 := *job
+		jobs[i] = &job2
+	}
+	return jobs, nil
+}
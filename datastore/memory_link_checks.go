@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"sort"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// memoryLinkChecksStore is an in-memory implementation of LinkChecksStore
+// that behaves like linkChecksStore.
+type memoryLinkChecksStore struct{ *memoryStore }
+
+func (s *memoryLinkChecksStore) Record(check *thesrc.LinkCheck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	check.CheckedAt = check.CheckedAt.UTC()
+	check2 := *check
+	s.linkChecks[check.PostID] = &check2
+	return nil
+}
+
+func (s *memoryLinkChecksStore) ListDue(checkedBefore time.Time, limit int) ([]*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := map[int]bool{}
+	for _, job := range s.jobs {
+		if job.Kind == JobCheckLink && !job.Failed {
+			pending[job.PostID] = true
+		}
+	}
+
+	var posts []*thesrc.Post
+	for _, post := range s.posts {
+		if post.LinkURL == "" || pending[post.ID] {
+			continue
+		}
+		check := s.linkCheck(post.ID)
+		if check != nil && !check.CheckedAt.Before(checkedBefore) {
+			continue
+		}
+		post = copyPost(post)
+		post.Metadata = nil
+		post.LinkCheck = check
+		posts = append(posts, post)
+	}
+	sort.Sort(postsByID(posts))
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// linkCheck returns a copy of the result of the last check of the link of the
+// post with the given ID, or nil if it hasn't been checked. The caller must
+// hold s.mu.
+func (s *memoryStore) linkCheck(postID int) *thesrc.LinkCheck {
+	check, present := s.linkChecks[postID]
+	if !present {
+		return nil
+	}
+	check2 := *check
+	return &check2
+}
+
+type postsByID []*thesrc.Post
+
+func (v postsByID) Len() int           { return len(v) }
+func (v postsByID) Less(i, j int) bool { return v[i].ID < v[j].ID }
+func (v postsByID) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
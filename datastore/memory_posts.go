@@ -0,0 +1,364 @@
+package datastore
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// memoryPostsStore is an in-memory implementation of thesrc.PostsService that
+// behaves like postsStore.
+type memoryPostsStore struct{ *memoryStore }
+
+// copyPost returns a copy of post that shares no memory with it.
+func copyPost(post *thesrc.Post) *thesrc.Post {
+	post2 := *post
+	if post.Tags != nil {
+		post2.Tags = append([]string(nil), post.Tags...)
+	}
+	if post.Languages != nil {
+		post2.Languages = append([]string(nil), post.Languages...)
+	}
+	if post.Metadata != nil {
+		md := *post.Metadata
+		post2.Metadata = &md
+	}
+	post2.LinkCheck = nil // stored separately, in linkChecks
+	return &post2
+}
+
+func (s *memoryPostsStore) Get(id int) (*thesrc.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, present := s.posts[id]
+	if !present {
+		return nil, thesrc.ErrPostNotFound
+	}
+	post = copyPost(post)
+	post.LinkCheck = s.linkCheck(id)
+	return post, nil
+}
+
+func (s *memoryPostsStore) List(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
+	if opt == nil {
+		opt = &thesrc.PostListOptions{}
+	}
+	if !thesrc.ValidPostSort(opt.Sort) {
+		return nil, fmt.Errorf("unknown sort order %q", opt.Sort)
+	}
+
+	var q *memoryQuery
+	if opt.Query != "" {
+		q = parseMemoryQuery(opt.Query)
+	}
+
+	s.mu.Lock()
+	var posts []*thesrc.Post
+	ranks := map[int]float64{}
+	for _, post := range s.posts {
+		if opt.CodeOnly && post.Classification != thesrc.ClassificationCode {
+			continue
+		}
+		if opt.AuthorUserID != 0 && post.AuthorUserID != opt.AuthorUserID {
+			continue
+		}
+		if opt.Tag != "" && !containsString(post.Tags, opt.Tag) {
+			continue
+		}
+		if opt.Language != "" && !containsString(post.Languages, opt.Language) {
+			continue
+		}
+		if opt.ClassificationConfirmed && !post.ClassificationConfirmed {
+			continue
+		}
+		if opt.Dead {
+			if check, present := s.linkChecks[post.ID]; !present || !check.Dead {
+				continue
+			}
+		}
+		if q != nil {
+			rank, match := q.rank(post)
+			if !match {
+				continue
+			}
+			ranks[post.ID] = rank
+		}
+		post = copyPost(post)
+		post.Metadata = nil // like postsStore.List
+		posts = append(posts, post)
+	}
+	s.mu.Unlock()
+
+	// Order like postsStore.List, breaking the remaining ties by ID so that
+	// the order (and therefore pagination) is deterministic.
+	now := time.Now()
+	var key func(*thesrc.Post) float64
+	switch opt.Sort {
+	case "":
+		if q != nil {
+			key = func(p *thesrc.Post) float64 { return ranks[p.ID] }
+		}
+	case thesrc.PostSortTop:
+		key = func(p *thesrc.Post) float64 { return float64(p.Score) }
+	case thesrc.PostSortHot:
+		key = func(p *thesrc.Post) float64 { return hotRank(p, now) }
+	}
+	sort.Sort(postsByKey{posts, key})
+
+	start, end := paginate(len(posts), opt.ListOptions)
+	return posts[start:end], nil
+}
+
+// hotRank is the Go equivalent of hotRankSQL.
+func hotRank(post *thesrc.Post, now time.Time) float64 {
+	ageHours := now.Sub(post.SubmittedAt).Hours()
+	return float64(post.Score+1) / math.Pow(ageHours+2, 1.8)
+}
+
+// postsByKey sorts posts by descending key (if key is non-nil), then by
+// descending SubmittedAt, then by descending ID.
+type postsByKey struct {
+	posts []*thesrc.Post
+	key   func(*thesrc.Post) float64
+}
+
+func (v postsByKey) Len() int { return len(v.posts) }
+func (v postsByKey) Less(i, j int) bool {
+	a, b := v.posts[i], v.posts[j]
+	if v.key != nil {
+		if ka, kb := v.key(a), v.key(b); ka != kb {
+			return ka > kb
+		}
+	}
+	if !a.SubmittedAt.Equal(b.SubmittedAt) {
+		return a.SubmittedAt.After(b.SubmittedAt)
+	}
+	return a.ID > b.ID
+}
+func (v postsByKey) Swap(i, j int) { v.posts[i], v.posts[j] = v.posts[j], v.posts[i] }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// A memoryQuery is a full-text search query, approximating PostgreSQL's
+// websearch_to_tsquery: all of the terms must match (except for terms
+// prefixed with "-", which must not), and terms match words that they are a
+// prefix of (a crude form of stemming).
+type memoryQuery struct {
+	include, exclude []string
+}
+
+func parseMemoryQuery(query string) *memoryQuery {
+	q := &memoryQuery{}
+	for _, f := range strings.Fields(strings.ToLower(query)) {
+		exclude := strings.HasPrefix(f, "-")
+		for _, term := range searchWords(f) {
+			if exclude {
+				q.exclude = append(q.exclude, term)
+			} else {
+				q.include = append(q.include, term)
+			}
+		}
+	}
+	return q
+}
+
+var domainPattern = regexp.MustCompile(`^[A-Za-z]+://([^/:?#]+)`)
+
+// rank returns whether post matches q and, if so, its rank. Like ts_rank
+// with the weights in postSearchSQL, title matches count the most, then body
+// matches, then link URL domain matches.
+func (q *memoryQuery) rank(post *thesrc.Post) (rank float64, match bool) {
+	domain := ""
+	if m := domainPattern.FindStringSubmatch(post.LinkURL); m != nil {
+		domain = strings.ToLower(m[1])
+	}
+	fields := []struct {
+		words  []string
+		weight float64
+	}{
+		{searchWords(post.Title), 1.0},
+		{searchWords(post.Body), 0.4},
+		{[]string{domain}, 0.2},
+	}
+
+	for _, term := range q.exclude {
+		for _, f := range fields {
+			if hasPrefixWord(f.words, term) {
+				return 0, false
+			}
+		}
+	}
+	for _, term := range q.include {
+		matched := false
+		for _, f := range fields {
+			if hasPrefixWord(f.words, term) {
+				rank += f.weight
+				matched = true
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	return rank, len(q.include) > 0
+}
+
+// searchWords splits s into lowercase words. Dots are kept inside words so
+// that domain names (like "github.com") are single words.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+}
+
+func hasPrefixWord(words []string, prefix string) bool {
+	prefix = strings.Trim(prefix, ".")
+	if prefix == "" {
+		return false
+	}
+	for _, w := range words {
+		if strings.HasPrefix(strings.Trim(w, "."), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryPostsStore) Submit(post *thesrc.Post) (bool, error) {
+	post.CanonicalURL = thesrc.CanonicalizeURL(post.LinkURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.posts {
+		if existing.CanonicalURL == post.CanonicalURL {
+			*post = *copyPost(existing)
+			return false, nil
+		}
+	}
+
+	s.lastPostID++
+	post.ID = s.lastPostID
+	if post.Metadata != nil {
+		post.Metadata.PostID = post.ID
+	}
+	s.posts[post.ID] = copyPost(post)
+	if needsClassification(post) {
+		s.enqueueJob(&Job{Kind: JobClassifyPost, PostID: post.ID})
+	}
+	return true, nil
+}
+
+func (s *memoryPostsStore) Update(post *thesrc.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orig, present := s.posts[post.ID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+	post.CanonicalURL = updatedCanonicalURL(orig, post)
+	for _, other := range s.posts {
+		if other.ID != post.ID && post.CanonicalURL != "" && other.CanonicalURL == post.CanonicalURL {
+			return fmt.Errorf("another post (%d) has the canonical URL %q", other.ID, post.CanonicalURL)
+		}
+	}
+	if post.Metadata != nil {
+		post.Metadata.PostID = post.ID
+	}
+	post2 := copyPost(post)
+	if post2.Metadata == nil {
+		post2.Metadata = orig.Metadata
+	}
+	s.posts[post.ID] = post2
+	return nil
+}
+
+func (s *memoryPostsStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, present := s.posts[id]; !present {
+		return thesrc.ErrPostNotFound
+	}
+	delete(s.posts, id)
+	delete(s.votes, id)
+	delete(s.linkChecks, id)
+	for cid, c := range s.comments {
+		if c.PostID == id {
+			delete(s.comments, cid)
+		}
+	}
+	for jid, job := range s.jobs {
+		if job.PostID == id {
+			delete(s.jobs, jid)
+		}
+	}
+	return nil
+}
+
+func (s *memoryPostsStore) ListTags(opt *thesrc.ListOptions) ([]*thesrc.TagCount, error) {
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+
+	s.mu.Lock()
+	counts := map[string]int{}
+	for _, post := range s.posts {
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+	s.mu.Unlock()
+
+	tags := make([]*thesrc.TagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, &thesrc.TagCount{Tag: tag, Count: n})
+	}
+	sort.Sort(tagCountsByCount(tags))
+	start, end := paginate(len(tags), *opt)
+	return tags[start:end], nil
+}
+
+type tagCountsByCount []*thesrc.TagCount
+
+func (v tagCountsByCount) Len() int { return len(v) }
+func (v tagCountsByCount) Less(i, j int) bool {
+	if v[i].Count != v[j].Count {
+		return v[i].Count > v[j].Count
+	}
+	return v[i].Tag < v[j].Tag
+}
+func (v tagCountsByCount) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+
+func (s *memoryPostsStore) Vote(vote *thesrc.Vote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post, present := s.posts[vote.PostID]
+	if !present {
+		return thesrc.ErrPostNotFound
+	}
+
+	voters := s.votes[vote.PostID]
+	if voters == nil {
+		voters = map[int]struct{}{}
+		s.votes[vote.PostID] = voters
+	}
+	if vote.Unvote {
+		delete(voters, vote.UserID)
+	} else {
+		voters[vote.UserID] = struct{}{}
+	}
+	post.Score = len(voters)
+	return nil
+}
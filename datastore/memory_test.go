@@ -0,0 +1,295 @@
+package datastore
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func postIDs(posts []*thesrc.Post) []int {
+	var ids []int
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestMemoryPostsStore_Submit(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	post := &thesrc.Post{LinkURL: "http://example.com", Title: "t", Tags: []string{"go"}}
+	created, err := d.Posts.Submit(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || post.ID == 0 {
+		t.Errorf("got created=%v, ID=%d, want a newly created post", created, post.ID)
+	}
+
+	// Modifying the submitted post must not modify the stored post.
+	post.Tags[0] = "x"
+
+	dup := &thesrc.Post{LinkURL: "http://example.com", Title: "other"}
+	created, err = d.Posts.Submit(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("got created == true for duplicate link URL")
+	}
+	if want := (&thesrc.Post{ID: post.ID, LinkURL: "http://example.com", CanonicalURL: "https://example.com", Title: "t", Tags: []string{"go"}}); !reflect.DeepEqual(dup, want) {
+		t.Errorf("got existing post %+v, want %+v", dup, want)
+	}
+}
+
+func TestMemoryPostsStore_List(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	now := time.Now()
+	for i, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/1", SubmittedAt: now.Add(-72 * time.Hour), Score: 10, Classification: "CODE"},
+		{LinkURL: "http://example.com/2", SubmittedAt: now.Add(-1 * time.Hour), Score: 2, Classification: "NONCODE"},
+		{LinkURL: "http://example.com/3", SubmittedAt: now, Classification: "CODE", AuthorUserID: 7, Tags: []string{"go"}},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != i+1 {
+			t.Fatalf("got post ID %d, want %d", p.ID, i+1)
+		}
+	}
+
+	tests := []struct {
+		opt  thesrc.PostListOptions
+		want []int
+	}{
+		{thesrc.PostListOptions{}, []int{3, 2, 1}},
+		{thesrc.PostListOptions{Sort: thesrc.PostSortTop}, []int{1, 2, 3}},
+		{thesrc.PostListOptions{Sort: thesrc.PostSortHot}, []int{2, 3, 1}},
+		{thesrc.PostListOptions{CodeOnly: true}, []int{3, 1}},
+		{thesrc.PostListOptions{AuthorUserID: 7}, []int{3}},
+		{thesrc.PostListOptions{Tag: "go"}, []int{3}},
+		{thesrc.PostListOptions{ListOptions: thesrc.ListOptions{PerPage: 2, Page: 2}}, []int{1}},
+		{thesrc.PostListOptions{ListOptions: thesrc.ListOptions{PerPage: 2, Page: 3}}, nil},
+	}
+	for _, test := range tests {
+		posts, err := d.Posts.List(&test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := postIDs(posts); !reflect.DeepEqual(ids, test.want) && !(len(ids) == 0 && len(test.want) == 0) {
+			t.Errorf("%+v: got post IDs %v, want %v", test.opt, ids, test.want)
+		}
+	}
+
+	if _, err := d.Posts.List(&thesrc.PostListOptions{Sort: "x"}); err == nil {
+		t.Error("got nil error for unknown sort order")
+	}
+}
+
+func TestMemoryPostsStore_List_query(t *testing.T) {
+	d := NewMemoryDatastore()
+	for _, p := range []*thesrc.Post{
+		{LinkURL: "http://example.com/1", Title: "A fast JSON parser", Body: "Decoding quickly"},
+		{LinkURL: "http://example.com/2", Title: "Compilers", Body: "Writing a parser for JSON by hand"},
+		{LinkURL: "https://github.com/a/b", Title: "Some repository"},
+		{LinkURL: "http://example.com/4", Title: "Unrelated"},
+	} {
+		if _, err := d.Posts.Submit(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]int{
+		"json parser": {1, 2}, // title matches rank above body matches
+		"github.com":  {3},
+		"json -hand":  {1},
+		"nonexistent": nil,
+	}
+	for query, want := range tests {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{Query: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := postIDs(posts); !reflect.DeepEqual(ids, want) {
+			t.Errorf("query %q: got post IDs %v, want %v", query, ids, want)
+		}
+	}
+}
+
+func TestMemoryPostsStore_UpdateDeleteVote(t *testing.T) {
+	d := NewMemoryDatastore()
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	post.Title = "t2"
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.Posts.Get(post.ID); got.Title != "t2" {
+		t.Errorf("got title %q after update, want %q", got.Title, "t2")
+	}
+	if err := d.Posts.Update(&thesrc.Post{ID: 123}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+
+	for _, vote := range []*thesrc.Vote{{PostID: post.ID, UserID: 1}, {PostID: post.ID, UserID: 2}, {PostID: post.ID, UserID: 2}, {PostID: post.ID, UserID: 1, Unvote: true}} {
+		if err := d.Posts.Vote(vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := d.Posts.Get(post.ID); got.Score != 1 {
+		t.Errorf("got score %d, want 1", got.Score)
+	}
+
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Delete(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestMemoryPostsStore_concurrent(t *testing.T) {
+	d := NewMemoryDatastore()
+
+	// Concurrently submit the same 10 links 10 times each. Exactly 10 posts
+	// should be created.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := d.Posts.Submit(&thesrc.Post{LinkURL: fmt.Sprintf("http://example.com/%d", i%10)})
+			if err != nil {
+				t.Error(err)
+			}
+			if c {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+			d.Posts.List(nil)
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 10 {
+		t.Errorf("got %d posts created, want 10", created)
+	}
+}
+
+func TestMemoryCommentsStore(t *testing.T) {
+	d := NewMemoryDatastore()
+	user := &thesrc.User{Login: "alice"}
+	if err := d.Users.Signup(user, "password"); err != nil {
+		t.Fatal(err)
+	}
+	post := &thesrc.Post{}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	top := &thesrc.Comment{PostID: post.ID, AuthorUserID: user.ID, Body: "a"}
+	if err := d.Comments.Submit(top); err != nil {
+		t.Fatal(err)
+	}
+	reply := &thesrc.Comment{PostID: post.ID, ParentID: top.ID, Body: "b"}
+	if err := d.Comments.Submit(reply); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Comments.Submit(&thesrc.Comment{PostID: post.ID, ParentID: 123}); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrCommentNotFound)
+	}
+
+	comments, err := d.Comments.List(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].AuthorLogin != "alice" || comments[1].ParentID != top.ID {
+		t.Errorf("got comments %+v", comments)
+	}
+	if p, _ := d.Posts.Get(post.ID); p.CommentCount != 2 {
+		t.Errorf("got comment count %d, want 2", p.CommentCount)
+	}
+
+	if err := d.Comments.Delete(post.ID, top.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Comments.Get(post.ID, reply.ID); err != thesrc.ErrCommentNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrCommentNotFound)
+	}
+	if p, _ := d.Posts.Get(post.ID); p.CommentCount != 0 {
+		t.Errorf("got comment count %d, want 0", p.CommentCount)
+	}
+}
+
+func TestMemoryUsersStore(t *testing.T) {
+	d := NewMemoryDatastore()
+	user := &thesrc.User{Login: "alice"}
+	if err := d.Users.Signup(user, "password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Users.Signup(&thesrc.User{Login: "alice"}, "password"); err != thesrc.ErrLoginTaken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrLoginTaken)
+	}
+
+	if _, err := d.Users.Login(&thesrc.Credentials{Login: "alice", Password: "wrong"}); err != thesrc.ErrBadCredentials {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadCredentials)
+	}
+	sess, err := d.Users.Login(&thesrc.Credentials{Login: "alice", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := d.Users.Authenticate(sess.Token); err != nil || u.ID != user.ID {
+		t.Errorf("got user %+v (error %v), want user %d", u, err, user.ID)
+	}
+
+	tok := &thesrc.APIToken{UserID: user.ID, Name: "n"}
+	if err := d.Tokens.Create(tok); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := d.Users.Authenticate(tok.Token); err != nil || u.ID != user.ID {
+		t.Errorf("got user %+v (error %v) for API token, want user %d", u, err, user.ID)
+	}
+	if err := d.Tokens.Revoke(tok.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Users.Authenticate(tok.Token); err != thesrc.ErrBadToken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadToken)
+	}
+
+	if err := d.Users.Logout(sess.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Users.Authenticate(sess.Token); err != thesrc.ErrBadToken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadToken)
+	}
+}
+
+func TestMemoryUsersStore_sessionExpiry(t *testing.T) {
+	testSessionExpiry(t, NewMemoryDatastore())
+}
+
+func TestNewDatastore_inMemory(t *testing.T) {
+	InMemory = true
+	defer func() { InMemory = false }()
+
+	if d1, d2 := NewDatastore(nil), NewDatastore(nil); d1 != d2 {
+		t.Error("got different in-memory datastores, want the shared one")
+	}
+	if _, ok := NewDatastore(nil).Posts.(*memoryPostsStore); !ok {
+		t.Error("got non-memory posts store")
+	}
+}
@@ -0,0 +1,212 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/modl"
+)
+
+// A migration is a numbered, reversible step that changes the database
+// schema. Applied migrations are recorded in the schema_migrations table.
+type migration struct {
+	// version orders the migrations. It must be unique, and new migrations
+	// must have higher versions than all existing ones.
+	version int
+
+	// name briefly describes the migration.
+	name string
+
+	// up and down are the statements that apply and revert the migration, in
+	// all dialects. They may contain the type placeholders in postgresTypes
+	// and sqliteTypes.
+	up, down []string
+
+	// postgresUp and postgresDown are statements run in PostgreSQL only, after
+	// up and before down, respectively.
+	postgresUp, postgresDown []string
+}
+
+func init() {
+	for i, m := range migrations {
+		if i > 0 && m.version <= migrations[i-1].version {
+			panic(fmt.Sprintf("migration %d (%s) is out of order", m.version, m.name))
+		}
+	}
+}
+
+var (
+	postgresTypes = strings.NewReplacer(
+		"{{serial}}", "serial PRIMARY KEY",
+		"{{timestamp}}", "timestamp with time zone",
+		"{{bytes}}", "bytea",
+	)
+	sqliteTypes = strings.NewReplacer(
+		"{{serial}}", "integer PRIMARY KEY AUTOINCREMENT",
+		"{{timestamp}}", "datetime",
+		"{{bytes}}", "blob",
+	)
+)
+
+// dialectSQL replaces the type placeholders in q with the current dialect's
+// types.
+func dialectSQL(q string) string {
+	if isSQLite() {
+		return sqliteTypes.Replace(q)
+	}
+	return postgresTypes.Replace(q)
+}
+
+// statements returns the statements that apply (if up is true) or revert m
+// in the current dialect.
+func (m *migration) statements(up bool) []string {
+	var qs []string
+	if up {
+		qs = append(qs, m.up...)
+		if !isSQLite() {
+			qs = append(qs, m.postgresUp...)
+		}
+	} else {
+		if !isSQLite() {
+			qs = append(qs, m.postgresDown...)
+		}
+		qs = append(qs, m.down...)
+	}
+	for i, q := range qs {
+		qs[i] = dialectSQL(q)
+	}
+	return qs
+}
+
+// A MigrationStatus describes a schema migration and whether it has been
+// applied to the database.
+type MigrationStatus struct {
+	Version int
+	Name    string
+
+	// AppliedAt is when the migration was applied, or the zero time if it is
+	// pending.
+	AppliedAt time.Time
+}
+
+// Applied returns whether the migration has been applied.
+func (s *MigrationStatus) Applied() bool { return !s.AppliedAt.IsZero() }
+
+// MigrationStatuses lists all migrations in order, including those that have
+// been applied to the database but that are unknown to this version of thesrc.
+func MigrationStatuses() ([]*MigrationStatus, error) {
+	if err := createMigrationsTable(); err != nil {
+		return nil, err
+	}
+	var applied []*MigrationStatus
+	if err := DB.Select(&applied, `SELECT version, name, appliedat FROM schema_migrations ORDER BY version;`); err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*MigrationStatus, len(applied))
+	for _, s := range applied {
+		byVersion[s.Version] = s
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		if s, present := byVersion[m.version]; present {
+			statuses = append(statuses, s)
+			delete(byVersion, m.version)
+		} else {
+			statuses = append(statuses, &MigrationStatus{Version: m.version, Name: m.name})
+		}
+	}
+	for _, s := range applied {
+		if _, unknown := byVersion[s.Version]; unknown {
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies the first n pending migrations (or all of them, if n <=
+// 0) in order. Each migration runs in its own transaction, so a migration that
+// fails leaves the schema as it was after the previous migration.
+func MigrateUp(n int) error {
+	return migrate(migrations, n, true)
+}
+
+// MigrateDown reverts the n most recently applied migrations (or all of them,
+// if n <= 0) in reverse order.
+func MigrateDown(n int) error {
+	reversed := make([]*migration, len(migrations))
+	for i, m := range migrations {
+		reversed[len(migrations)-1-i] = m
+	}
+	return migrate(reversed, n, false)
+}
+
+// migrate applies (if up is true) or reverts the first n migrations in ms
+// that need it (or all of them, if n <= 0).
+func migrate(ms []*migration, n int, up bool) error {
+	if err := createMigrationsTable(); err != nil {
+		return err
+	}
+	done := 0
+	for _, m := range ms {
+		if n > 0 && done == n {
+			break
+		}
+		ran, err := migrateStep(m, up)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %s", m.version, m.name, err)
+		}
+		if ran {
+			done++
+		}
+	}
+	return nil
+}
+
+// migrateStep applies (if up is true) or reverts m, unless it has already
+// been applied (or reverted). It reports whether it changed the schema.
+func migrateStep(m *migration, up bool) (bool, error) {
+	var ran bool
+	err := transact(DB, func(tx modl.SqlExecutor) error {
+		// Keep concurrent migrators (such as several servers starting at
+		// once) from applying the same migration twice. SQLite transactions
+		// already exclude each other (see sqliteDataSource).
+		if !isSQLite() {
+			if _, err := tx.Exec(`LOCK TABLE schema_migrations IN EXCLUSIVE MODE;`); err != nil {
+				return err
+			}
+		}
+
+		var applied []*MigrationStatus
+		if err := tx.Select(&applied, `SELECT version, name, appliedat FROM schema_migrations WHERE version=$1;`, m.version); err != nil {
+			return err
+		}
+		if (len(applied) > 0) == up {
+			return nil
+		}
+
+		for _, q := range m.statements(up) {
+			if _, err := tx.Exec(q); err != nil {
+				return fmt.Errorf("running query %q: %s", q, err)
+			}
+		}
+
+		var err error
+		if up {
+			_, err = tx.Exec(`INSERT INTO schema_migrations(version, name, appliedat) VALUES($1, $2, $3);`, m.version, m.name, time.Now())
+		} else {
+			_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version=$1;`, m.version)
+		}
+		ran = err == nil
+		return err
+	})
+	return ran, err
+}
+
+// createMigrationsTable creates the schema_migrations table if it doesn't
+// exist.
+func createMigrationsTable() error {
+	_, err := DB.Exec(dialectSQL(`CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name text NOT NULL, appliedat {{timestamp}} NOT NULL);`))
+	return err
+}
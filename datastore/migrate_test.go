@@ -0,0 +1,109 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func checkMigrationsApplied(t *testing.T, label string, want bool) {
+	statuses, err := MigrationStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("%s: got %d migration statuses, want %d", label, len(statuses), len(migrations))
+	}
+	for _, s := range statuses {
+		if s.Applied() != want {
+			t.Errorf("%s: migration %d (%s): got applied %v, want %v", label, s.Version, s.Name, s.Applied(), want)
+		}
+	}
+}
+
+func TestMigrate_db(t *testing.T) {
+	checkMigrationsApplied(t, "initially", true)
+
+	// Applying migrations again does nothing.
+	if err := MigrateUp(0); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrationsApplied(t, "after reapplying", true)
+
+	if err := MigrateDown(0); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrationsApplied(t, "after reverting", false)
+	if _, err := DB.Exec(`SELECT * FROM post;`); err == nil {
+		t.Error("post table exists after reverting all migrations")
+	}
+
+	if err := MigrateUp(0); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrationsApplied(t, "after applying", true)
+}
+
+// baselineSchema is the schema of the first databases, which were created
+// (in PostgreSQL) from the table definitions registered with modl before any
+// columns were added to the post table.
+var baselineSchema = []string{
+	`CREATE TABLE post (id serial PRIMARY KEY, title text, linkurl text, body text, submittedat timestamp with time zone, authoruserid integer, score integer, classification text);`,
+	`CREATE INDEX post_submittedat ON post(submittedat DESC);`,
+	`CREATE UNIQUE INDEX post_linkurl ON post(linkurl);`,
+}
+
+func TestMigrate_existingSchema_db(t *testing.T) {
+	if isSQLite() {
+		t.Skip("databases created before schema migrations existed are all PostgreSQL databases")
+	}
+
+	// Simulate a database created before schema migrations existed.
+	if err := MigrateDown(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DB.Exec(`DROP TABLE schema_migrations;`); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range baselineSchema {
+		if _, err := DB.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Posts' scores were their points on the sites that they were imported
+	// from.
+	var id int
+	if err := DB.Dbx.QueryRow(`INSERT INTO post(title, linkurl, body, submittedat, authoruserid, score, classification) VALUES('t', 'http://example.com', '', now(), 0, 500, '') RETURNING id;`).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Exec(`DELETE FROM post WHERE id=$1;`, id)
+	defer DB.Exec(`DELETE FROM vote WHERE postid=$1;`, id)
+
+	if err := MigrateUp(0); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrationsApplied(t, "after applying to existing schema", true)
+
+	// Posts created then are readable with the current schema, and their
+	// points are now their external scores.
+	d := NewDatastore(nil)
+	post, err := d.Posts.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "t" || post.Score != 0 || post.ExternalScore != 500 || post.CommentCount != 0 {
+		t.Errorf("got post %+v, want title t, external score 500, and no votes or comments", post)
+	}
+
+	// Votes don't replace their points.
+	if err := d.Posts.Vote(&thesrc.Vote{PostID: id, UserID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	post, err = d.Posts.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Score != 1 || post.ExternalScore != 500 {
+		t.Errorf("after voting, got score %d and external score %d, want 1 and 500", post.Score, post.ExternalScore)
+	}
+}
@@ -0,0 +1,186 @@
+package datastore
+
+// migrations are the schema migrations, in order. Never change a migration
+// that has been released; add a new one instead.
+//
+// Migration 1 creates the schema as it was before migrations existed, when
+// it was created from the table definitions registered with modl. It only
+// creates tables, columns, and indexes that don't exist, so it is safe to
+// apply to databases created then (even those created with the original
+// schema, before columns were added to the post table, whose scores it moves
+// to the new externalscore column). Such databases are all PostgreSQL
+// databases, because SQLite support was added just before migrations.
+var migrations = []*migration{
+	{
+		version: 1,
+		name:    "create initial schema",
+		up: []string{
+			`CREATE TABLE IF NOT EXISTS post (id {{serial}}, title text, linkurl text, body text, submittedat {{timestamp}}, authoruserid integer, score integer, externalscore integer, commentcount integer, classification text);`,
+			`CREATE INDEX IF NOT EXISTS post_submittedat ON post(submittedat DESC);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS post_linkurl ON post(linkurl);`,
+			`CREATE INDEX IF NOT EXISTS post_authoruserid ON post(authoruserid);`,
+
+			`CREATE TABLE IF NOT EXISTS vote (postid integer, userid integer, PRIMARY KEY (postid, userid));`,
+
+			`CREATE TABLE IF NOT EXISTS post_tags (postid integer, tag text, PRIMARY KEY (postid, tag));`,
+			`CREATE INDEX IF NOT EXISTS post_tags_tag ON post_tags(tag);`,
+
+			`CREATE TABLE IF NOT EXISTS comment (id {{serial}}, postid integer, parentid integer, authoruserid integer, body text, submittedat {{timestamp}});`,
+			`CREATE INDEX IF NOT EXISTS comment_postid ON comment(postid, submittedat);`,
+			`CREATE INDEX IF NOT EXISTS comment_parentid ON comment(parentid);`,
+
+			`CREATE TABLE IF NOT EXISTS users (id {{serial}}, login text, registeredat {{timestamp}}, admin boolean, passwordhash {{bytes}});`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS users_login ON users(login);`,
+
+			`CREATE TABLE IF NOT EXISTS session (token text PRIMARY KEY, userid integer, createdat {{timestamp}});`,
+			`CREATE INDEX IF NOT EXISTS session_userid ON session(userid);`,
+
+			`CREATE TABLE IF NOT EXISTS api_token (id {{serial}}, userid integer, name text, tokenhash text, createdat {{timestamp}});`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS api_token_tokenhash ON api_token(tokenhash);`,
+			`CREATE INDEX IF NOT EXISTS api_token_userid ON api_token(userid);`,
+		},
+		postgresUp: []string{
+			// In databases created with the original schema, posts' scores
+			// are their points on the sites that they were imported from,
+			// so move them to externalscore (and count the votes, of which
+			// there are none yet, as their scores).
+			`DO $$ BEGIN
+				IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema=current_schema() AND table_name='post' AND column_name='externalscore') THEN
+					ALTER TABLE post ADD COLUMN externalscore integer DEFAULT 0;
+					UPDATE post SET externalscore=coalesce(score, 0), score=(SELECT count(*) FROM vote WHERE vote.postid=post.id);
+				END IF;
+			END $$;`,
+			`ALTER TABLE post ADD COLUMN IF NOT EXISTS commentcount integer DEFAULT 0;`,
+			`ALTER TABLE post ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (` + postSearchSQL + `) STORED;`,
+			`CREATE INDEX IF NOT EXISTS post_search ON post USING GIN(search);`,
+		},
+		down: []string{
+			`DROP TABLE IF EXISTS api_token;`,
+			`DROP TABLE IF EXISTS session;`,
+			`DROP TABLE IF EXISTS users;`,
+			`DROP TABLE IF EXISTS comment;`,
+			`DROP TABLE IF EXISTS post_tags;`,
+			`DROP TABLE IF EXISTS vote;`,
+			`DROP TABLE IF EXISTS post;`,
+		},
+	},
+	{
+		version: 2,
+		name:    "split post classification into label and score",
+		up: []string{
+			`ALTER TABLE post ADD COLUMN classificationscore double precision NOT NULL DEFAULT 0;`,
+			// Classifications used to be like "CODE 12.3% code (a/b)".
+			`UPDATE post SET classification='CODE' WHERE classification LIKE 'CODE %';`,
+			`UPDATE post SET classification='NOTCODE' WHERE classification LIKE 'NOTCODE %';`,
+			`CREATE INDEX post_classification ON post(classification);`,
+		},
+		down: []string{
+			`DROP INDEX post_classification;`,
+			`ALTER TABLE post DROP COLUMN classificationscore;`,
+		},
+	},
+	{
+		version: 3,
+		name:    "add post languages",
+		up: []string{
+			`CREATE TABLE post_languages (postid integer, language text, PRIMARY KEY (postid, language));`,
+			`CREATE INDEX post_languages_language ON post_languages(language);`,
+		},
+		down: []string{
+			`DROP TABLE post_languages;`,
+		},
+	},
+	{
+		version: 4,
+		name:    "add post classification confirmation",
+		up: []string{
+			`ALTER TABLE post ADD COLUMN classificationconfirmed boolean NOT NULL DEFAULT false;`,
+		},
+		down: []string{
+			`ALTER TABLE post DROP COLUMN classificationconfirmed;`,
+		},
+	},
+	{
+		version: 5,
+		name:    "add job queue",
+		up: []string{
+			`CREATE TABLE job (id {{serial}}, kind text NOT NULL, postid integer NOT NULL, attempts integer NOT NULL DEFAULT 0, runat {{timestamp}} NOT NULL, lockeduntil {{timestamp}} NOT NULL, lasterror text NOT NULL DEFAULT '', failed boolean NOT NULL DEFAULT false, createdat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX job_runat ON job(runat) WHERE NOT failed;`,
+			`CREATE INDEX job_postid ON job(postid);`,
+		},
+		down: []string{
+			`DROP TABLE job;`,
+		},
+	},
+	{
+		version: 6,
+		name:    "add link metadata",
+		up: []string{
+			`CREATE TABLE link_metadata (postid integer PRIMARY KEY, title text NOT NULL DEFAULT '', description text NOT NULL DEFAULT '', imageurl text NOT NULL DEFAULT '', canonicalurl text NOT NULL DEFAULT '', author text NOT NULL DEFAULT '', publishedat {{timestamp}} NOT NULL, finalurl text NOT NULL DEFAULT '', fetchedat {{timestamp}} NOT NULL);`,
+		},
+		down: []string{
+			`DROP TABLE link_metadata;`,
+		},
+	},
+	{
+		version: 7,
+		name:    "add link checks",
+		up: []string{
+			`CREATE TABLE link_check (postid integer PRIMARY KEY, statuscode integer NOT NULL DEFAULT 0, finalurl text NOT NULL DEFAULT '', error text NOT NULL DEFAULT '', failures integer NOT NULL DEFAULT 0, dead boolean NOT NULL DEFAULT false, checkedat {{timestamp}} NOT NULL);`,
+			`CREATE INDEX link_check_checkedat ON link_check(checkedat);`,
+			`CREATE INDEX link_check_dead ON link_check(postid) WHERE dead;`,
+		},
+		down: []string{
+			`DROP TABLE link_check;`,
+		},
+	},
+	{
+		version: 8,
+		name:    "dedupe posts by canonical link URL",
+		up: []string{
+			// Existing posts' canonical URLs are their link URLs (which are
+			// unique) until "thesrc dedupe" canonicalizes them and merges
+			// the duplicates.
+			`ALTER TABLE post ADD COLUMN canonicalurl text NOT NULL DEFAULT '';`,
+			`UPDATE post SET canonicalurl=coalesce(linkurl, '');`,
+			`DROP INDEX post_linkurl;`,
+			`CREATE INDEX post_linkurl ON post(linkurl);`,
+			`CREATE UNIQUE INDEX post_canonicalurl ON post(canonicalurl) WHERE canonicalurl <> '';`,
+		},
+		down: []string{
+			`DROP INDEX post_canonicalurl;`,
+			`DROP INDEX post_linkurl;`,
+			`CREATE UNIQUE INDEX post_linkurl ON post(linkurl);`,
+			`ALTER TABLE post DROP COLUMN canonicalurl;`,
+		},
+	},
+	{
+		version: 9,
+		name:    "add import states",
+		up: []string{
+			`CREATE TABLE import_state (site text PRIMARY KEY, lastrunat {{timestamp}} NOT NULL, lastsuccessat {{timestamp}} NOT NULL, lastfailureat {{timestamp}} NOT NULL, lasterror text NOT NULL DEFAULT '', failures integer NOT NULL DEFAULT 0, etag text NOT NULL DEFAULT '', lastmodified text NOT NULL DEFAULT '');`,
+			`CREATE TABLE import_seen_item (site text, position integer, itemid text NOT NULL, PRIMARY KEY (site, position));`,
+		},
+		down: []string{
+			`DROP TABLE import_seen_item;`,
+			`DROP TABLE import_state;`,
+		},
+	},
+	{
+		version: 10,
+		name:    "store hashes of session tokens",
+		// Existing sessions' tokens can't be hashed in SQL (in all
+		// databases), so existing sessions are removed, and users must log
+		// in again.
+		up: []string{
+			`DROP TABLE session;`,
+			`CREATE TABLE session (tokenhash text PRIMARY KEY, userid integer, createdat {{timestamp}});`,
+			`CREATE INDEX session_userid ON session(userid);`,
+		},
+		down: []string{
+			`DROP TABLE session;`,
+			`CREATE TABLE session (token text PRIMARY KEY, userid integer, createdat {{timestamp}});`,
+			`CREATE INDEX session_userid ON session(userid);`,
+		},
+	},
+}
@@ -12,23 +12,58 @@ import (
 
 func init() {
 	DB.AddTableWithName(thesrc.Post{}, "post").SetKeys(true, "ID")
-	createSQL = append(createSQL,
-		`CREATE INDEX post_submittedat ON post(submittedat DESC);`,
-		`CREATE UNIQUE INDEX post_linkurl ON post(linkurl);`,
-	)
+	DB.AddTableWithName(thesrc.Vote{}, "vote").SetKeys(false, "PostID", "UserID")
+	DB.AddTableWithName(postTag{}, "post_tags").SetKeys(false, "PostID", "Tag")
+	DB.AddTableWithName(postLanguage{}, "post_languages").SetKeys(false, "PostID", "Language")
+	DB.AddTableWithName(thesrc.LinkMetadata{}, "link_metadata").SetKeys(false, "PostID")
+}
+
+// A postTag is a row in the post_tags table, which associates a tag with a
+// post.
+type postTag struct {
+	PostID int
+	Tag    string
+}
 
+// A postLanguage is a row in the post_languages table, which associates a
+// (detected) programming language with a post.
+type postLanguage struct {
+	PostID   int
+	Language string
 }
 
+// postSearchSQL is a SQL expression for the full-text search document of a
+// post, which is stored in the generated post.search column (in PostgreSQL
+// only; see sqliteSearch). Matches in the title rank above those in the body,
+// which rank above the link URL's domain.
+const postSearchSQL = `setweight(to_tsvector('english', coalesce(title, '')), 'A') || ` +
+	`setweight(to_tsvector('english', coalesce(body, '')), 'B') || ` +
+	`setweight(to_tsvector('simple', coalesce(substring(linkurl from '^[A-Za-z]+://([^/:?#]+)'), '')), 'C')`
+
+// postColumns are the columns of the post table that correspond to fields of
+// thesrc.Post. Queries select these instead of * so that they don't return
+// the (generated) search column.
+const postColumns = `id, title, linkurl, canonicalurl, body, submittedat, authoruserid, score, externalscore, commentcount, classification, classificationscore, classificationconfirmed`
+
 type postsStore struct{ *Datastore }
 
 func (s *postsStore) Get(id int) (*thesrc.Post, error) {
 	var posts []*thesrc.Post
-	if err := s.dbh.Select(&posts, `SELECT * FROM post WHERE id=$1;`, id); err != nil {
+	if err := s.dbh.Select(&posts, `SELECT `+postColumns+` FROM post WHERE id=$1;`, id); err != nil {
 		return nil, err
 	}
 	if len(posts) == 0 {
 		return nil, thesrc.ErrPostNotFound
 	}
+	if err := loadTagsAndLanguages(s.dbh, posts); err != nil {
+		return nil, err
+	}
+	if err := loadMetadata(s.dbh, posts[0]); err != nil {
+		return nil, err
+	}
+	if err := loadLinkChecks(s.dbh, posts); err != nil {
+		return nil, err
+	}
 	return posts[0], nil
 }
 
@@ -37,26 +72,258 @@ func (s *postsStore) List(opt *thesrc.PostListOptions) ([]*thesrc.Post, error) {
 		opt = &thesrc.PostListOptions{}
 	}
 
-	sql := `SELECT * FROM post`
+	sql := `SELECT ` + postColumns + ` FROM post`
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
 	var conds []string
 	if opt.CodeOnly {
-		conds = append(conds, "classification LIKE 'CODE%'")
+		conds = append(conds, "classification="+arg(thesrc.ClassificationCode))
+	}
+	if opt.AuthorUserID != 0 {
+		conds = append(conds, "authoruserid="+arg(opt.AuthorUserID))
+	}
+	if opt.Tag != "" {
+		conds = append(conds, "id IN (SELECT postid FROM post_tags WHERE tag="+arg(opt.Tag)+")")
+	}
+	if opt.Language != "" {
+		conds = append(conds, "id IN (SELECT postid FROM post_languages WHERE language="+arg(opt.Language)+")")
+	}
+	if opt.ClassificationConfirmed {
+		conds = append(conds, "classificationconfirmed="+arg(true))
+	}
+	if opt.Dead {
+		conds = append(conds, "id IN (SELECT postid FROM link_check WHERE dead)")
+	}
+	var rankSQL string
+	if opt.Query != "" {
+		if isSQLite() {
+			var cond string
+			cond, rankSQL = sqliteSearch(opt.Query, arg)
+			conds = append(conds, cond)
+		} else {
+			tsquery := "websearch_to_tsquery('english', " + arg(opt.Query) + ")"
+			conds = append(conds, "search @@ "+tsquery)
+			rankSQL = "ts_rank(search, " + tsquery + ")"
+		}
 	}
 	if len(conds) > 0 {
 		sql += " WHERE (" + strings.Join(conds, ") AND (") + ")"
 	}
 
-	sql += " ORDER BY submittedat DESC LIMIT $1 OFFSET $2;"
+	switch opt.Sort {
+	case "":
+		if rankSQL != "" {
+			sql += " ORDER BY " + rankSQL + " DESC, submittedat DESC"
+		} else {
+			sql += " ORDER BY submittedat DESC"
+		}
+	case thesrc.PostSortNew:
+		sql += " ORDER BY submittedat DESC"
+	case thesrc.PostSortTop:
+		sql += " ORDER BY score DESC, submittedat DESC"
+	case thesrc.PostSortHot:
+		sql += " ORDER BY " + hotRankSQL() + " DESC, submittedat DESC"
+	default:
+		return nil, fmt.Errorf("unknown sort order %q", opt.Sort)
+	}
+
+	sql += " LIMIT " + arg(opt.PerPageOrDefault()) + " OFFSET " + arg(opt.Offset()) + ";"
 
 	var posts []*thesrc.Post
-	err := s.dbh.Select(&posts, sql, opt.PerPageOrDefault(), opt.Offset())
+	err := s.dbh.Select(&posts, sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	if err := loadTagsAndLanguages(s.dbh, posts); err != nil {
+		return nil, err
+	}
 	return posts, nil
 }
 
+// loadTagsAndLanguages sets the Tags and Languages fields of each post (see
+// loadTags and loadLanguages).
+func loadTagsAndLanguages(dbh modl.SqlExecutor, posts []*thesrc.Post) error {
+	if err := loadTags(dbh, posts); err != nil {
+		return err
+	}
+	return loadLanguages(dbh, posts)
+}
+
+// loadTags sets the Tags field of each post to the post's tags (in sorted
+// order).
+func loadTags(dbh modl.SqlExecutor, posts []*thesrc.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*thesrc.Post, len(posts))
+	var args []interface{}
+	var placeholders []string
+	for _, post := range posts {
+		post.Tags = nil
+		byID[post.ID] = post
+		args = append(args, post.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var tags []*postTag
+	if err := dbh.Select(&tags, `SELECT * FROM post_tags WHERE postid IN (`+strings.Join(placeholders, ",")+`) ORDER BY tag;`, args...); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if post, present := byID[t.PostID]; present {
+			post.Tags = append(post.Tags, t.Tag)
+		}
+	}
+	return nil
+}
+
+// setTags replaces the tags of the post with the given ID.
+func setTags(dbh modl.SqlExecutor, postID int, tags []string) error {
+	if _, err := dbh.Exec(`DELETE FROM post_tags WHERE postid=$1;`, postID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := dbh.Insert(&postTag{PostID: postID, Tag: tag}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLanguages sets the Languages field of each post to the post's
+// languages (in sorted order).
+func loadLanguages(dbh modl.SqlExecutor, posts []*thesrc.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*thesrc.Post, len(posts))
+	var args []interface{}
+	var placeholders []string
+	for _, post := range posts {
+		post.Languages = nil
+		byID[post.ID] = post
+		args = append(args, post.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var langs []*postLanguage
+	if err := dbh.Select(&langs, `SELECT * FROM post_languages WHERE postid IN (`+strings.Join(placeholders, ",")+`) ORDER BY language;`, args...); err != nil {
+		return err
+	}
+	for _, l := range langs {
+		if post, present := byID[l.PostID]; present {
+			post.Languages = append(post.Languages, l.Language)
+		}
+	}
+	return nil
+}
+
+// setLanguages replaces the languages of the post with the given ID.
+func setLanguages(dbh modl.SqlExecutor, postID int, langs []string) error {
+	if _, err := dbh.Exec(`DELETE FROM post_languages WHERE postid=$1;`, postID); err != nil {
+		return err
+	}
+	for _, lang := range langs {
+		if err := dbh.Insert(&postLanguage{PostID: postID, Language: lang}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMetadata sets post's Metadata field to its link's metadata, if any.
+func loadMetadata(dbh modl.SqlExecutor, post *thesrc.Post) error {
+	var mds []*thesrc.LinkMetadata
+	if err := dbh.Select(&mds, `SELECT * FROM link_metadata WHERE postid=$1;`, post.ID); err != nil {
+		return err
+	}
+	post.Metadata = nil
+	if len(mds) > 0 {
+		post.Metadata = mds[0]
+	}
+	return nil
+}
+
+// setMetadata replaces the link metadata of the post with the given ID.
+func setMetadata(dbh modl.SqlExecutor, postID int, md *thesrc.LinkMetadata) error {
+	if _, err := dbh.Exec(`DELETE FROM link_metadata WHERE postid=$1;`, postID); err != nil {
+		return err
+	}
+	md.PostID = postID
+	md.PublishedAt = md.PublishedAt.UTC()
+	md.FetchedAt = md.FetchedAt.UTC()
+	return dbh.Insert(md)
+}
+
+// hotRankSQL returns a SQL expression that ranks a post by its score
+// relative to its age in hours, with older posts sinking ("gravity"). Adding 1
+// to the score means that posts with no votes are ranked by age alone.
+func hotRankSQL() string {
+	ageHours := `extract(epoch from (now() - submittedat)) / 3600`
+	if isSQLite() {
+		ageHours = `(julianday('now') - julianday(submittedat)) * 24`
+	}
+	return `(score + 1) / power(` + ageHours + ` + 2, 1.8)`
+}
+
+// sqliteSearch returns a SQL condition that matches posts containing every
+// term in the full-text search query q (except for terms prefixed by "-",
+// which must not appear), and a SQL expression that ranks matching posts. It
+// stands in for PostgreSQL full-text search in SQLite, which lacks it, so it
+// matches substrings instead of stemmed words. arg adds a query argument and
+// returns its placeholder.
+func sqliteSearch(q string, arg func(v interface{}) string) (cond, rank string) {
+	var conds, ranks []string
+	for _, term := range strings.Fields(q) {
+		exclude := strings.HasPrefix(term, "-") && len(term) > 1
+		term = strings.Trim(strings.TrimPrefix(term, "-"), `"`)
+		if term == "" {
+			continue
+		}
+		p := arg("%" + likeEscaper.Replace(term) + "%")
+		like := func(col string) string { return col + " LIKE " + p + ` ESCAPE '\'` }
+		match := like("title") + " OR " + like("body") + " OR " + like("linkurl")
+		if exclude {
+			conds = append(conds, "NOT ("+match+")")
+			continue
+		}
+		conds = append(conds, match)
+		ranks = append(ranks, "4*("+like("title")+") + 2*("+like("body")+") + ("+like("linkurl")+")")
+	}
+	if len(ranks) == 0 {
+		return "0", ""
+	}
+	return "(" + strings.Join(conds, ") AND (") + ")", strings.Join(ranks, " + ")
+}
+
+// updatedCanonicalURL returns the canonical URL of post, an updated version of
+// orig. If the link URL didn't change, it is orig's canonical URL, which may
+// not be canonical yet (see Submit).
+func updatedCanonicalURL(orig, post *thesrc.Post) string {
+	if post.LinkURL == orig.LinkURL {
+		return orig.CanonicalURL
+	}
+	return thesrc.CanonicalizeURL(post.LinkURL)
+}
+
+// needsClassification returns whether a newly submitted post's link should be
+// fetched and classified in the background (by a JobClassifyPost job). Posts
+// whose classifications are already confirmed still need their link
+// metadata.
+func needsClassification(post *thesrc.Post) bool {
+	return post.LinkURL != ""
+}
+
+// likeEscaper escapes the LIKE wildcards in a string.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (s *postsStore) Submit(post *thesrc.Post) (bool, error) {
 	retries := 3
 	var wantRetry bool
@@ -68,25 +335,46 @@ retry:
 		return false, fmt.Errorf("failed to submit post with URL %q after retrying", post.LinkURL)
 	}
 
+	post.CanonicalURL = thesrc.CanonicalizeURL(post.LinkURL)
+
 	var created bool
 	err := transact(s.dbh, func(tx modl.SqlExecutor) error {
+		// Posts submitted before canonical URLs were introduced have their
+		// link URLs as their canonical URLs until "thesrc dedupe" runs, so
+		// also look for them by link URL.
 		var existing []*thesrc.Post
-		if err := tx.Select(&existing, `SELECT * FROM post WHERE linkurl=$1 LIMIT 1;`, post.LinkURL); err != nil {
+		if err := tx.Select(&existing, `SELECT `+postColumns+` FROM post WHERE canonicalurl=$1 OR linkurl=$2 ORDER BY canonicalurl<>$1 LIMIT 1;`, post.CanonicalURL, post.LinkURL); err != nil {
 			return err
 		}
 		if len(existing) > 0 {
 			*post = *existing[0]
-			return nil
+			return loadTagsAndLanguages(tx, []*thesrc.Post{post})
 		}
 
 		if err := tx.Insert(post); err != nil {
-			if strings.Contains(err.Error(), `violates unique constraint "post_linkurl"`) {
+			if isUniqueViolation(err, "post_canonicalurl", "post", "canonicalurl") {
 				time.Sleep(time.Duration(rand.Intn(75)) * time.Millisecond)
 				wantRetry = true
 				return err
 			}
 			return err
 		}
+		if err := setTags(tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+		if err := setLanguages(tx, post.ID, post.Languages); err != nil {
+			return err
+		}
+		if post.Metadata != nil {
+			if err := setMetadata(tx, post.ID, post.Metadata); err != nil {
+				return err
+			}
+		}
+		if needsClassification(post) {
+			if err := enqueueJob(tx, &Job{Kind: JobClassifyPost, PostID: post.ID}); err != nil {
+				return err
+			}
+		}
 
 		created = true
 		return nil
@@ -96,3 +384,110 @@ retry:
 	}
 	return created, err
 }
+
+func (s *postsStore) Update(post *thesrc.Post) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		var orig []*thesrc.Post
+		if err := tx.Select(&orig, `SELECT `+postColumns+` FROM post WHERE id=$1;`, post.ID); err != nil {
+			return err
+		}
+		if len(orig) == 0 {
+			return thesrc.ErrPostNotFound
+		}
+		post.CanonicalURL = updatedCanonicalURL(orig[0], post)
+
+		n, err := tx.Update(post)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return thesrc.ErrPostNotFound
+		}
+		if err := setTags(tx, post.ID, post.Tags); err != nil {
+			return err
+		}
+		if err := setLanguages(tx, post.ID, post.Languages); err != nil {
+			return err
+		}
+		// Posts from List have no Metadata, so leave the stored metadata
+		// alone unless it is set.
+		if post.Metadata != nil {
+			return setMetadata(tx, post.ID, post.Metadata)
+		}
+		return nil
+	})
+}
+
+func (s *postsStore) Delete(id int) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		res, err := tx.Exec(`DELETE FROM post WHERE id=$1;`, id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return thesrc.ErrPostNotFound
+		}
+
+		if _, err := tx.Exec(`DELETE FROM comment WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM post_tags WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM post_languages WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM link_metadata WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM link_check WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM job WHERE postid=$1;`, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM vote WHERE postid=$1;`, id)
+		return err
+	})
+}
+
+func (s *postsStore) ListTags(opt *thesrc.ListOptions) ([]*thesrc.TagCount, error) {
+	if opt == nil {
+		opt = &thesrc.ListOptions{}
+	}
+
+	var tags []*thesrc.TagCount
+	err := s.dbh.Select(&tags, `SELECT tag, count(*) AS count FROM post_tags GROUP BY tag ORDER BY count DESC, tag ASC LIMIT $1 OFFSET $2;`, opt.PerPageOrDefault(), opt.Offset())
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *postsStore) Vote(vote *thesrc.Vote) error {
+	return transact(s.dbh, func(tx modl.SqlExecutor) error {
+		if _, err := NewDatastore(tx).Posts.Get(vote.PostID); err != nil {
+			return err
+		}
+
+		if vote.Unvote {
+			if _, err := tx.Exec(`DELETE FROM vote WHERE postid=$1 AND userid=$2;`, vote.PostID, vote.UserID); err != nil {
+				return err
+			}
+		} else {
+			// Ignore duplicate votes atomically, so that concurrent votes
+			// by the same user don't violate the primary key. (Both
+			// PostgreSQL and SQLite support ON CONFLICT DO NOTHING.)
+			if _, err := tx.Exec(`INSERT INTO vote(postid, userid) VALUES($1, $2) ON CONFLICT DO NOTHING;`, vote.PostID, vote.UserID); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`UPDATE post SET score=(SELECT count(*) FROM vote WHERE postid=$1) WHERE id=$1;`, vote.PostID)
+		return err
+	})
+}
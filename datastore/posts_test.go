@@ -3,12 +3,13 @@ package datastore
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
 
 func TestPostsStore_Get_db(t *testing.T) {
-	want := &thesrc.Post{ID: 1}
+	want := &thesrc.Post{}
 
 	tx, _ := DB.Begin()
 	defer tx.Rollback()
@@ -18,7 +19,7 @@ func TestPostsStore_Get_db(t *testing.T) {
 	}
 
 	d := NewDatastore(tx)
-	post, err := d.Posts.Get(1)
+	post, err := d.Posts.Get(want.ID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -30,7 +31,7 @@ func TestPostsStore_Get_db(t *testing.T) {
 }
 
 func TestPostsStore_List_db(t *testing.T) {
-	want := []*thesrc.Post{{ID: 1}}
+	want := []*thesrc.Post{{}}
 
 	tx, _ := DB.Begin()
 	defer tx.Rollback()
@@ -75,7 +76,7 @@ func TestPostsStore_Submit_new_db(t *testing.T) {
 }
 
 func TestPostsStore_Submit_existing_db(t *testing.T) {
-	want := &thesrc.Post{ID: 1, Title: "existing", LinkURL: "http://example.com"}
+	want := &thesrc.Post{Title: "existing", LinkURL: "http://example.com"}
 
 	tx, _ := DB.Begin()
 	defer tx.Rollback()
@@ -100,3 +101,392 @@ func TestPostsStore_Submit_existing_db(t *testing.T) {
 		t.Error("got post %+v, want %+v", post, want)
 	}
 }
+
+func TestPostsStore_Update_db(t *testing.T) {
+	want := &thesrc.Post{Title: "old", LinkURL: "http://example.com"}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(want); err != nil {
+		t.Fatal(err)
+	}
+
+	want.Title = "new"
+	d := NewDatastore(tx)
+	if err := d.Posts.Update(want); err != nil {
+		t.Fatal(err)
+	}
+
+	post, err := d.Posts.Get(want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("got post %+v, want %+v", post, want)
+	}
+}
+
+func TestPostsStore_Update_notFound_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	if err := d.Posts.Update(&thesrc.Post{ID: 1}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestPostsStore_Delete_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	post := &thesrc.Post{}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	if err := d.Posts.Delete(post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Posts.Get(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+	if err := d.Posts.Delete(post.ID); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
+
+func TestPostsStore_List_author_db(t *testing.T) {
+	want := []*thesrc.Post{{LinkURL: "http://example.com/2", AuthorUserID: 1}}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(&thesrc.Post{LinkURL: "http://example.com/1"}, want[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	posts, err := d.Posts.List(&thesrc.PostListOptions{AuthorUserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range want {
+		normalizeTime(&p.SubmittedAt)
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+}
+
+func TestPostsStore_List_codeOnly_db(t *testing.T) {
+	want := []*thesrc.Post{{LinkURL: "http://example.com/2", Classification: thesrc.ClassificationCode, ClassificationScore: 0.75}}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(&thesrc.Post{LinkURL: "http://example.com/1", Classification: thesrc.ClassificationNotCode, ClassificationScore: 0.9}, want[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	posts, err := d.Posts.List(&thesrc.PostListOptions{CodeOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range want {
+		normalizeTime(&p.SubmittedAt)
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+}
+
+func TestPostsStore_List_classificationConfirmed_db(t *testing.T) {
+	want := []*thesrc.Post{{LinkURL: "http://example.com/2", Classification: thesrc.ClassificationCode, ClassificationConfirmed: true}}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(&thesrc.Post{LinkURL: "http://example.com/1", Classification: thesrc.ClassificationCode}, want[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	posts, err := d.Posts.List(&thesrc.PostListOptions{ClassificationConfirmed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range want {
+		normalizeTime(&p.SubmittedAt)
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts %+v, want %+v", posts, want)
+	}
+}
+
+func TestPostsStore_List_sort_db(t *testing.T) {
+	now := time.Now()
+	old := &thesrc.Post{LinkURL: "http://example.com/1", SubmittedAt: now.Add(-72 * time.Hour), Score: 10}
+	recent := &thesrc.Post{LinkURL: "http://example.com/2", SubmittedAt: now.Add(-1 * time.Hour), Score: 2}
+	newest := &thesrc.Post{LinkURL: "http://example.com/3", SubmittedAt: now, Score: 0}
+
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	if err := tx.Insert(old, recent, newest); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]int{
+		"":                 {newest.ID, recent.ID, old.ID},
+		thesrc.PostSortNew: {newest.ID, recent.ID, old.ID},
+		thesrc.PostSortTop: {old.ID, recent.ID, newest.ID},
+		thesrc.PostSortHot: {recent.ID, newest.ID, old.ID},
+	}
+	d := NewDatastore(tx)
+	for sort, wantIDs := range tests {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{Sort: sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		if !reflect.DeepEqual(ids, wantIDs) {
+			t.Errorf("sort %q: got post IDs %v, want %v", sort, ids, wantIDs)
+		}
+	}
+
+	if _, err := d.Posts.List(&thesrc.PostListOptions{Sort: "x"}); err == nil {
+		t.Error("got nil error for unknown sort order")
+	}
+}
+
+func TestPostsStore_List_query_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post;`) // test on a clean DB
+	inTitle := &thesrc.Post{LinkURL: "http://example.com/1", Title: "A fast JSON parser", Body: "Decoding quickly"}
+	inBody := &thesrc.Post{LinkURL: "http://example.com/2", Title: "Compilers", Body: "Writing a parser for JSON by hand"}
+	inDomain := &thesrc.Post{LinkURL: "https://github.com/a/b", Title: "Some repository"}
+	if err := tx.Insert(inTitle, inBody, inDomain, &thesrc.Post{LinkURL: "http://example.com/4", Title: "Unrelated"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]int{
+		"json parser": {inTitle.ID, inBody.ID}, // title matches rank above body matches
+		"github.com":  {inDomain.ID},
+		"nonexistent": nil,
+	}
+	d := NewDatastore(tx)
+	for query, wantIDs := range tests {
+		posts, err := d.Posts.List(&thesrc.PostListOptions{Query: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		if !reflect.DeepEqual(ids, wantIDs) {
+			t.Errorf("query %q: got post IDs %v, want %v", query, ids, wantIDs)
+		}
+	}
+}
+
+func TestPostsStore_tags_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post_tags;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+
+	d := NewDatastore(tx)
+	post1 := &thesrc.Post{LinkURL: "http://example.com/1", Tags: []string{"go", "postgresql"}}
+	post2 := &thesrc.Post{LinkURL: "http://example.com/2", Tags: []string{"go"}}
+	for _, post := range []*thesrc.Post{post1, post2, {LinkURL: "http://example.com/3"}} {
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post, err := d.Posts.Get(post1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "postgresql"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("got tags %q, want %q", post.Tags, want)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Tag: "postgresql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != post1.ID {
+		t.Errorf("got posts %+v, want only post %d", posts, post1.ID)
+	}
+
+	tags, err := d.Posts.ListTags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*thesrc.TagCount{{Tag: "go", Count: 2}, {Tag: "postgresql", Count: 1}}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tag counts %+v, want %+v", tags, want)
+	}
+
+	post.Tags = []string{"sql"}
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	post, err = d.Posts.Get(post1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sql"}; !reflect.DeepEqual(post.Tags, want) {
+		t.Errorf("after update, got tags %q, want %q", post.Tags, want)
+	}
+}
+
+func TestPostsStore_languages_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM post_languages;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+
+	d := NewDatastore(tx)
+	post1 := &thesrc.Post{LinkURL: "http://example.com/1", Languages: []string{"go", "sql"}}
+	post2 := &thesrc.Post{LinkURL: "http://example.com/2", Languages: []string{"go"}}
+	for _, post := range []*thesrc.Post{post1, post2, {LinkURL: "http://example.com/3"}} {
+		if _, err := d.Posts.Submit(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post, err := d.Posts.Get(post1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "sql"}; !reflect.DeepEqual(post.Languages, want) {
+		t.Errorf("got languages %q, want %q", post.Languages, want)
+	}
+
+	posts, err := d.Posts.List(&thesrc.PostListOptions{Language: "sql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != post1.ID {
+		t.Errorf("got posts %+v, want only post %d", posts, post1.ID)
+	}
+
+	post.Languages = []string{"rust"}
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	post, err = d.Posts.Get(post1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"rust"}; !reflect.DeepEqual(post.Languages, want) {
+		t.Errorf("after update, got languages %q, want %q", post.Languages, want)
+	}
+}
+
+func TestPostsStore_metadata_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM link_metadata;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+
+	d := NewDatastore(tx)
+	post := &thesrc.Post{LinkURL: "http://example.com"}
+	if _, err := d.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &thesrc.LinkMetadata{
+		PostID:      post.ID,
+		Title:       "t",
+		Description: "d",
+		ImageURL:    "http://example.com/i.png",
+		PublishedAt: time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC),
+		FinalURL:    "http://example.com/",
+		FetchedAt:   time.Date(2014, 3, 2, 12, 0, 0, 0, time.UTC),
+	}
+	post.Metadata = want
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	post, err := d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Metadata != nil {
+		normalizeTime(&post.Metadata.PublishedAt)
+		normalizeTime(&post.Metadata.FetchedAt)
+	}
+	if !reflect.DeepEqual(post.Metadata, want) {
+		t.Errorf("got metadata %+v, want %+v", post.Metadata, want)
+	}
+
+	// Updating a post without metadata (e.g., from List) keeps its metadata.
+	post.Metadata = nil
+	post.Title = "t2"
+	if err := d.Posts.Update(post); err != nil {
+		t.Fatal(err)
+	}
+	post, err = d.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Metadata == nil || post.Metadata.Description != want.Description {
+		t.Errorf("after update without metadata, got metadata %+v, want %+v", post.Metadata, want)
+	}
+}
+
+func TestPostsStore_Vote_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM vote;`) // test on a clean DB
+	tx.Exec(`DELETE FROM post;`)
+	post := &thesrc.Post{ExternalScore: 100}
+	if err := tx.Insert(post); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDatastore(tx)
+	checkScore := func(want int) {
+		got, err := d.Posts.Get(post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Score != want {
+			t.Errorf("got score %d, want %d", got.Score, want)
+		}
+	}
+
+	for _, vote := range []*thesrc.Vote{{PostID: post.ID, UserID: 1}, {PostID: post.ID, UserID: 2}, {PostID: post.ID, UserID: 2}} {
+		if err := d.Posts.Vote(vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkScore(2) // voting twice only counts once
+
+	if err := d.Posts.Vote(&thesrc.Vote{PostID: post.ID, UserID: 1, Unvote: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkScore(1)
+
+	if err := d.Posts.Vote(&thesrc.Vote{PostID: post.ID + 1, UserID: 1}); err != thesrc.ErrPostNotFound {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrPostNotFound)
+	}
+}
@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jmoiron/modl"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName is the name of the database/sql driver for SQLite
+// databases. It is the go-sqlite3 driver with the SQL functions that thesrc's
+// queries use but that SQLite lacks.
+const sqliteDriverName = "sqlite3_thesrc"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("power", math.Pow, true)
+		},
+	})
+}
+
+// sqliteDataSource returns the go-sqlite3 data source name for the SQLite
+// database file at path, which may be followed by "?" and go-sqlite3
+// options.
+//
+// Transactions take the write lock when they begin (instead of when they
+// first write) so that concurrent read-then-write transactions (like
+// postsStore.Submit) wait for each other instead of failing.
+func sqliteDataSource(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return "file:" + path + sep + "_busy_timeout=5000&_txlock=immediate&_journal_mode=WAL"
+}
+
+// isSQLite returns whether DB is a SQLite database.
+func isSQLite() bool {
+	_, ok := DB.Dialect.(modl.SqliteDialect)
+	return ok
+}
+
+// isUniqueViolation returns whether err is a violation of the unique index
+// named index on table.column. PostgreSQL reports the index name, and SQLite
+// reports the table and column.
+func isUniqueViolation(err error, index, table, column string) bool {
+	msg := err.Error()
+	return strings.Contains(msg, fmt.Sprintf("violates unique constraint %q", index)) ||
+		strings.Contains(msg, "UNIQUE constraint failed: "+table+"."+column)
+}
@@ -0,0 +1,87 @@
+package datastore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.APIToken{}, "api_token").SetKeys(true, "ID")
+}
+
+type tokensStore struct{ *Datastore }
+
+func (s *tokensStore) Get(id int) (*thesrc.APIToken, error) {
+	var tokens []*thesrc.APIToken
+	if err := s.dbh.Select(&tokens, `SELECT * FROM api_token WHERE id=$1;`, id); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, thesrc.ErrAPITokenNotFound
+	}
+	return tokens[0], nil
+}
+
+func (s *tokensStore) List(opt *thesrc.APITokenListOptions) ([]*thesrc.APIToken, error) {
+	if opt == nil {
+		opt = &thesrc.APITokenListOptions{}
+	}
+
+	var tokens []*thesrc.APIToken
+	err := s.dbh.Select(&tokens, `SELECT * FROM api_token WHERE userid=$1 ORDER BY createdat DESC LIMIT $2 OFFSET $3;`, opt.UserID, opt.PerPageOrDefault(), opt.Offset())
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *tokensStore) Create(token *thesrc.APIToken) error {
+	secret, err := newToken()
+	if err != nil {
+		return err
+	}
+	token.Token = secret
+	token.TokenHash = hashToken(secret)
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	return s.dbh.Insert(token)
+}
+
+func (s *tokensStore) Revoke(id int) error {
+	res, err := s.dbh.Exec(`DELETE FROM api_token WHERE id=$1;`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return thesrc.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// authenticate returns the user that the API token is for.
+func (s *tokensStore) authenticate(token string) (*thesrc.User, error) {
+	var users []*thesrc.User
+	if err := s.dbh.Select(&users, `SELECT users.* FROM users INNER JOIN api_token ON api_token.userid=users.id WHERE api_token.tokenhash=$1;`, hashToken(token)); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, thesrc.ErrBadToken
+	}
+	return users[0], nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of an API or session token.
+// Only the hashes of tokens are stored, so that a leaked database doesn't leak
+// usable tokens.
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
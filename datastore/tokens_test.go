@@ -0,0 +1,55 @@
+package datastore
+
+import (
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestTokensStore_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM api_token;`) // test on a clean DB
+	tx.Exec(`DELETE FROM session;`)
+	tx.Exec(`DELETE FROM users;`)
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "u"}
+	if err := d.Users.Signup(user, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	token := &thesrc.APIToken{UserID: user.ID, Name: "n"}
+	if err := d.Tokens.Create(token); err != nil {
+		t.Fatal(err)
+	}
+	if token.Token == "" {
+		t.Fatal("want token secret after creating")
+	}
+
+	tokens, err := d.Tokens.List(&thesrc.APITokenListOptions{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != token.ID {
+		t.Errorf("got tokens %+v, want only %+v", tokens, token)
+	}
+	if tokens[0].Token != "" {
+		t.Error("listed token includes secret")
+	}
+
+	authUser, err := d.Users.Authenticate(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authUser.ID != user.ID {
+		t.Errorf("got authenticated user ID %d, want %d", authUser.ID, user.ID)
+	}
+
+	if err := d.Tokens.Revoke(token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Users.Authenticate(token.Token); err != thesrc.ErrBadToken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadToken)
+	}
+}
@@ -0,0 +1,105 @@
+package datastore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	DB.AddTableWithName(thesrc.User{}, "users").SetKeys(true, "ID")
+	DB.AddTableWithName(thesrc.Session{}, "session").SetKeys(false, "TokenHash")
+}
+
+// SessionMaxAge is how long after logging in a session expires (and the user
+// must log in again).
+var SessionMaxAge = 30 * 24 * time.Hour
+
+type usersStore struct{ *Datastore }
+
+func (s *usersStore) Get(login string) (*thesrc.User, error) {
+	var users []*thesrc.User
+	if err := s.dbh.Select(&users, `SELECT * FROM users WHERE login=$1;`, login); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, thesrc.ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+func (s *usersStore) Signup(user *thesrc.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	if user.RegisteredAt.IsZero() {
+		user.RegisteredAt = time.Now()
+	}
+
+	if err := s.dbh.Insert(user); err != nil {
+		if isUniqueViolation(err, "users_login", "users", "login") {
+			return thesrc.ErrLoginTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *usersStore) Login(cred *thesrc.Credentials) (*thesrc.Session, error) {
+	user, err := s.Get(cred.Login)
+	if err == thesrc.ErrUserNotFound {
+		return nil, thesrc.ErrBadCredentials
+	} else if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(cred.Password)); err != nil {
+		return nil, thesrc.ErrBadCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sess := &thesrc.Session{Token: token, TokenHash: hashToken(token), UserID: user.ID, CreatedAt: now}
+	if err := s.dbh.Insert(sess); err != nil {
+		return nil, err
+	}
+
+	// Remove expired sessions, which can no longer be used.
+	if _, err := s.dbh.Exec(`DELETE FROM session WHERE createdat<=$1;`, now.Add(-SessionMaxAge)); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *usersStore) Logout(token string) error {
+	_, err := s.dbh.Exec(`DELETE FROM session WHERE tokenhash=$1;`, hashToken(token))
+	return err
+}
+
+func (s *usersStore) Authenticate(token string) (*thesrc.User, error) {
+	var users []*thesrc.User
+	if err := s.dbh.Select(&users, `SELECT users.* FROM users INNER JOIN session ON session.userid=users.id WHERE session.tokenhash=$1 AND session.createdat>$2;`, hashToken(token), time.Now().UTC().Add(-SessionMaxAge)); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		// Not a session token, so try it as an API token.
+		return (&tokensStore{s.Datastore}).authenticate(token)
+	}
+	return users[0], nil
+}
+
+// newToken returns a new random token suitable for use as a secret.
+func newToken() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,113 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestUsersStore_Signup_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM users;`) // test on a clean DB
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "u"}
+	if err := d.Users.Signup(user, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 {
+		t.Error("want nonzero user.ID after signing up")
+	}
+	if string(user.PasswordHash) == "secret" {
+		t.Error("password was stored unhashed")
+	}
+
+	if err := d.Users.Signup(&thesrc.User{Login: "u"}, "secret"); err != thesrc.ErrLoginTaken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrLoginTaken)
+	}
+}
+
+func TestUsersStore_Login_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM session;`) // test on a clean DB
+	tx.Exec(`DELETE FROM users;`)
+
+	d := NewDatastore(tx)
+	user := &thesrc.User{Login: "u"}
+	if err := d.Users.Signup(user, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Users.Login(&thesrc.Credentials{Login: "u", Password: "wrong"}); err != thesrc.ErrBadCredentials {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadCredentials)
+	}
+	if _, err := d.Users.Login(&thesrc.Credentials{Login: "nobody", Password: "secret"}); err != thesrc.ErrBadCredentials {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadCredentials)
+	}
+
+	sess, err := d.Users.Login(&thesrc.Credentials{Login: "u", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.UserID != user.ID {
+		t.Errorf("got session user ID %d, want %d", sess.UserID, user.ID)
+	}
+
+	authUser, err := d.Users.Authenticate(sess.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authUser.ID != user.ID {
+		t.Errorf("got authenticated user ID %d, want %d", authUser.ID, user.ID)
+	}
+
+	var sessions []*thesrc.Session
+	if err := tx.Select(&sessions, `SELECT * FROM session;`); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].TokenHash != hashToken(sess.Token) {
+		t.Errorf("got stored sessions %+v, want only the session's token hash", sessions)
+	}
+
+	if err := d.Users.Logout(sess.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Users.Authenticate(sess.Token); err != thesrc.ErrBadToken {
+		t.Errorf("got error %v, want %v", err, thesrc.ErrBadToken)
+	}
+}
+
+func TestUsersStore_sessionExpiry_db(t *testing.T) {
+	tx, _ := DB.Begin()
+	defer tx.Rollback()
+	tx.Exec(`DELETE FROM session;`) // test on a clean DB
+	tx.Exec(`DELETE FROM users;`)
+
+	testSessionExpiry(t, NewDatastore(tx))
+}
+
+// testSessionExpiry tests that d's sessions expire after SessionMaxAge.
+func testSessionExpiry(t *testing.T, d *Datastore) {
+	orig := SessionMaxAge
+	defer func() { SessionMaxAge = orig }()
+
+	if err := d.Users.Signup(&thesrc.User{Login: "u"}, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	sess, err := d.Users.Login(&thesrc.Credentials{Login: "u", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Users.Authenticate(sess.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	SessionMaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := d.Users.Authenticate(sess.Token); err != thesrc.ErrBadToken {
+		t.Errorf("got error %v for expired session, want %v", err, thesrc.ErrBadToken)
+	}
+}
@@ -11,8 +11,23 @@ import (
 type ErrorResponse struct {
 	Response *http.Response `json:",omitempty"`
 	Message  string
+
+	// Code is a machine-readable code describing the kind of error (one of the
+	// ErrorCode* constants).
+	Code string `json:",omitempty"`
 }
 
+// Error codes reported in the Code field of an ErrorResponse.
+const (
+	ErrorCodeBadRequest   = "bad_request"
+	ErrorCodeUnauthorized = "unauthorized"
+	ErrorCodeForbidden    = "forbidden"
+	ErrorCodeNotFound     = "not_found"
+	ErrorCodeConflict     = "conflict"
+	ErrorCodeInvalid      = "invalid"
+	ErrorCodeInternal     = "internal"
+)
+
 func (r *ErrorResponse) Error() string {
 	return fmt.Sprintf("%v %v: %d %v",
 		r.Response.Request.Method, r.Response.Request.URL,
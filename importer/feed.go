@@ -0,0 +1,296 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+// A Source is a feed to import posts from, as configured in a sources file
+// (see LoadSources).
+type Source struct {
+	// URL is the URL of the RSS 2.0, Atom, or JSON Feed feed. It must be
+	// on a public host, on port 80 or 443 (see Transport).
+	URL string
+
+	// Site is the name of the site (which must be unique). It defaults to the
+	// feed URL's host.
+	Site string
+
+	// Tags are the tags given to posts imported from the feed.
+	Tags []string
+
+	// Interval is how often "thesrc import -daemon" imports posts from the
+	// feed (e.g., "30m"). If empty, it uses its default interval.
+	Interval string
+}
+
+// LoadSources reads a JSON array of Sources from the named file and returns
+// Fetchers that fetch posts from them. For example:
+//
+//	[
+//	  {"url": "https://blog.golang.org/feed.atom", "site": "go-blog", "tags": ["go"]},
+//	  {"url": "https://example.com/feed.json", "interval": "6h"}
+//	]
+func LoadSources(filename string) ([]Fetcher, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fetchers, err := readSources(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return fetchers, nil
+}
+
+func readSources(r io.Reader) ([]Fetcher, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var sources []*Source
+	if err := dec.Decode(&sources); err != nil {
+		return nil, err
+	}
+
+	fetchers := make([]Fetcher, len(sources))
+	sites := map[string]bool{}
+	for i, src := range sources {
+		u, err := url.Parse(src.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("source %d: invalid feed URL %q", i, src.URL)
+		}
+		site := src.Site
+		if site == "" {
+			site = u.Host
+		}
+		if sites[site] {
+			return nil, fmt.Errorf("source %d: duplicate site name %q", i, site)
+		}
+		sites[site] = true
+
+		tags, err := thesrc.NormalizeTags(src.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %s", i, err)
+		}
+		var interval time.Duration
+		if src.Interval != "" {
+			interval, err = time.ParseDuration(src.Interval)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("source %d: invalid interval %q", i, src.Interval)
+			}
+		}
+
+		fetchers[i] = &feed{url: src.URL, site: site, tags: tags, interval: interval}
+	}
+	return fetchers, nil
+}
+
+// maxFeedSize is the maximum size of a feed, in bytes.
+const maxFeedSize = 5 << 20 // 5 MB
+
+// feed fetches posts from an RSS 2.0, Atom, or JSON Feed feed.
+type feed struct {
+	url      string
+	site     string
+	tags     []string
+	interval time.Duration
+}
+
+func (f *feed) Fetch() ([]*thesrc.Post, error) {
+	posts, _, err := f.FetchIfModified(Validators{})
+	return posts, err
+}
+
+func (f *feed) FetchIfModified(v Validators) ([]*thesrc.Post, Validators, error) {
+	resp, err := getIfModified(f.url, v)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err == nil && len(data) > maxFeedSize {
+		err = fmt.Errorf("feed is larger than %d bytes", maxFeedSize)
+	}
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	items, err := parseFeed(data)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+
+	base := resp.Request.URL
+	fetchedAt := time.Now().UTC()
+	posts := make([]*thesrc.Post, 0, len(items))
+	for _, item := range items {
+		title := strings.Join(strings.Fields(item.title), " ")
+		if title == "" || item.link == "" {
+			continue
+		}
+		link, err := base.Parse(strings.TrimSpace(item.link))
+		if err != nil {
+			continue
+		}
+		// Items without (valid) dates were presumably published recently,
+		// so use the time they were fetched instead of the zero time
+		// (which would put them at the end of the list of new posts).
+		submittedAt := parseFeedTime(item.date)
+		if submittedAt.IsZero() {
+			submittedAt = fetchedAt
+		}
+		posts = append(posts, &thesrc.Post{
+			Title:       title,
+			LinkURL:     link.String(),
+			SubmittedAt: submittedAt,
+			Tags:        f.tags,
+		})
+	}
+	return posts, responseValidators(resp), nil
+}
+
+func (f *feed) Site() string { return f.site }
+
+func (f *feed) Interval() time.Duration { return f.interval }
+
+// A feedItem is an item in a feed of any format.
+type feedItem struct {
+	title, link, date string
+}
+
+// parseFeed parses an RSS 2.0, Atom, or JSON Feed feed, determining the format
+// from its contents (because feeds' content types are often wrong).
+func parseFeed(data []byte) ([]*feedItem, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return parseJSONFeed(data)
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing feed: %s", err)
+	}
+	switch root.XMLName.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	}
+	return nil, fmt.Errorf("unsupported feed format (root element <%s>)", root.XMLName.Local)
+}
+
+func parseRSS(data []byte) ([]*feedItem, error) {
+	var rss struct {
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+			Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		return nil, fmt.Errorf("parsing RSS feed: %s", err)
+	}
+	items := make([]*feedItem, len(rss.Items))
+	for i, item := range rss.Items {
+		items[i] = &feedItem{title: item.Title, link: item.Link, date: item.PubDate}
+		if items[i].date == "" {
+			items[i].date = item.Date
+		}
+	}
+	return items, nil
+}
+
+func parseAtom(data []byte) ([]*feedItem, error) {
+	var atom struct {
+		Entries []struct {
+			Title string `xml:"title"`
+			Links []struct {
+				Rel  string `xml:"rel,attr"`
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &atom); err != nil {
+		return nil, fmt.Errorf("parsing Atom feed: %s", err)
+	}
+	items := make([]*feedItem, len(atom.Entries))
+	for i, entry := range atom.Entries {
+		items[i] = &feedItem{title: entry.Title, date: entry.Published}
+		if items[i].date == "" {
+			items[i].date = entry.Updated
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				items[i].link = link.Href
+				break
+			}
+		}
+	}
+	return items, nil
+}
+
+func parseJSONFeed(data []byte) ([]*feedItem, error) {
+	var jsonFeed struct {
+		Items []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			ExternalURL   string `json:"external_url"`
+			DatePublished string `json:"date_published"`
+			DateModified  string `json:"date_modified"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &jsonFeed); err != nil {
+		return nil, fmt.Errorf("parsing JSON Feed feed: %s", err)
+	}
+	items := make([]*feedItem, len(jsonFeed.Items))
+	for i, item := range jsonFeed.Items {
+		// Items in link blogs' feeds are about the page at external_url.
+		items[i] = &feedItem{title: item.Title, link: item.ExternalURL, date: item.DatePublished}
+		if items[i].link == "" {
+			items[i].link = item.URL
+		}
+		if items[i].date == "" {
+			items[i].date = item.DateModified
+		}
+	}
+	return items, nil
+}
+
+// feedTimeLayouts are the time formats used in feeds: RFC 3339 in Atom and
+// JSON Feed feeds, and RFC 822 (with variations) in RSS feeds.
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 02 Jan 2006 15:04 -0700",
+}
+
+// parseFeedTime parses a time in a feed, returning the zero time if it can't
+// be parsed.
+func parseFeedTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
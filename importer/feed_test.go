@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func TestFeed_Fetch(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer ts.Close()
+
+	tests := map[string][]*thesrc.Post{
+		"/rss.xml": {
+			{Title: "Understanding the Go memory model", LinkURL: "https://research.example.com/go-memory-model", SubmittedAt: time.Date(2026, 10, 6, 14, 30, 0, 0, time.UTC)},
+			{Title: "Parsing JSON & YAML", LinkURL: ts.URL + "/2026/10/parsing", SubmittedAt: time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)},
+		},
+		"/atom.xml": {
+			{Title: "Zero-downtime PostgreSQL migrations", LinkURL: "https://blog.example.com/pg-migrations", SubmittedAt: time.Date(2026, 10, 7, 10, 0, 0, 0, time.UTC)},
+			{Title: "Profiling Go services", LinkURL: "https://blog.example.com/profiling", SubmittedAt: time.Date(2026, 9, 30, 16, 45, 0, 0, time.UTC)},
+		},
+		"/feed.json": {
+			{Title: "fastjson: a fast JSON parser for Go", LinkURL: "https://github.com/example/fastjson", SubmittedAt: time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC)},
+			{Title: "This week in Rust", LinkURL: "https://news.example.com/41", SubmittedAt: time.Date(2026, 9, 24, 10, 0, 0, 0, time.UTC)},
+		},
+	}
+	for path, want := range tests {
+		f := &feed{url: ts.URL + path, site: "example", tags: []string{"go"}}
+		for _, post := range want {
+			post.Tags = f.tags
+		}
+
+		posts, err := f.Fetch()
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		if len(posts) != len(want) {
+			t.Errorf("%s: got %d posts, want %d", path, len(posts), len(want))
+			continue
+		}
+		for i := range posts {
+			if !reflect.DeepEqual(posts[i], want[i]) {
+				t.Errorf("%s: got post %+v, want %+v", path, posts[i], want[i])
+			}
+		}
+	}
+}
+
+func TestFeed_Fetch_noDate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel>
+<item><title>No date</title><link>https://example.com/1</link></item>
+<item><title>Bad date</title><link>https://example.com/2</link><pubDate>yesterday</pubDate></item>
+</channel></rss>`))
+	}))
+	defer ts.Close()
+
+	before := time.Now()
+	posts, err := (&feed{url: ts.URL}).Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+	for _, post := range posts {
+		if post.SubmittedAt.Before(before) || post.SubmittedAt.After(after) {
+			t.Errorf("%s: got SubmittedAt %s, want the fetch time (between %s and %s)", post.Title, post.SubmittedAt, before, after)
+		}
+	}
+}
+
+func TestFeed_Fetch_errors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Write([]byte(`<html><body>Not a feed</body></html>`))
+		case "/invalid":
+			w.Write([]byte(`<rss><channel><item>`))
+		case "/huge":
+			w.Write([]byte(`<rss><channel><item><title>`))
+			w.Write([]byte(strings.Repeat("x", maxFeedSize)))
+			w.Write([]byte(`</title></item></channel></rss>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/html", "/invalid", "/huge", "/missing"} {
+		if _, err := (&feed{url: ts.URL + path}).Fetch(); err == nil {
+			t.Errorf("%s: got no error", path)
+		}
+	}
+}
+
+func TestLoadSources(t *testing.T) {
+	fetchers, err := LoadSources("testdata/sources.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Fetcher{
+		&feed{url: "https://links.example.com/rss.xml", site: "example-links", tags: []string{"databases", "go"}},
+		&feed{url: "https://news.example.com/feed.json", site: "news.example.com", interval: 6 * time.Hour},
+	}
+	if !reflect.DeepEqual(fetchers, want) {
+		t.Errorf("got fetchers %+v, want %+v", fetchers, want)
+	}
+}
+
+func TestLoadSources_invalid(t *testing.T) {
+	tests := map[string]string{
+		`[{"url": "ftp://example.com/feed"}]`:                                           "invalid feed URL",
+		`[{"url": "https://a.example.com/feed"}, {"url": "https://a.example.com/rss"}]`: "duplicate site name",
+		`[{"url": "https://example.com/feed", "tags": ["not a tag"]}]`:                  "invalid tag",
+		`[{"url": "https://example.com/feed", "interval": "often"}]`:                    "invalid interval",
+		`[{"url": "https://example.com/feed", "tag": "go"}]`:                            "unknown field",
+	}
+	for input, wantErr := range tests {
+		_, err := readSources(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: got error %v, want %q", input, err, wantErr)
+		}
+	}
+}
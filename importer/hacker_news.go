@@ -2,8 +2,6 @@ package importer
 
 import (
 	"encoding/json"
-	"fmt"
-	"net/http"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
@@ -17,16 +15,17 @@ type hackerNews struct {
 }
 
 func (f *hackerNews) Fetch() ([]*thesrc.Post, error) {
-	resp, err := http.Get("http://hnify.herokuapp.com/get/" + f.which)
+	posts, _, err := f.FetchIfModified(Validators{})
+	return posts, err
+}
+
+func (f *hackerNews) FetchIfModified(v Validators) ([]*thesrc.Post, Validators, error) {
+	resp, err := getIfModified("http://hnify.herokuapp.com/get/"+f.which, v)
 	if err != nil {
-		return nil, err
+		return nil, Validators{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
-	}
-
 	var results *struct {
 		Stories []*struct {
 			Title  string
@@ -35,19 +34,19 @@ func (f *hackerNews) Fetch() ([]*thesrc.Post, error) {
 		}
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, err
+		return nil, Validators{}, err
 	}
 
 	posts := make([]*thesrc.Post, len(results.Stories))
 	for i, s := range results.Stories {
 		posts[i] = &thesrc.Post{
-			Title:   s.Title,
-			LinkURL: s.Link,
-			Score:   s.Points,
+			Title:         s.Title,
+			LinkURL:       s.Link,
+			ExternalScore: s.Points,
 		}
 	}
 
-	return posts, nil
+	return posts, responseValidators(resp), nil
 }
 
 func (f *hackerNews) Site() string { return "hn/" + f.which }
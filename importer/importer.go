@@ -1,9 +1,34 @@
 package importer
 
-import "sourcegraph.com/sourcegraph/thesrc"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/safehttp"
+)
 
 var Fetchers = []Fetcher{}
 
+// Transport is the HTTP transport that fetches sites' listings and feeds. It
+// only connects to public addresses and limits the size of responses, and
+// the client that uses it times out, so that a site that never finishes
+// responding can't stall an import forever.
+var Transport http.RoundTripper = &safehttp.Transport{}
+
+var (
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+)
+
+func getHTTPClient() *http.Client {
+	httpClientOnce.Do(func() { httpClient = safehttp.NewClient(Transport) })
+	return httpClient
+}
+
 // A Fetcher fetches posts from other sites.
 type Fetcher interface {
 	// Fetch posts.
@@ -13,27 +38,182 @@ type Fetcher interface {
 	Site() string
 }
 
+// A ConditionalFetcher is a Fetcher that can skip fetching a listing that
+// hasn't changed since it was last fetched, with a conditional HTTP request.
+type ConditionalFetcher interface {
+	Fetcher
+
+	// FetchIfModified is like Fetch, but it sends the validators of the
+	// previous response (if any) in a conditional request. It returns the
+	// validators of the new response, or ErrNotModified if the listing
+	// hasn't changed.
+	FetchIfModified(v Validators) ([]*thesrc.Post, Validators, error)
+}
+
+// Validators are the ETag and Last-Modified headers of an HTTP response.
+type Validators struct {
+	ETag, LastModified string
+}
+
+// ErrNotModified is returned by ConditionalFetchers when the listing hasn't
+// changed since it was last fetched.
+var ErrNotModified = errors.New("not modified")
+
 var Store = thesrc.NewClient(nil)
 
-// Import posts fetched by f. If Imported is non-nil, it is called each time a
-// post is successfully imported.
+// Import posts fetched by f, and record the result in the site's import state
+// (in Store.Imports). Posts whose links were seen in recent imports aren't
+// submitted again, and if f is a ConditionalFetcher, an unchanged listing
+// isn't fetched again. If Imported is non-nil, it is called each time a post
+// is successfully imported.
 func Import(f Fetcher) error {
-	posts, err := f.Fetch()
+	states, err := Store.Imports.List(&thesrc.ImportStateListOptions{Site: f.Site()})
+	if err != nil {
+		return err
+	}
+	state := &thesrc.ImportState{Site: f.Site()}
+	if len(states) > 0 {
+		state = states[0]
+	}
+
+	state.LastRunAt = time.Now().UTC()
+	err = importPosts(f, state)
+	if err == nil {
+		state.LastSuccessAt = state.LastRunAt
+		state.LastError = ""
+		state.Failures = 0
+	} else {
+		state.LastFailureAt = state.LastRunAt
+		state.LastError = err.Error()
+		state.Failures++
+	}
+	if err2 := Store.Imports.Update(state); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
+}
+
+// importPosts imports the posts fetched by f, updating state's validators and
+// seen item IDs.
+func importPosts(f Fetcher, state *thesrc.ImportState) error {
+	var posts []*thesrc.Post
+	var v Validators
+	var err error
+	if cf, ok := f.(ConditionalFetcher); ok {
+		posts, v, err = cf.FetchIfModified(Validators{ETag: state.ETag, LastModified: state.LastModified})
+		if err == ErrNotModified {
+			return nil
+		}
+	} else {
+		posts, err = f.Fetch()
+	}
 	if err != nil {
 		return err
 	}
 
+	seen := make(map[string]bool, len(state.SeenIDs))
+	for _, id := range state.SeenIDs {
+		seen[id] = true
+	}
+	var ids []string
+	defer func() { state.SeenIDs = mergeSeenIDs(ids, state.SeenIDs) }()
+
 	for _, post := range posts {
+		id := itemID(post)
+		if id != "" && seen[id] {
+			if Skipped != nil {
+				Skipped(f.Site(), post)
+			}
+			ids = append(ids, id)
+			continue
+		}
+
 		created, err := Store.Posts.Submit(post)
 		if err != nil {
 			return err
 		}
+		if id != "" {
+			ids = append(ids, id)
+		}
 		if Imported != nil {
 			Imported(f.Site(), post, created)
 		}
 	}
+
+	// Only record the new validators once all of the posts in the listing
+	// have been imported, so that a failed import is retried in full.
+	state.ETag, state.LastModified = v.ETag, v.LastModified
 	return nil
 }
 
+// itemID returns the ID of a fetched post that is used to recognize it in
+// later imports: the canonical URL of its link.
+func itemID(post *thesrc.Post) string {
+	if post.LinkURL == "" {
+		return ""
+	}
+	return thesrc.CanonicalizeURL(post.LinkURL)
+}
+
+// mergeSeenIDs returns the seen item IDs after an import: the IDs of the
+// items in the listing, followed by the previously seen IDs that weren't in
+// it, up to thesrc.MaxSeenIDs.
+func mergeSeenIDs(ids, prev []string) []string {
+	merged := make([]string, 0, len(ids)+len(prev))
+	added := make(map[string]bool, len(ids)+len(prev))
+	for _, list := range [][]string{ids, prev} {
+		for _, id := range list {
+			if len(merged) == thesrc.MaxSeenIDs {
+				return merged
+			}
+			if !added[id] {
+				merged = append(merged, id)
+				added[id] = true
+			}
+		}
+	}
+	return merged
+}
+
+// getIfModified gets url with a conditional request using the validators v,
+// returning ErrNotModified if the server responds with HTTP 304 Not Modified.
+// Otherwise the response must be HTTP 200, and the caller must close its
+// body.
+func getIfModified(url string, v Validators) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
+}
+
+// responseValidators returns the validators of resp.
+func responseValidators(resp *http.Response) Validators {
+	return Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+}
+
 // Imported (if non-nil) is called each time a post is successfully imported.
 var Imported func(site string, post *thesrc.Post, created bool)
+
+// Skipped (if non-nil) is called each time a post isn't imported because it
+// was seen in a recent import.
+var Skipped func(site string, post *thesrc.Post)
@@ -1,11 +1,21 @@
 package importer
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
 
+func init() {
+	// Allow fetching from the (local) test servers.
+	Transport = http.DefaultTransport
+}
+
 type mockFetcher struct {
 	posts []*thesrc.Post
 	err   error
@@ -14,10 +24,43 @@ type mockFetcher struct {
 func (f *mockFetcher) Fetch() ([]*thesrc.Post, error) { return f.posts, f.err }
 func (f *mockFetcher) Site() string                   { return "mock" }
 
+// mockConditionalFetcher is a mockFetcher whose listing has the validators v
+// (and is unmodified if requested with them).
+type mockConditionalFetcher struct {
+	mockFetcher
+	v Validators
+}
+
+func (f *mockConditionalFetcher) FetchIfModified(v Validators) ([]*thesrc.Post, Validators, error) {
+	if v == f.v {
+		return nil, Validators{}, ErrNotModified
+	}
+	return f.posts, f.v, f.err
+}
+
+// mockImportsService returns a MockImportsService that stores the import
+// state in *state.
+func mockImportsService(state **thesrc.ImportState) *thesrc.MockImportsService {
+	return &thesrc.MockImportsService{
+		List_: func(opt *thesrc.ImportStateListOptions) ([]*thesrc.ImportState, error) {
+			if *state == nil {
+				return nil, nil
+			}
+			state2 := **state
+			return []*thesrc.ImportState{&state2}, nil
+		},
+		Update_: func(s *thesrc.ImportState) error {
+			*state = s
+			return nil
+		},
+	}
+}
+
 func TestImport(t *testing.T) {
 	want := &thesrc.Post{Title: "t"}
 
 	var submitCalled bool
+	var state *thesrc.ImportState
 	Store = &thesrc.Client{
 		Posts: &thesrc.MockPostsService{
 			Submit_: func(post *thesrc.Post) (bool, error) {
@@ -28,6 +71,7 @@ func TestImport(t *testing.T) {
 				return true, nil
 			},
 		},
+		Imports: mockImportsService(&state),
 	}
 
 	var imported int
@@ -47,4 +91,125 @@ func TestImport(t *testing.T) {
 	if want := 1; imported != want {
 		t.Errorf("got imported == %d, want %d", imported, want)
 	}
+
+	if state == nil || state.Site != "mock" || state.LastSuccessAt.IsZero() || !state.LastSuccessAt.Equal(state.LastRunAt) {
+		t.Errorf("got import state %+v, want a successful run", state)
+	}
+}
+
+func TestImport_seen(t *testing.T) {
+	var submitted []string
+	state := &thesrc.ImportState{Site: "mock", SeenIDs: []string{"https://example.com/1", "https://example.com/old"}}
+	Store = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Submit_: func(post *thesrc.Post) (bool, error) {
+				submitted = append(submitted, post.LinkURL)
+				return true, nil
+			},
+		},
+		Imports: mockImportsService(&state),
+	}
+	var skipped int
+	Skipped = func(site string, post *thesrc.Post) { skipped++ }
+	defer func() { Skipped = nil }()
+
+	f := &mockFetcher{posts: []*thesrc.Post{{LinkURL: "http://www.example.com/1/"}, {LinkURL: "http://example.com/2"}}}
+	if err := Import(f); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"http://example.com/2"}; !reflect.DeepEqual(submitted, want) {
+		t.Errorf("got submitted %v, want %v", submitted, want)
+	}
+	if skipped != 1 {
+		t.Errorf("got %d skipped posts, want 1", skipped)
+	}
+	if want := []string{"https://example.com/1", "https://example.com/2", "https://example.com/old"}; !reflect.DeepEqual(state.SeenIDs, want) {
+		t.Errorf("got seen IDs %v, want %v", state.SeenIDs, want)
+	}
+}
+
+func TestImport_conditional(t *testing.T) {
+	var submitted int
+	var state *thesrc.ImportState
+	Store = &thesrc.Client{
+		Posts: &thesrc.MockPostsService{
+			Submit_: func(post *thesrc.Post) (bool, error) {
+				submitted++
+				return true, nil
+			},
+		},
+		Imports: mockImportsService(&state),
+	}
+
+	f := &mockConditionalFetcher{mockFetcher{posts: []*thesrc.Post{{LinkURL: "http://example.com"}}}, Validators{ETag: `"1"`}}
+	for i := 0; i < 2; i++ {
+		if err := Import(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if submitted != 1 {
+		t.Errorf("got %d submitted posts, want 1 (the listing wasn't modified)", submitted)
+	}
+	if state.ETag != f.v.ETag || state.Failures != 0 {
+		t.Errorf("got import state %+v, want ETag %q and no failures", state, f.v.ETag)
+	}
+}
+
+func TestImport_failure(t *testing.T) {
+	state := &thesrc.ImportState{Site: "mock", ETag: `"1"`, Failures: 1}
+	Store = &thesrc.Client{Imports: mockImportsService(&state)}
+
+	f := &mockFetcher{err: errors.New("x")}
+	if err := Import(f); err != f.err {
+		t.Fatalf("got error %v, want %v", err, f.err)
+	}
+
+	if state.Failures != 2 || state.LastError != "x" || !state.LastFailureAt.Equal(state.LastRunAt) || !state.LastSuccessAt.IsZero() {
+		t.Errorf("got import state %+v, want a failed run", state)
+	}
+	if state.ETag != `"1"` {
+		t.Errorf("got ETag %q, want it unchanged", state.ETag)
+	}
+}
+
+func TestGetIfModified(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Thu, 01 Oct 2026 12:00:00 GMT")
+	}))
+	defer ts.Close()
+
+	resp, err := getIfModified(ts.URL, Validators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	v := responseValidators(resp)
+	if want := (Validators{ETag: `"v1"`, LastModified: "Thu, 01 Oct 2026 12:00:00 GMT"}); v != want {
+		t.Errorf("got validators %+v, want %+v", v, want)
+	}
+
+	if _, err := getIfModified(ts.URL, v); err != ErrNotModified {
+		t.Errorf("got error %v, want %v", err, ErrNotModified)
+	}
+}
+
+func TestMergeSeenIDs(t *testing.T) {
+	if got, want := mergeSeenIDs([]string{"c", "a"}, []string{"a", "b"}), []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var many []string
+	for i := 0; i < thesrc.MaxSeenIDs+10; i++ {
+		many = append(many, strconv.Itoa(i))
+	}
+	if got := mergeSeenIDs(many[:10], many[10:]); len(got) != thesrc.MaxSeenIDs || got[0] != many[0] {
+		t.Errorf("got %d seen IDs starting with %q, want %d starting with %q", len(got), got[0], thesrc.MaxSeenIDs, many[0])
+	}
 }
@@ -3,7 +3,6 @@ package importer
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
 
 	"sourcegraph.com/sourcegraph/thesrc"
 )
@@ -17,35 +16,36 @@ type lobsters struct {
 }
 
 func (f *lobsters) Fetch() ([]*thesrc.Post, error) {
-	resp, err := http.Get(fmt.Sprintf("https://lobste.rs/%s.json", f.which))
+	posts, _, err := f.FetchIfModified(Validators{})
+	return posts, err
+}
+
+func (f *lobsters) FetchIfModified(v Validators) ([]*thesrc.Post, Validators, error) {
+	resp, err := getIfModified(fmt.Sprintf("https://lobste.rs/%s.json", f.which), v)
 	if err != nil {
-		return nil, err
+		return nil, Validators{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("non-200 HTTP response status: %d", resp.StatusCode)
-	}
-
 	var results []*struct {
 		Title string
 		URL   string
 		Score int
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, err
+		return nil, Validators{}, err
 	}
 
 	posts := make([]*thesrc.Post, len(results))
 	for i, s := range results {
 		posts[i] = &thesrc.Post{
-			Title:   s.Title,
-			LinkURL: s.URL,
-			Score:   s.Score,
+			Title:         s.Title,
+			LinkURL:       s.URL,
+			ExternalScore: s.Score,
 		}
 	}
 
-	return posts, nil
+	return posts, responseValidators(resp), nil
 }
 
 func (f *lobsters) Site() string { return "lobsters/" + f.which }
@@ -21,6 +21,12 @@ type subreddit struct {
 	name string
 }
 
+// subredditTags are the tags given to posts imported from each subreddit.
+var subredditTags = map[string][]string{
+	"golang":     {"go"},
+	"postgresql": {"postgresql"},
+}
+
 func (f *subreddit) Fetch() ([]*thesrc.Post, error) {
 	postsMap := map[string]*thesrc.Post{}
 	patterns := []string{
@@ -46,7 +52,7 @@ func (f *subreddit) Fetch() ([]*thesrc.Post, error) {
 }
 
 func (f *subreddit) fetchOne(urlStr string) ([]*thesrc.Post, error) {
-	resp, err := http.Get(urlStr)
+	resp, err := getHTTPClient().Get(urlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -74,9 +80,10 @@ func (f *subreddit) fetchOne(urlStr string) ([]*thesrc.Post, error) {
 	posts := make([]*thesrc.Post, len(results.Data.Children))
 	for i, s := range results.Data.Children {
 		posts[i] = &thesrc.Post{
-			Title:   s.Data.Title,
-			LinkURL: s.Data.URL,
-			Score:   s.Data.Score,
+			Title:         s.Data.Title,
+			LinkURL:       s.Data.URL,
+			ExternalScore: s.Data.Score,
+			Tags:          subredditTags[f.name],
 		}
 	}
 
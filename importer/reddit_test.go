@@ -0,0 +1,33 @@
+package importer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSubreddit_tags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"children":[{"data":{"title":"t","url":"http://example.com","score":3}}]}}`))
+	}))
+	defer ts.Close()
+
+	tests := map[string][]string{
+		"golang":      {"go"},
+		"postgresql":  {"postgresql"},
+		"programming": nil,
+	}
+	for name, wantTags := range tests {
+		posts, err := (&subreddit{name}).fetchOne(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != 1 {
+			t.Fatalf("%s: got %d posts, want 1", name, len(posts))
+		}
+		if !reflect.DeepEqual(posts[0].Tags, wantTags) {
+			t.Errorf("%s: got tags %q, want %q", name, posts[0].Tags, wantTags)
+		}
+	}
+}
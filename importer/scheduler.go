@@ -0,0 +1,239 @@
+package importer
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// An IntervalFetcher is a Fetcher whose site should be imported from on its
+// own interval (see Scheduler).
+type IntervalFetcher interface {
+	Fetcher
+
+	// Interval is how often posts should be imported from the site, or 0
+	// for the Scheduler's default interval.
+	Interval() time.Duration
+}
+
+// A Clock tells the time and waits. Scheduler uses it instead of the time
+// package so that tests can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// randFloat returns a random number in [0, 1), for jitter.
+var randFloat = rand.Float64
+
+// A Scheduler imports posts from each of its fetchers' sites periodically,
+// until it's stopped.
+type Scheduler struct {
+	Fetchers []Fetcher
+
+	// Interval is how often posts are imported from each site, unless its
+	// fetcher is an IntervalFetcher with its own interval (default 1 hour).
+	Interval time.Duration
+
+	// Jitter is the fraction by which each wait is randomly lengthened or
+	// shortened, so that imports from sites with the same interval are
+	// spread out. The first import from each site is also delayed by up to
+	// this fraction of its interval. If it is 0, imports run exactly on
+	// schedule.
+	Jitter float64
+
+	// Backoff returns how long to wait before importing from a site (with
+	// the given interval) again after failures consecutive failed imports
+	// (default Backoff).
+	Backoff func(interval time.Duration, failures int) time.Duration
+
+	// Import imports posts fetched by a fetcher (default Import).
+	Import func(f Fetcher) error
+
+	// Clock is the clock that imports are scheduled with (default: the
+	// system clock).
+	Clock Clock
+
+	mu     sync.Mutex
+	status map[string]*SiteStatus
+}
+
+// Backoff is the default Scheduler.Backoff. It doubles the interval with each
+// failure, up to a day (or the interval, if that is longer).
+func Backoff(interval time.Duration, failures int) time.Duration {
+	max := 24 * time.Hour
+	if interval > max {
+		max = interval
+	}
+	d := interval
+	for i := 0; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// SiteStatus is the status of a Scheduler's imports from a site.
+type SiteStatus struct {
+	Site string
+
+	// Running is whether posts are being imported from the site.
+	Running bool `json:",omitempty"`
+
+	// LastRunAt, LastSuccessAt, and LastFailureAt are when the scheduler
+	// last imported posts from the site, last did so successfully, and last
+	// failed to.
+	LastRunAt     time.Time
+	LastSuccessAt time.Time
+	LastFailureAt time.Time
+
+	// LastError is the error of the last import, if it failed.
+	LastError string `json:",omitempty"`
+
+	// Failures is the number of consecutive failed imports.
+	Failures int `json:",omitempty"`
+
+	// NextRunAt is when posts will next be imported from the site.
+	NextRunAt time.Time
+}
+
+// Run imports posts from each site on its schedule until stop is closed, and
+// then waits for running imports to finish.
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	s.mu.Lock()
+	s.status = make(map[string]*SiteStatus, len(s.Fetchers))
+	for _, f := range s.Fetchers {
+		s.status[f.Site()] = &SiteStatus{Site: f.Site()}
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, f := range s.Fetchers {
+		wg.Add(1)
+		go func(f Fetcher) {
+			defer wg.Done()
+			s.schedule(f, stop)
+		}(f)
+	}
+	wg.Wait()
+}
+
+// schedule imports posts fetched by f on f's schedule until stop is closed.
+func (s *Scheduler) schedule(f Fetcher, stop <-chan struct{}) {
+	clock := s.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	importFunc := s.Import
+	if importFunc == nil {
+		importFunc = Import
+	}
+	backoff := s.Backoff
+	if backoff == nil {
+		backoff = Backoff
+	}
+	interval := s.interval(f)
+	site := f.Site()
+
+	delay := time.Duration(float64(interval) * s.Jitter * randFloat())
+	for {
+		s.updateStatus(site, func(st *SiteStatus) { st.NextRunAt = clock.Now().Add(delay) })
+		select {
+		case <-stop:
+			return
+		case <-clock.After(delay):
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		s.updateStatus(site, func(st *SiteStatus) {
+			st.Running = true
+			st.LastRunAt = clock.Now()
+		})
+		err := importFunc(f)
+		var failures int
+		s.updateStatus(site, func(st *SiteStatus) {
+			st.Running = false
+			if err == nil {
+				st.LastSuccessAt = clock.Now()
+				st.LastError = ""
+				st.Failures = 0
+			} else {
+				st.LastFailureAt = clock.Now()
+				st.LastError = err.Error()
+				st.Failures++
+			}
+			failures = st.Failures
+		})
+
+		if err == nil {
+			delay = interval
+		} else {
+			delay = backoff(interval, failures)
+			log.Printf("Error importing from %s (%d failures in a row): %s. (Retrying in %s...)", site, failures, err, delay)
+		}
+		delay = s.jitter(delay)
+	}
+}
+
+// interval returns how often posts are imported from f's site.
+func (s *Scheduler) interval(f Fetcher) time.Duration {
+	if f, ok := f.(IntervalFetcher); ok && f.Interval() > 0 {
+		return f.Interval()
+	}
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	return time.Hour
+}
+
+// jitter randomly lengthens or shortens d by up to s.Jitter of d.
+func (s *Scheduler) jitter(d time.Duration) time.Duration {
+	return d + time.Duration(float64(d)*s.Jitter*(2*randFloat()-1))
+}
+
+// updateStatus calls update with the status of site while holding s.mu.
+func (s *Scheduler) updateStatus(site string, update func(st *SiteStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(s.status[site])
+}
+
+// Status returns the status of imports from each site, in the order of
+// s.Fetchers.
+func (s *Scheduler) Status() []*SiteStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]*SiteStatus, 0, len(s.status))
+	for _, f := range s.Fetchers {
+		if st, present := s.status[f.Site()]; present {
+			st2 := *st
+			statuses = append(statuses, &st2)
+		}
+	}
+	return statuses
+}
+
+// ServeHTTP responds with the status of imports from each site, as JSON.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := json.MarshalIndent(s.Status(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.Write(data)
+}
@@ -0,0 +1,204 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only changes when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	c := &fakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{until: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- c.now
+		return w.c
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w.c
+}
+
+// Advance advances the clock by d, firing the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var waiting []*fakeWaiter
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			waiting = append(waiting, w)
+		} else {
+			w.c <- c.now
+		}
+	}
+	c.waiters = waiting
+}
+
+// BlockUntil blocks until n goroutines are waiting on the clock.
+func (c *fakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) != n {
+		c.cond.Wait()
+	}
+}
+
+type mockIntervalFetcher struct {
+	mockFetcher
+	site     string
+	interval time.Duration
+}
+
+func (f *mockIntervalFetcher) Site() string            { return f.site }
+func (f *mockIntervalFetcher) Interval() time.Duration { return f.interval }
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+
+	hourly := &mockIntervalFetcher{site: "hourly"}
+	flaky := &mockIntervalFetcher{site: "flaky", interval: 10 * time.Minute}
+
+	var mu sync.Mutex
+	runs := map[string]int{}
+	s := &Scheduler{
+		Fetchers: []Fetcher{hourly, flaky},
+		Interval: time.Hour,
+		Clock:    clock,
+		Import: func(f Fetcher) error {
+			mu.Lock()
+			defer mu.Unlock()
+			runs[f.Site()]++
+			if f == flaky && runs[f.Site()] <= 2 {
+				return errors.New("x")
+			}
+			return nil
+		},
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(stop)
+		close(done)
+	}()
+
+	checkRuns := func(label string, wantHourly, wantFlaky int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if runs["hourly"] != wantHourly || runs["flaky"] != wantFlaky {
+			t.Errorf("%s: got %d hourly and %d flaky runs, want %d and %d", label, runs["hourly"], runs["flaky"], wantHourly, wantFlaky)
+		}
+	}
+
+	// Both sites are imported from immediately (because there is no
+	// jitter). The flaky site fails, so it is retried in 20 minutes (not
+	// 10).
+	clock.BlockUntil(2)
+	checkRuns("start", 1, 1)
+	clock.Advance(10 * time.Minute)
+	clock.BlockUntil(2)
+	checkRuns("after 10m", 1, 1)
+
+	// It fails again, so it is retried in 40 minutes.
+	clock.Advance(10 * time.Minute)
+	clock.BlockUntil(2)
+	checkRuns("after 20m", 1, 2)
+	st := s.Status()[1]
+	if st.Site != "flaky" || st.Failures != 2 || st.LastError != "x" || !st.LastFailureAt.Equal(start.Add(20*time.Minute)) || !st.NextRunAt.Equal(start.Add(60*time.Minute)) {
+		t.Errorf("after 20m: got status %+v, want 2 failures and the next run at 60m", st)
+	}
+
+	// At 60 minutes, both sites are due, and the flaky site succeeds.
+	clock.Advance(40 * time.Minute)
+	clock.BlockUntil(2)
+	checkRuns("after 60m", 2, 3)
+	st = s.Status()[1]
+	if st.Failures != 0 || st.LastError != "" || !st.LastSuccessAt.Equal(start.Add(60*time.Minute)) || !st.NextRunAt.Equal(start.Add(70*time.Minute)) {
+		t.Errorf("after 60m: got status %+v, want a success and the next run at 70m", st)
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after stop was closed")
+	}
+}
+
+func TestScheduler_jitter(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+
+	s := &Scheduler{Jitter: 0.1}
+	for r, want := range map[float64]time.Duration{0: 54 * time.Minute, 0.5: time.Hour, 1: 66 * time.Minute} {
+		randFloat = func() float64 { return r }
+		if got := s.jitter(time.Hour); got != want {
+			t.Errorf("with random number %v: got %s, want %s", r, got, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{time.Hour, 0, time.Hour},
+		{time.Hour, 1, 2 * time.Hour},
+		{time.Hour, 3, 8 * time.Hour},
+		{time.Hour, 10, 24 * time.Hour},
+		{48 * time.Hour, 2, 48 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := Backoff(test.interval, test.failures); got != test.want {
+			t.Errorf("Backoff(%s, %d): got %s, want %s", test.interval, test.failures, got, test.want)
+		}
+	}
+}
+
+func TestScheduler_ServeHTTP(t *testing.T) {
+	s := &Scheduler{Fetchers: []Fetcher{&mockFetcher{}}}
+	s.status = map[string]*SiteStatus{"mock": {Site: "mock", Failures: 1, LastError: "x"}}
+
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+
+	var statuses []*SiteStatus
+	if err := json.Unmarshal(rw.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Site != "mock" || statuses[0].Failures != 1 || statuses[0].LastError != "x" {
+		t.Errorf("got statuses %+v, want the mock site's status", statuses)
+	}
+}
@@ -0,0 +1,120 @@
+package thesrc
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// An ImportState is the state of importing posts from a site (see the
+// importer package), which lets the importer skip listings and posts that it
+// has already imported.
+type ImportState struct {
+	// Site is the name of the site that posts are imported from.
+	Site string
+
+	// LastRunAt is when posts were last imported from the site (whether or
+	// not that succeeded).
+	LastRunAt time.Time
+
+	// LastSuccessAt is when posts were last imported successfully.
+	LastSuccessAt time.Time
+
+	// LastFailureAt is when importing posts last failed.
+	LastFailureAt time.Time
+
+	// LastError is the error of the last import, if it failed.
+	LastError string `json:",omitempty"`
+
+	// Failures is the number of consecutive failed imports.
+	Failures int `json:",omitempty"`
+
+	// ETag and LastModified are the validators of the site's listing when
+	// it was last fetched, which are sent in conditional requests so that
+	// an unchanged listing isn't fetched again.
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+
+	// SeenIDs are the IDs of the most recently imported items (most recent
+	// first, and at most MaxSeenIDs), which aren't submitted again.
+	SeenIDs []string `db:"-" json:",omitempty"`
+}
+
+// MaxSeenIDs is the maximum number of seen item IDs that are stored for a
+// site.
+const MaxSeenIDs = 1000
+
+// ImportsService interacts with the import state-related endpoints in
+// thesrc's API.
+type ImportsService interface {
+	// List import states.
+	List(opt *ImportStateListOptions) ([]*ImportState, error)
+
+	// Update the import state of a site (given by state.Site), creating it
+	// if it doesn't exist.
+	Update(state *ImportState) error
+}
+
+// ImportStateListOptions specifies options for listing import states.
+type ImportStateListOptions struct {
+	// Site filters the result set to only the import state of this site.
+	Site string `url:",omitempty" json:",omitempty"`
+}
+
+type importsService struct{ client *Client }
+
+func (s *importsService) List(opt *ImportStateListOptions) ([]*ImportState, error) {
+	url, err := s.client.url(router.ImportStates, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []*ImportState
+	_, err = s.client.Do(req, &states)
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (s *importsService) Update(state *ImportState) error {
+	url, err := s.client.url(router.UpdateImportState, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), state)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockImportsService struct {
+	List_   func(opt *ImportStateListOptions) ([]*ImportState, error)
+	Update_ func(state *ImportState) error
+}
+
+var _ ImportsService = &MockImportsService{}
+
+func (s *MockImportsService) List(opt *ImportStateListOptions) ([]*ImportState, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockImportsService) Update(state *ImportState) error {
+	if s.Update_ == nil {
+		return nil
+	}
+	return s.Update_(state)
+}
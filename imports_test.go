@@ -0,0 +1,66 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestImportsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*ImportState{{Site: "hn/top", Failures: 1}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.ImportStates, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"Site": "hn/top"})
+
+		writeJSON(w, want)
+	})
+
+	states, err := client.Imports.List(&ImportStateListOptions{Site: "hn/top"})
+	if err != nil {
+		t.Errorf("Imports.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, state := range want {
+		normalizeTime(&state.LastRunAt)
+		normalizeTime(&state.LastSuccessAt)
+		normalizeTime(&state.LastFailureAt)
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Imports.List returned %+v, want %+v", states, want)
+	}
+}
+
+func TestImportsService_Update(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UpdateImportState, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"Site":"hn/top","LastRunAt":"0001-01-01T00:00:00Z","LastSuccessAt":"0001-01-01T00:00:00Z","LastFailureAt":"0001-01-01T00:00:00Z","ETag":"\"1\"","SeenIDs":["a"]}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Imports.Update(&ImportState{Site: "hn/top", ETag: `"1"`, SeenIDs: []string{"a"}})
+	if err != nil {
+		t.Errorf("Imports.Update returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
@@ -0,0 +1,58 @@
+package thesrc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NormalizeLanguages lowercases languages, trims surrounding whitespace, and
+// removes empty and duplicate languages, returning the result in sorted
+// order. It returns an error if any language contains disallowed characters
+// or is too long. Languages have the same syntax as tags.
+func NormalizeLanguages(langs []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(langs))
+	var norm []string
+	for _, lang := range langs {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" {
+			continue
+		}
+		if !tagPattern.MatchString(lang) {
+			return nil, fmt.Errorf("invalid language %q (languages may contain up to 30 letters, digits, and '+', '#', '.', or '-')", lang)
+		}
+		if _, present := seen[lang]; present {
+			continue
+		}
+		seen[lang] = struct{}{}
+		norm = append(norm, lang)
+	}
+	sort.Strings(norm)
+	return norm, nil
+}
+
+// languageNames are the display names of languages whose names aren't just
+// capitalized.
+var languageNames = map[string]string{
+	"cpp":        "C++",
+	"csharp":     "C#",
+	"css":        "CSS",
+	"html":       "HTML",
+	"javascript": "JavaScript",
+	"objc":       "Objective-C",
+	"php":        "PHP",
+	"sql":        "SQL",
+	"typescript": "TypeScript",
+}
+
+// LanguageName returns the display name of the language lang (such as
+// "JavaScript" for "javascript").
+func LanguageName(lang string) string {
+	if name, present := languageNames[lang]; present {
+		return name
+	}
+	if lang == "" {
+		return ""
+	}
+	return strings.ToUpper(lang[:1]) + lang[1:]
+}
@@ -0,0 +1,42 @@
+package thesrc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLanguages(t *testing.T) {
+	tests := []struct {
+		langs   []string
+		want    []string
+		wantErr bool
+	}{
+		{langs: nil, want: nil},
+		{langs: []string{" Go ", "sql", "go", ""}, want: []string{"go", "sql"}},
+		{langs: []string{"c++", "objective c"}, wantErr: true},
+	}
+	for _, test := range tests {
+		langs, err := NormalizeLanguages(test.langs)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got nil error, want error", test.langs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: NormalizeLanguages returned error: %v", test.langs, err)
+			continue
+		}
+		if !reflect.DeepEqual(langs, test.want) {
+			t.Errorf("%q: got %q, want %q", test.langs, langs, test.want)
+		}
+	}
+}
+
+func TestLanguageName(t *testing.T) {
+	for lang, want := range map[string]string{"go": "Go", "javascript": "JavaScript", "cpp": "C++", "": ""} {
+		if got := LanguageName(lang); got != want {
+			t.Errorf("%q: got %q, want %q", lang, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package linkcheck checks whether posts' links still work, so that dead
+// links can be marked as such (and linked to an archived copy instead).
+package linkcheck
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/safehttp"
+)
+
+// DeadAfterFailures is the number of consecutive failed checks (other than
+// those in which the server said that the page is gone) after which a link is
+// considered dead. Servers are sometimes down temporarily.
+var DeadAfterFailures = 3
+
+// Transport is the HTTP transport that checks links. Because posts' links are
+// user-submitted, it only connects to public addresses.
+var Transport http.RoundTripper = &safehttp.Transport{}
+
+var (
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+)
+
+func getHTTPClient() *http.Client {
+	httpClientOnce.Do(func() { httpClient = safehttp.NewClient(Transport) })
+	return httpClient
+}
+
+// Check checks whether post's link works, returning the result. It requests
+// the link with HEAD, and then with GET if that fails (because many servers
+// don't support HEAD requests). It uses post.LinkCheck (the result of the
+// previous check, if any) to count consecutive failures.
+func Check(post *thesrc.Post) *thesrc.LinkCheck {
+	check := &thesrc.LinkCheck{PostID: post.ID, CheckedAt: time.Now().UTC()}
+
+	resp, err := request("HEAD", post.LinkURL)
+	if err != nil || resp.StatusCode >= 400 {
+		resp, err = request("GET", post.LinkURL)
+	}
+	if err != nil {
+		check.Error = err.Error()
+	} else {
+		check.StatusCode = resp.StatusCode
+		check.FinalURL = resp.Request.URL.String()
+	}
+
+	var prevFailures int
+	if post.LinkCheck != nil {
+		prevFailures = post.LinkCheck.Failures
+	}
+	switch {
+	case gone(check.StatusCode):
+		check.Failures = prevFailures + 1
+		check.Dead = true
+	case err != nil || failed(check.StatusCode):
+		check.Failures = prevFailures + 1
+		check.Dead = check.Failures >= DeadAfterFailures
+	}
+	return check
+}
+
+// request makes a request to url, returning the response (with its body
+// closed) after following redirects.
+func request(method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// gone returns whether an HTTP response status code means that the page no
+// longer exists.
+func gone(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// failed returns whether an HTTP response status code means that the page
+// couldn't be retrieved. Responses that mean that the server is refusing to
+// serve the page to us (such as HTTP 403, which some sites return to
+// crawlers) aren't failures, because the page is probably still there.
+func failed(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return statusCode >= 400
+}
+
+// CheckAll checks the links of posts, running up to concurrency checks at
+// once, and calls done with each post and the result of checking its link.
+// Calls to done are serialized.
+func CheckAll(posts []*thesrc.Post, concurrency int, done func(post *thesrc.Post, check *thesrc.LinkCheck)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan *thesrc.Post)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for post := range work {
+				check := Check(post)
+				mu.Lock()
+				done(post, check)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, post := range posts {
+		work <- post
+	}
+	close(work)
+	wg.Wait()
+}
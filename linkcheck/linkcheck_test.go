@@ -0,0 +1,135 @@
+package linkcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+)
+
+func init() {
+	// Allow checking links on the (local) test server, including redirects
+	// (which safehttp.NewClient's clients only follow to public hosts).
+	httpClientOnce.Do(func() { httpClient = &http.Client{} })
+}
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			http.Error(w, "", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusGone)
+	})
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusForbidden)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusInternalServerError)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	tests := []struct {
+		path         string
+		prevFailures int
+		wantStatus   int
+		wantFinal    string
+		wantFailures int
+		wantDead     bool
+	}{
+		{path: "/ok", wantStatus: 200, wantFinal: "/ok"},
+		{path: "/ok", prevFailures: 2, wantStatus: 200, wantFinal: "/ok"},
+		{path: "/no-head", wantStatus: 200, wantFinal: "/no-head"},
+		{path: "/moved", wantStatus: 200, wantFinal: "/ok"},
+		{path: "/forbidden", wantStatus: 403, wantFinal: "/forbidden"},
+		{path: "/gone", wantStatus: 410, wantFinal: "/gone", wantFailures: 1, wantDead: true},
+		{path: "/missing", wantStatus: 404, wantFinal: "/missing", wantFailures: 1, wantDead: true},
+		{path: "/error", wantStatus: 500, wantFinal: "/error", wantFailures: 1},
+		{path: "/error", prevFailures: 2, wantStatus: 500, wantFinal: "/error", wantFailures: 3, wantDead: true},
+	}
+	for _, test := range tests {
+		post := &thesrc.Post{ID: 1, LinkURL: s.URL + test.path}
+		if test.prevFailures > 0 {
+			post.LinkCheck = &thesrc.LinkCheck{Failures: test.prevFailures}
+		}
+		check := Check(post)
+		if check.PostID != post.ID {
+			t.Errorf("%s: got post ID %d, want %d", test.path, check.PostID, post.ID)
+		}
+		if check.StatusCode != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.path, check.StatusCode, test.wantStatus)
+		}
+		if want := s.URL + test.wantFinal; check.FinalURL != want {
+			t.Errorf("%s: got final URL %q, want %q", test.path, check.FinalURL, want)
+		}
+		if check.Failures != test.wantFailures || check.Dead != test.wantDead {
+			t.Errorf("%s (%d previous failures): got failures=%d dead=%v, want failures=%d dead=%v", test.path, test.prevFailures, check.Failures, check.Dead, test.wantFailures, test.wantDead)
+		}
+		if check.CheckedAt.IsZero() {
+			t.Errorf("%s: CheckedAt is not set", test.path)
+		}
+	}
+}
+
+func TestCheck_unreachable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Close() // so that connections are refused
+
+	check := Check(&thesrc.Post{LinkURL: s.URL})
+	if check.StatusCode != 0 || check.Error == "" || check.Failures != 1 || check.Dead {
+		t.Errorf("got %+v, want an error and 1 failure (not yet dead)", check)
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	// Hold requests until 2 are running at once, to test that CheckAll runs
+	// checks concurrently.
+	var releaseOnce sync.Once
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if running == 2 {
+			releaseOnce.Do(func() { close(release) })
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}))
+	defer s.Close()
+
+	var posts []*thesrc.Post
+	for i := 0; i < 6; i++ {
+		posts = append(posts, &thesrc.Post{ID: i, LinkURL: s.URL})
+	}
+	checked := map[int]bool{}
+	CheckAll(posts, 2, func(post *thesrc.Post, check *thesrc.LinkCheck) {
+		checked[post.ID] = true
+		if check.StatusCode != 200 {
+			t.Errorf("post %d: got status %d, want 200", post.ID, check.StatusCode)
+		}
+	})
+
+	if len(checked) != len(posts) {
+		t.Errorf("checked %d posts, want %d", len(checked), len(posts))
+	}
+	if maxRunning > 2 {
+		t.Errorf("got %d concurrent checks, want at most 2", maxRunning)
+	}
+}
@@ -0,0 +1,122 @@
+package thesrc
+
+import (
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// LinkMetadata is information about the page at a post's link, taken from
+// the page's OpenGraph and other meta tags when it was fetched.
+type LinkMetadata struct {
+	// PostID is the ID of the post whose link this describes. It is zero for
+	// previews of links that haven't been submitted.
+	PostID int `json:",omitempty"`
+
+	// Title is the page's og:title (or, if it has none, its title element).
+	Title string `json:",omitempty"`
+
+	// Description is the page's og:description (or description meta tag).
+	Description string `json:",omitempty"`
+
+	// ImageURL is the URL of the page's og:image.
+	ImageURL string `json:",omitempty"`
+
+	// CanonicalURL is the URL in the page's <link rel="canonical"> (or its
+	// og:url).
+	CanonicalURL string `json:",omitempty"`
+
+	// Author is the page's author meta tag (or article:author).
+	Author string `json:",omitempty"`
+
+	// PublishedAt is when the page says it was published (from its
+	// article:published_time meta tag), or the zero time if unknown.
+	PublishedAt time.Time
+
+	// FinalURL is the URL of the page after following redirects.
+	FinalURL string `json:",omitempty"`
+
+	// FetchedAt is when the page was fetched.
+	FetchedAt time.Time
+}
+
+// A LinkCheck is the result of checking whether a post's link still works.
+type LinkCheck struct {
+	// PostID is the ID of the post whose link was checked.
+	PostID int
+
+	// StatusCode is the HTTP status code of the response (after following
+	// redirects), or 0 if the request failed.
+	StatusCode int `json:",omitempty"`
+
+	// FinalURL is the URL that the link redirected to, or the link URL if it
+	// didn't redirect.
+	FinalURL string `json:",omitempty"`
+
+	// Error describes why the request failed, if it did.
+	Error string `json:",omitempty"`
+
+	// Failures is the number of consecutive checks that have failed.
+	Failures int `json:",omitempty"`
+
+	// Dead is whether the link is considered dead: either the server said
+	// that the page is gone (with HTTP 404 or 410), or the last several
+	// checks failed.
+	Dead bool `json:",omitempty"`
+
+	// CheckedAt is when the link was last checked.
+	CheckedAt time.Time
+}
+
+// Redirected returns whether the link redirected to a different URL than
+// linkURL.
+func (c *LinkCheck) Redirected(linkURL string) bool {
+	return c.FinalURL != "" && c.FinalURL != linkURL
+}
+
+// LinksService interacts with the link-related endpoints in thesrc's API.
+type LinksService interface {
+	// Preview fetches the page at url and returns its metadata, without
+	// submitting a post.
+	Preview(url string) (*LinkMetadata, error)
+}
+
+// LinkPreviewOptions specifies the link to preview.
+type LinkPreviewOptions struct {
+	URL string `url:"url"`
+}
+
+type linksService struct{ client *Client }
+
+func (s *linksService) Preview(linkURL string) (*LinkMetadata, error) {
+	url, err := s.client.url(router.PreviewLink, nil, &LinkPreviewOptions{URL: linkURL})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var md *LinkMetadata
+	_, err = s.client.Do(req, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	return md, nil
+}
+
+type MockLinksService struct {
+	Preview_ func(url string) (*LinkMetadata, error)
+}
+
+var _ LinksService = &MockLinksService{}
+
+func (s *MockLinksService) Preview(url string) (*LinkMetadata, error) {
+	if s.Preview_ == nil {
+		return nil, nil
+	}
+	return s.Preview_(url)
+}
@@ -0,0 +1,40 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestLinksService_Preview(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &LinkMetadata{Title: "t", Description: "d"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.PreviewLink, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"url": "http://example.com"})
+
+		writeJSON(w, want)
+	})
+
+	md, err := client.Links.Preview("http://example.com")
+	if err != nil {
+		t.Errorf("Links.Preview returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.PublishedAt)
+	normalizeTime(&want.FetchedAt)
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("Links.Preview returned %+v, want %+v", md, want)
+	}
+}
@@ -20,6 +20,12 @@ type Post struct {
 	// LinkURL is the URL to a link that this post is about.
 	LinkURL string
 
+	// CanonicalURL is the canonical form of LinkURL (see CanonicalizeURL).
+	// Posts are unique by CanonicalURL, so submitting a link that has already
+	// been submitted in another form (e.g., with "www." or tracking query
+	// parameters) returns the existing post. It is set by the datastore.
+	CanonicalURL string `json:",omitempty"`
+
 	// Body of the post.
 	Body string
 
@@ -29,13 +35,64 @@ type Post struct {
 	// AuthorUserID is the user ID of this post's author.
 	AuthorUserID int
 
-	// Score in points.
+	// Score in points. It is the number of users who have voted for this post.
 	Score int
 
-	// Classification is the output of the classifier on this post.
+	// ExternalScore is the post's score on the site it was imported from (if
+	// any).
+	ExternalScore int
+
+	// CommentCount is the number of comments on this post.
+	CommentCount int
+
+	// Classification is the label that the classifier assigned to this
+	// post's link (ClassificationCode or ClassificationNotCode), or empty if
+	// the post hasn't been classified.
 	Classification string
+
+	// ClassificationScore is the classifier's confidence in Classification,
+	// from 0 to 1.
+	ClassificationScore float64
+
+	// ClassificationConfirmed is whether a moderator has confirmed that
+	// Classification is correct. The classifier doesn't change confirmed
+	// classifications, and it is trained on them (see "thesrc classify
+	// train").
+	ClassificationConfirmed bool
+
+	// Tags are free-form labels (such as "go" or "postgresql") attached to
+	// the post by its submitter or a moderator. They are stored separately
+	// from the post (in the post_tags table).
+	Tags []string `db:"-" json:",omitempty"`
+
+	// Languages are the programming languages (such as "go" or "sql") of the
+	// code in the post's link, as detected by the classifier, in sorted
+	// order. They are stored separately from the post (in the post_languages
+	// table).
+	Languages []string `db:"-" json:",omitempty"`
+
+	// Metadata is information about the page at the post's link (such as
+	// its description), fetched when the post is classified. It is stored
+	// separately from the post (in the link_metadata table), and it is only
+	// set on posts returned by PostsService.Get.
+	Metadata *LinkMetadata `db:"-" json:",omitempty"`
+
+	// LinkCheck is the result of the last check of whether the post's link
+	// still works (or nil if it hasn't been checked). It is stored separately
+	// from the post (in the link_check table), and it is only set on posts
+	// returned by PostsService.Get.
+	LinkCheck *LinkCheck `db:"-" json:",omitempty"`
 }
 
+// Labels for Post.Classification.
+const (
+	// ClassificationCode means that the post's link contains code.
+	ClassificationCode = "CODE"
+
+	// ClassificationNotCode means that the post's link doesn't contain code.
+	ClassificationNotCode = "NOTCODE"
+)
+
 // PostsService interacts with the post-related endpoints in thesrc's API.
 type PostsService interface {
 	// Get a post.
@@ -44,11 +101,38 @@ type PostsService interface {
 	// List posts.
 	List(opt *PostListOptions) ([]*Post, error)
 
-	// Submit a post. If this post's link URL has never been submitted, post.ID
-	// will be a new ID, and created will be true. If it has been submitted
-	// before, post.ID will be the ID of the previous post, and created will be
-	// false.
+	// Submit a post. If this post's link URL has never been submitted (in any
+	// form with the same canonical URL), post.ID will be a new ID, and created
+	// will be true. If it has been submitted before, post will be the previous
+	// post, and created will be false.
 	Submit(post *Post) (created bool, err error)
+
+	// Update an existing post. The post's ID field must be set.
+	Update(post *Post) error
+
+	// Delete a post.
+	Delete(id int) error
+
+	// Vote for a post (or, if vote.Unvote is true, remove a previous vote)
+	// and update the post's Score.
+	Vote(vote *Vote) error
+
+	// ListTags lists tags and the number of posts with each tag, most-used
+	// tags first.
+	ListTags(opt *ListOptions) ([]*TagCount, error)
+}
+
+// A Vote is a user's vote for a post.
+type Vote struct {
+	// PostID is the ID of the post being voted for.
+	PostID int
+
+	// UserID is the ID of the user voting. The API sets it to the
+	// authenticated user's ID.
+	UserID int
+
+	// Unvote is whether to remove the user's vote instead of adding it.
+	Unvote bool `db:"-" json:",omitempty"`
 }
 
 var (
@@ -78,12 +162,65 @@ func (s *postsService) Get(id int) (*Post, error) {
 }
 
 type PostListOptions struct {
-	// CodeOnly filters the result set to only those posts whose links contain code.
+	// CodeOnly filters the result set to only those posts whose links contain
+	// code (i.e., whose Classification is ClassificationCode).
 	CodeOnly bool
 
+	// AuthorUserID, if nonzero, filters the result set to only those posts
+	// submitted by the user with this ID.
+	AuthorUserID int `url:",omitempty" json:",omitempty"`
+
+	// Tag, if non-empty, filters the result set to only those posts with
+	// this tag.
+	Tag string `url:",omitempty" json:",omitempty"`
+
+	// Language, if non-empty, filters the result set to only those posts
+	// whose links contain code in this language.
+	Language string `url:",omitempty" json:",omitempty"`
+
+	// ClassificationConfirmed filters the result set to only those posts
+	// whose classification a moderator has confirmed.
+	ClassificationConfirmed bool `url:",omitempty" json:",omitempty"`
+
+	// Dead filters the result set to only those posts whose links were dead
+	// when they were last checked (see LinkCheck.Dead).
+	Dead bool `url:",omitempty" json:",omitempty"`
+
+	// Query, if non-empty, is a full-text search query that filters the
+	// result set to posts whose title, body, or link URL domain match.
+	Query string `url:",omitempty" json:",omitempty"`
+
+	// Sort is the order of the result set: PostSortNew, PostSortTop, or
+	// PostSortHot. If empty, posts are ordered by search relevance if Query
+	// is set and by PostSortNew otherwise.
+	Sort string `url:",omitempty" json:",omitempty"`
+
 	ListOptions
 }
 
+// Sort orders for PostListOptions.Sort.
+const (
+	// PostSortNew lists the most recently submitted posts first.
+	PostSortNew = "new"
+
+	// PostSortTop lists the highest-scoring posts first.
+	PostSortTop = "top"
+
+	// PostSortHot lists posts with high scores relative to their age first,
+	// so that new posts can outrank older, higher-scoring posts.
+	PostSortHot = "hot"
+)
+
+// ValidPostSort returns whether sort is a valid value for
+// PostListOptions.Sort.
+func ValidPostSort(sort string) bool {
+	switch sort {
+	case "", PostSortNew, PostSortTop, PostSortHot:
+		return true
+	}
+	return false
+}
+
 func (s *postsService) List(opt *PostListOptions) ([]*Post, error) {
 	url, err := s.client.url(router.Posts, nil, opt)
 	if err != nil {
@@ -123,10 +260,79 @@ func (s *postsService) Submit(post *Post) (bool, error) {
 	return resp.StatusCode == http.StatusCreated, nil
 }
 
+func (s *postsService) Update(post *Post) error {
+	url, err := s.client.url(router.UpdatePost, map[string]string{"ID": strconv.Itoa(post.ID)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("PUT", url.String(), post)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &post)
+	return err
+}
+
+func (s *postsService) Delete(id int) error {
+	url, err := s.client.url(router.DeletePost, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) Vote(vote *Vote) error {
+	url, err := s.client.url(router.VotePost, map[string]string{"ID": strconv.Itoa(vote.PostID)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), vote)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *postsService) ListTags(opt *ListOptions) ([]*TagCount, error) {
+	url, err := s.client.url(router.Tags, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*TagCount
+	_, err = s.client.Do(req, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
 type MockPostsService struct {
-	Get_    func(id int) (*Post, error)
-	List_   func(opt *PostListOptions) ([]*Post, error)
-	Submit_ func(post *Post) (bool, error)
+	Get_      func(id int) (*Post, error)
+	List_     func(opt *PostListOptions) ([]*Post, error)
+	Submit_   func(post *Post) (bool, error)
+	Update_   func(post *Post) error
+	Delete_   func(id int) error
+	Vote_     func(vote *Vote) error
+	ListTags_ func(opt *ListOptions) ([]*TagCount, error)
 }
 
 var _ PostsService = &MockPostsService{}
@@ -151,3 +357,31 @@ func (s *MockPostsService) Submit(post *Post) (bool, error) {
 	}
 	return s.Submit_(post)
 }
+
+func (s *MockPostsService) Update(post *Post) error {
+	if s.Update_ == nil {
+		return nil
+	}
+	return s.Update_(post)
+}
+
+func (s *MockPostsService) Delete(id int) error {
+	if s.Delete_ == nil {
+		return nil
+	}
+	return s.Delete_(id)
+}
+
+func (s *MockPostsService) Vote(vote *Vote) error {
+	if s.Vote_ == nil {
+		return nil
+	}
+	return s.Vote_(vote)
+}
+
+func (s *MockPostsService) ListTags(opt *ListOptions) ([]*TagCount, error) {
+	if s.ListTags_ == nil {
+		return nil, nil
+	}
+	return s.ListTags_(opt)
+}
@@ -79,7 +79,7 @@ func TestPostsService_Submit_new(t *testing.T) {
 	mux.HandleFunc(urlPath(t, router.SubmitPost, nil), func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		testMethod(t, r, "POST")
-		testBody(t, r, `{"Title":"t","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"Classification":""}`+"\n")
+		testBody(t, r, `{"Title":"t","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"ExternalScore":0,"CommentCount":0,"Classification":"","ClassificationScore":0,"ClassificationConfirmed":false}`+"\n")
 
 		w.WriteHeader(http.StatusCreated)
 		writeJSON(w, want)
@@ -115,7 +115,7 @@ func TestPostsService_Submit_existing(t *testing.T) {
 	mux.HandleFunc(urlPath(t, router.SubmitPost, nil), func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		testMethod(t, r, "POST")
-		testBody(t, r, `{"Title":"t","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"Classification":""}`+"\n")
+		testBody(t, r, `{"Title":"t","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"ExternalScore":0,"CommentCount":0,"Classification":"","ClassificationScore":0,"ClassificationConfirmed":false}`+"\n")
 
 		writeJSON(w, want)
 	})
@@ -139,3 +139,79 @@ func TestPostsService_Submit_existing(t *testing.T) {
 		t.Errorf("Posts.Submit returned %+v, want %+v", post, want)
 	}
 }
+
+func TestPostsService_Update(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Post{ID: 1, Title: "t2"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.UpdatePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "PUT")
+		testBody(t, r, `{"ID":1,"Title":"t2","LinkURL":"","Body":"","SubmittedAt":"0001-01-01T00:00:00Z","AuthorUserID":0,"Score":0,"ExternalScore":0,"CommentCount":0,"Classification":"","ClassificationScore":0,"ClassificationConfirmed":false}`+"\n")
+
+		writeJSON(w, want)
+	})
+
+	post := &Post{ID: 1, Title: "t2"}
+	err := client.Posts.Update(post)
+	if err != nil {
+		t.Errorf("Posts.Update returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.SubmittedAt)
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("Posts.Update returned %+v, want %+v", post, want)
+	}
+}
+
+func TestPostsService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.DeletePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Posts.Delete(1)
+	if err != nil {
+		t.Errorf("Posts.Delete returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestPostsService_Vote(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.VotePost, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"PostID":1,"UserID":0,"Unvote":true}`+"\n")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Posts.Vote(&Vote{PostID: 1, Unvote: true})
+	if err != nil {
+		t.Errorf("Posts.Vote returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
@@ -6,6 +6,26 @@ func API() *mux.Router {
 	m := mux.NewRouter()
 	m.Path("/posts").Methods("GET").Name(Posts)
 	m.Path("/posts").Methods("POST").Name(SubmitPost)
+	m.Path("/tags").Methods("GET").Name(Tags)
+	m.Path("/preview").Methods("GET").Name(PreviewLink)
+	m.Path("/posts/{ID:.+}/comments").Methods("GET").Name(Comments)
+	m.Path("/posts/{ID:.+}/comments").Methods("POST").Name(SubmitComment)
+	m.Path("/posts/{ID:.+}/comments/{CommentID:.+}").Methods("GET").Name(Comment)
+	m.Path("/posts/{ID:.+}/comments/{CommentID:.+}").Methods("DELETE").Name(DeleteComment)
+	m.Path("/posts/{ID:.+}/vote").Methods("POST").Name(VotePost)
 	m.Path("/posts/{ID:.+}").Methods("GET").Name(Post)
+	m.Path("/posts/{ID:.+}").Methods("PUT", "PATCH").Name(UpdatePost)
+	m.Path("/posts/{ID:.+}").Methods("DELETE").Name(DeletePost)
+	m.Path("/users").Methods("POST").Name(Signup)
+	m.Path("/users/{Login}").Methods("GET").Name(User)
+	m.Path("/user").Methods("GET").Name(AuthenticatedUser)
+	m.Path("/session").Methods("POST").Name(LogIn)
+	m.Path("/session").Methods("DELETE").Name(LogOut)
+	m.Path("/tokens").Methods("GET").Name(APITokens)
+	m.Path("/tokens").Methods("POST").Name(CreateAPIToken)
+	m.Path("/tokens/{ID:.+}").Methods("GET").Name(APIToken)
+	m.Path("/tokens/{ID:.+}").Methods("DELETE").Name(RevokeAPIToken)
+	m.Path("/imports").Methods("GET").Name(ImportStates)
+	m.Path("/imports").Methods("PUT").Name(UpdateImportState)
 	return m
 }
@@ -4,14 +4,39 @@ import "github.com/gorilla/mux"
 
 // App-only routes
 const (
+	NewPosts       = "posts:new"
+	TopPosts       = "posts:top"
+	SearchPosts    = "posts:search"
+	TagPosts       = "posts:tag"
+	LanguagePosts  = "posts:language"
+	FeedAtom       = "feed:atom"
+	FeedRSS        = "feed:rss"
 	SubmitPostForm = "post:submit-form"
+	SignupForm     = "user:signup-form"
+	LogInForm      = "session:login-form"
 )
 
 func App() *mux.Router {
 	m := mux.NewRouter()
 	m.Path("/").Methods("GET").Name(Posts)
+	m.Path("/new").Methods("GET").Name(NewPosts)
+	m.Path("/top").Methods("GET").Name(TopPosts)
+	m.Path("/search").Methods("GET").Name(SearchPosts)
+	m.Path("/t/{Tag}").Methods("GET").Name(TagPosts)
+	m.Path("/tags").Methods("GET").Name(Tags)
+	m.Path("/lang/{Language}").Methods("GET").Name(LanguagePosts)
+	m.Path("/feed.atom").Methods("GET").Name(FeedAtom)
+	m.Path("/feed.rss").Methods("GET").Name(FeedRSS)
+	m.Path("/p/{ID:.+}/comments").Methods("POST").Name(SubmitComment)
+	m.Path("/p/{ID:.+}/vote").Methods("POST").Name(VotePost)
 	m.Path("/p/{ID:.+}").Methods("GET").Name(Post)
 	m.Path("/submit").Methods("GET").Name(SubmitPostForm)
 	m.Path("/posts").Methods("POST").Name(SubmitPost)
+	m.Path("/u/{Login}").Methods("GET").Name(User)
+	m.Path("/signup").Methods("GET").Name(SignupForm)
+	m.Path("/signup").Methods("POST").Name(Signup)
+	m.Path("/login").Methods("GET").Name(LogInForm)
+	m.Path("/login").Methods("POST").Name(LogIn)
+	m.Path("/logout").Methods("POST").Name(LogOut)
 	return m
 }
@@ -3,5 +3,30 @@ package router
 const (
 	Post       = "post"
 	SubmitPost = "post:submit"
+	UpdatePost = "post:update"
+	DeletePost = "post:delete"
+	VotePost   = "post:vote"
 	Posts      = "posts"
+	Tags       = "tags"
+
+	PreviewLink = "link:preview"
+
+	Comment       = "comment"
+	Comments      = "comments"
+	SubmitComment = "comment:submit"
+	DeleteComment = "comment:delete"
+
+	User              = "user"
+	Signup            = "user:signup"
+	AuthenticatedUser = "user:authenticated"
+	LogIn             = "session:login"
+	LogOut            = "session:logout"
+
+	APIToken       = "token"
+	APITokens      = "tokens"
+	CreateAPIToken = "token:create"
+	RevokeAPIToken = "token:revoke"
+
+	ImportStates      = "imports"
+	UpdateImportState = "imports:update"
 )
@@ -0,0 +1,283 @@
+// Package safehttp fetches URLs that users submitted without letting them
+// make the server connect to internal addresses (such as 127.0.0.1, the
+// cloud metadata service at 169.254.169.254, or private networks), and
+// without reading unbounded or unexpected responses.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A BlockedError is returned when a URL's host is, or resolves to, an
+// address that isn't public.
+type BlockedError struct {
+	Host string
+	IP   net.IP // the disallowed IP, if any
+}
+
+func (e *BlockedError) Error() string {
+	if e.IP == nil || e.IP.String() == e.Host {
+		return fmt.Sprintf("refusing to connect to non-public host %s", e.Host)
+	}
+	return fmt.Sprintf("refusing to connect to %s: it resolves to non-public address %s", e.Host, e.IP)
+}
+
+// nonPublicNets are the IP ranges that aren't publicly routable (in
+// addition to those that the net.IP methods check for in IsPublicIP).
+var nonPublicNets = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved (and broadcast)
+	"64:ff9b::/96",    // IPv4/IPv6 translation (may reach IPv4 private addresses)
+	"2001:db8::/32",   // documentation
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// IsPublicIP returns whether ip is a publicly routable address, and not a
+// loopback, private (such as RFC 1918), link-local, multicast, or otherwise
+// reserved address.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowIP returns whether clients may connect to ip. Tests replace it to
+// allow connections to local test servers.
+var allowIP = IsPublicIP
+
+// AllowedPorts are the ports that clients may connect to.
+var AllowedPorts = map[string]bool{"80": true, "443": true}
+
+// LookupIPAddr resolves hostnames. It is a variable so that tests can
+// replace it.
+var LookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// CheckURL returns an error if u isn't an http or https URL to a public host
+// on a standard port. It resolves u's hostname and returns a *BlockedError
+// if any of its addresses isn't public, but it doesn't return an error if
+// the hostname can't be resolved (because the failure may be temporary).
+//
+// Because a hostname may resolve to different addresses later, checking a
+// URL doesn't make it safe to fetch with other clients; use a Transport to
+// fetch it.
+func CheckURL(u *url.URL) error {
+	return checkURL(u, true)
+}
+
+// checkURL is like CheckURL, but it only resolves u's hostname if resolve
+// is true.
+func checkURL(u *url.URL, resolve bool) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("URL scheme must be http or https")
+	}
+	if port := u.Port(); port != "" && !AllowedPorts[port] {
+		return fmt.Errorf("URL port %s is not allowed", port)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return errors.New("URL has no host")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !allowIP(ip) {
+			return &BlockedError{Host: host, IP: ip}
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return &BlockedError{Host: host}
+	}
+	if !resolve {
+		return nil
+	}
+
+	addrs, err := LookupIPAddr(context.Background(), host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !allowIP(addr.IP) {
+			return &BlockedError{Host: host, IP: addr.IP}
+		}
+	}
+	return nil
+}
+
+// DefaultMaxResponseSize is the default Transport.MaxResponseSize.
+const DefaultMaxResponseSize = 5 << 20 // 5 MB
+
+// ErrResponseTooLarge is returned when reading more than a Transport's
+// MaxResponseSize from a response body.
+var ErrResponseTooLarge = errors.New("response body is too large")
+
+// A Transport is an http.RoundTripper that only connects to public
+// addresses on the AllowedPorts. It checks the address that it actually
+// connects to (after resolving the host), so hostnames that resolve to
+// internal addresses are blocked, as are redirects to them.
+type Transport struct {
+	// MaxResponseSize is the maximum size of response bodies, in bytes
+	// (default DefaultMaxResponseSize). Reading more returns
+	// ErrResponseTooLarge.
+	MaxResponseSize int64
+
+	// ContentTypes, if set, are the media types (such as "text/html") of
+	// the responses that are allowed. Responses with other content types
+	// are rejected with an error, without reading their bodies.
+	ContentTypes []string
+
+	once sync.Once
+	base *http.Transport
+}
+
+// dialControl rejects connections to addresses that aren't public or aren't
+// on the AllowedPorts. It runs after the host has been resolved, just before
+// connecting.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !AllowedPorts[port] {
+		return fmt.Errorf("refusing to connect to %s: port %s is not allowed", address, port)
+	}
+	if ip := net.ParseIP(host); ip == nil || !allowIP(ip) {
+		return &BlockedError{Host: host, IP: ip}
+	}
+	return nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialControl}
+		t.base = &http.Transport{
+			// Don't use a proxy: it would make the connections that
+			// dialControl checks.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+		}
+	})
+
+	// Fail early for URLs that are obviously not allowed. The dialer checks
+	// the addresses that hostnames resolve to.
+	if err := checkURL(req.URL, false); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.ContentTypes != nil && req.Method != "HEAD" && resp.StatusCode == http.StatusOK {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if !contains(t.ContentTypes, mediaType) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s has content type %q (allowed: %s)", req.URL, mediaType, strings.Join(t.ContentTypes, ", "))
+		}
+	}
+
+	max := t.MaxResponseSize
+	if max == 0 {
+		max = DefaultMaxResponseSize
+	}
+	if resp.ContentLength > max {
+		resp.Body.Close()
+		return nil, ErrResponseTooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: max}
+	return resp, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, s2 := range ss {
+		if s2 == s {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBody is a response body that returns ErrResponseTooLarge instead of
+// reading more than remaining bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// Read one more byte than allowed to tell whether the body is too
+	// large.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// maxRedirects is the number of redirects that clients created by NewClient
+// follow.
+const maxRedirects = 5
+
+// NewClient returns an HTTP client that fetches URLs with rt (which should be
+// a Transport, or wrap one), following at most 5 redirects and only to URLs
+// allowed by CheckURL.
+func NewClient(rt http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: rt,
+		Timeout:   30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkURL(req.URL, false)
+		},
+	}
+}
@@ -0,0 +1,196 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"127.1.2.3":       false,
+		"10.0.0.1":        false,
+		"172.16.5.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"255.255.255.255": false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	}
+	for ip, want := range tests {
+		if got := IsPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("%s: got %v, want %v", ip, got, want)
+		}
+	}
+}
+
+// fakeLookup makes hostnames resolve to the given addresses during a test.
+func fakeLookup(t *testing.T, hosts map[string]string) {
+	orig := LookupIPAddr
+	t.Cleanup(func() { LookupIPAddr = orig })
+	LookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ip, present := hosts[host]
+		if !present {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	fakeLookup(t, map[string]string{"example.com": "93.184.216.34", "internal.example.com": "10.0.0.1"})
+
+	tests := map[string]bool{ // URL -> whether it's blocked
+		"http://example.com/a":              false,
+		"https://example.com:443/a":         false,
+		"http://unresolvable.example.com/":  false,
+		"http://internal.example.com/":      true,
+		"http://127.0.0.1/":                 true,
+		"http://[::1]/":                     true,
+		"http://169.254.169.254/latest/":    true,
+		"http://localhost/":                 true,
+		"http://LOCALHOST./":                true,
+		"http://foo.localhost/":             true,
+		"http://metadata.google.internal/":  true,
+		"http://example.com:22/":            true,
+		"file:///etc/passwd":                true,
+		"gopher://example.com/":             true,
+		"http://93.184.216.34/":             false,
+		"http://0x7f000001/":                false, // not an IP literal to Go, and doesn't resolve
+		"http://[::ffff:169.254.169.254]/":  true,
+		"https://internal.example.com:443/": true,
+	}
+	for urlStr, wantBlocked := range tests {
+		u, err := url.Parse(urlStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckURL(u); (err != nil) != wantBlocked {
+			t.Errorf("%s: got error %v, want blocked %v", urlStr, err, wantBlocked)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := map[string]bool{ // address -> whether it's allowed
+		"93.184.216.34:80":  true,
+		"93.184.216.34:443": true,
+		"93.184.216.34:22":  false,
+		"10.0.0.1:80":       false,
+		"[::1]:443":         false,
+	}
+	for addr, want := range tests {
+		if err := dialControl("tcp", addr, nil); (err == nil) != want {
+			t.Errorf("%s: got error %v, want allowed %v", addr, err, want)
+		}
+	}
+}
+
+// allowLocal allows connections to local test servers during a test.
+func allowLocal(t *testing.T, s *httptest.Server) {
+	u, _ := url.Parse(s.URL)
+	origAllowIP := allowIP
+	AllowedPorts[u.Port()] = true
+	allowIP = func(ip net.IP) bool { return ip.IsLoopback() || IsPublicIP(ip) }
+	t.Cleanup(func() {
+		allowIP = origAllowIP
+		delete(AllowedPorts, u.Port())
+	})
+}
+
+func TestTransport_blocksLoopback(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer s.Close()
+
+	// Even on an allowed port, the loopback address is blocked.
+	u, _ := url.Parse(s.URL)
+	AllowedPorts[u.Port()] = true
+	defer delete(AllowedPorts, u.Port())
+
+	_, err := NewClient(&Transport{}).Get(s.URL)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Errorf("got error %v, want *BlockedError", err)
+	}
+}
+
+func TestTransport_blocksRedirects(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer s.Close()
+	allowLocal(t, s)
+
+	_, err := NewClient(&Transport{}).Get(s.URL)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Errorf("got error %v, want *BlockedError", err)
+	}
+}
+
+func TestTransport_limits(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer s.Close()
+	allowLocal(t, s)
+
+	c := NewClient(&Transport{MaxResponseSize: 10, ContentTypes: []string{"text/html"}})
+	get := func(path string) (string, error) {
+		resp, err := c.Get(s.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if body, err := get("/"); err != nil || body != "ok" {
+		t.Errorf("got body %q and error %v, want %q", body, err, "ok")
+	}
+	if _, err := get("/big"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("got error %v for large response, want ErrResponseTooLarge", err)
+	}
+	if _, err := get("/image"); err == nil || !strings.Contains(err.Error(), "content type") {
+		t.Errorf("got error %v for image, want content type error", err)
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	for size, wantErr := range map[int]bool{9: false, 10: false, 11: true} {
+		b := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader(strings.Repeat("x", size))), remaining: 10}
+		data, err := ioutil.ReadAll(b)
+		if (err != nil) != wantErr {
+			t.Errorf("size %d: got error %v, want error %v", size, err, wantErr)
+		}
+		if len(data) > 10 {
+			t.Errorf("size %d: read %d bytes, want at most 10", size, len(data))
+		}
+	}
+}
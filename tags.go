@@ -0,0 +1,55 @@
+package thesrc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A TagCount is a tag and the number of posts that have it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// MaxTags is the maximum number of tags a post may have.
+const MaxTags = 5
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#.-]{0,29}$`)
+
+// NormalizeTags lowercases tags, trims surrounding whitespace, and removes
+// empty and duplicate tags, returning the result in sorted order. It returns
+// an error if any tag contains disallowed characters or is too long, or if
+// there are more than MaxTags tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(tags))
+	var norm []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q (tags may contain up to 30 letters, digits, and '+', '#', '.', or '-')", tag)
+		}
+		if _, present := seen[tag]; present {
+			continue
+		}
+		seen[tag] = struct{}{}
+		norm = append(norm, tag)
+	}
+	if len(norm) > MaxTags {
+		return nil, fmt.Errorf("too many tags (a post may have at most %d)", MaxTags)
+	}
+	sort.Strings(norm)
+	return norm, nil
+}
+
+// SplitTags splits a string of tags separated by commas and/or whitespace
+// (as entered in a form field) into individual tags.
+func SplitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
@@ -0,0 +1,75 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{tags: nil, want: nil},
+		{tags: []string{" Go ", "postgresql", "go", ""}, want: []string{"go", "postgresql"}},
+		{tags: []string{"c++", "c#", "node.js"}, want: []string{"c#", "c++", "node.js"}},
+		{tags: []string{"has space"}, wantErr: true},
+		{tags: []string{"-leadingdash"}, wantErr: true},
+		{tags: []string{"a", "b", "c", "d", "e", "f"}, wantErr: true},
+	}
+	for _, test := range tests {
+		tags, err := NormalizeTags(test.tags)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got nil error, want error", test.tags)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: NormalizeTags returned error: %v", test.tags, err)
+			continue
+		}
+		if !reflect.DeepEqual(tags, test.want) {
+			t.Errorf("%q: got %q, want %q", test.tags, tags, test.want)
+		}
+	}
+}
+
+func TestSplitTags(t *testing.T) {
+	if got, want := SplitTags("go, postgresql  c++,"), []string{"go", "postgresql", "c++"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPostsService_ListTags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*TagCount{{Tag: "go", Count: 2}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Tags, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"PerPage": "5"})
+
+		writeJSON(w, want)
+	})
+
+	tags, err := client.Posts.ListTags(&ListOptions{PerPage: 5})
+	if err != nil {
+		t.Errorf("Posts.ListTags returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Posts.ListTags returned %+v, want %+v", tags, want)
+	}
+}
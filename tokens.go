@@ -0,0 +1,171 @@
+package thesrc
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// An APIToken is a long-lived secret that authenticates API requests as a
+// user. Unlike sessions, API tokens are intended for programmatic access (such
+// as by the thesrc command) and are explicitly created and revoked.
+type APIToken struct {
+	// ID a unique identifier for this token.
+	ID int `json:",omitempty"`
+
+	// UserID is the ID of the user that this token authenticates as.
+	UserID int
+
+	// Name is a description of what the token is used for.
+	Name string
+
+	// Token is the secret token value. It is only set in the response to
+	// creating a token; afterwards, only a hash of it is stored.
+	Token string `db:"-" json:",omitempty"`
+
+	// TokenHash is the hex-encoded SHA-256 hash of Token.
+	TokenHash string `json:"-"`
+
+	// CreatedAt is when the token was created.
+	CreatedAt time.Time
+}
+
+// TokensService interacts with the API token-related endpoints in thesrc's
+// API.
+type TokensService interface {
+	// Get an API token.
+	Get(id int) (*APIToken, error)
+
+	// List API tokens.
+	List(opt *APITokenListOptions) ([]*APIToken, error)
+
+	// Create a new API token. On success, token.ID and token.Token are set.
+	Create(token *APIToken) error
+
+	// Revoke an API token so that it may no longer be used to authenticate.
+	Revoke(id int) error
+}
+
+var (
+	ErrAPITokenNotFound = errors.New("API token not found")
+)
+
+type tokensService struct{ client *Client }
+
+func (s *tokensService) Get(id int) (*APIToken, error) {
+	url, err := s.client.url(router.APIToken, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var token *APIToken
+	_, err = s.client.Do(req, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+type APITokenListOptions struct {
+	// UserID filters the result set to only those tokens belonging to the user
+	// with this ID. The API ignores this field and always lists the
+	// authenticated user's tokens.
+	UserID int `url:",omitempty" json:",omitempty"`
+
+	ListOptions
+}
+
+func (s *tokensService) List(opt *APITokenListOptions) ([]*APIToken, error) {
+	url, err := s.client.url(router.APITokens, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*APIToken
+	_, err = s.client.Do(req, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *tokensService) Create(token *APIToken) error {
+	url, err := s.client.url(router.CreateAPIToken, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &token)
+	return err
+}
+
+func (s *tokensService) Revoke(id int) error {
+	url, err := s.client.url(router.RevokeAPIToken, map[string]string{"ID": strconv.Itoa(id)}, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+type MockTokensService struct {
+	Get_    func(id int) (*APIToken, error)
+	List_   func(opt *APITokenListOptions) ([]*APIToken, error)
+	Create_ func(token *APIToken) error
+	Revoke_ func(id int) error
+}
+
+var _ TokensService = &MockTokensService{}
+
+func (s *MockTokensService) Get(id int) (*APIToken, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(id)
+}
+
+func (s *MockTokensService) List(opt *APITokenListOptions) ([]*APIToken, error) {
+	if s.List_ == nil {
+		return nil, nil
+	}
+	return s.List_(opt)
+}
+
+func (s *MockTokensService) Create(token *APIToken) error {
+	if s.Create_ == nil {
+		return nil
+	}
+	return s.Create_(token)
+}
+
+func (s *MockTokensService) Revoke(id int) error {
+	if s.Revoke_ == nil {
+		return nil
+	}
+	return s.Revoke_(id)
+}
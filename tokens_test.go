@@ -0,0 +1,95 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestTokensService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := []*APIToken{{ID: 1, Name: "n"}}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.APITokens, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{})
+
+		writeJSON(w, want)
+	})
+
+	tokens, err := client.Tokens.List(nil)
+	if err != nil {
+		t.Errorf("Tokens.List returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	for _, tok := range want {
+		normalizeTime(&tok.CreatedAt)
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("Tokens.List returned %+v, want %+v", tokens, want)
+	}
+}
+
+func TestTokensService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &APIToken{ID: 1, Name: "n", Token: "secret"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.CreateAPIToken, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"UserID":0,"Name":"n","CreatedAt":"0001-01-01T00:00:00Z"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	token := &APIToken{Name: "n"}
+	err := client.Tokens.Create(token)
+	if err != nil {
+		t.Errorf("Tokens.Create returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("Tokens.Create returned %+v, want %+v", token, want)
+	}
+}
+
+func TestTokensService_Revoke(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.RevokeAPIToken, map[string]string{"ID": "1"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Tokens.Revoke(1)
+	if err != nil {
+		t.Errorf("Tokens.Revoke returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
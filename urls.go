@@ -0,0 +1,180 @@
+package thesrc
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// CanonicalizeURL returns the canonical form of a link URL, so that different
+// URLs of the same page are recognized as duplicates. The canonical form:
+//
+//   - always has the https scheme;
+//   - has a lowercase host without "www.", "m.", or "mobile." prefixes;
+//   - has no fragment, trailing slash, or tracking query parameters (such as
+//     utm_source), and has its other query parameters in sorted order;
+//   - follows site-specific rules for github.com (repository names are
+//     case-insensitive), youtube.com (youtu.be, embed, and shorts links are
+//     the same as watch links), and reddit.com (old.reddit.com, redd.it, and
+//     comment threads with and without subreddits and slugs are the same).
+//
+// The canonical form is only for comparing URLs, and it may not work as a
+// link (e.g., if the site doesn't support https). If linkURL isn't a valid
+// http or https URL, it is returned unchanged.
+func CanonicalizeURL(linkURL string) string {
+	u, err := url.Parse(strings.TrimSpace(linkURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return linkURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		if strings.HasPrefix(host, prefix) && strings.Contains(host[len(prefix):], ".") {
+			host = host[len(prefix):]
+			break
+		}
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	u.Scheme = "https"
+	u.User = nil
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = cleanURLPath(u.Path)
+	u.RawPath = ""
+
+	q := u.Query()
+	for key := range q {
+		if isTrackingParam(key) {
+			delete(q, key)
+		}
+	}
+
+	if canon, present := siteCanonicalizers[host]; present {
+		canon(u, q)
+	}
+
+	u.RawQuery = q.Encode() // sorted by key
+	u.ForceQuery = false
+	return u.String()
+}
+
+// cleanURLPath removes trailing slashes, duplicate slashes, and dot segments
+// from p. The root path is empty.
+func cleanURLPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	p = path.Clean(p)
+	if p == "/" || p == "." {
+		return ""
+	}
+	return p
+}
+
+// isTrackingParam returns whether a query parameter is used only to track
+// where visitors came from.
+func isTrackingParam(key string) bool {
+	if strings.HasPrefix(key, "utm_") {
+		return true
+	}
+	switch key {
+	case "fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_hsenc", "_hsmi", "ref_src":
+		return true
+	}
+	return false
+}
+
+// siteCanonicalizers are site-specific canonicalization rules, keyed by host
+// (with "www." and similar prefixes removed). They modify the URL's path and
+// query parameters (which replace its RawQuery) and may change its host.
+var siteCanonicalizers = map[string]func(u *url.URL, q url.Values){
+	"github.com":     canonicalizeGitHubURL,
+	"youtube.com":    canonicalizeYouTubeURL,
+	"youtu.be":       canonicalizeYouTubeURL,
+	"reddit.com":     canonicalizeRedditURL,
+	"old.reddit.com": canonicalizeRedditURL,
+	"new.reddit.com": canonicalizeRedditURL,
+	"np.reddit.com":  canonicalizeRedditURL,
+	"i.reddit.com":   canonicalizeRedditURL,
+	"redd.it":        canonicalizeRedditURL,
+}
+
+// canonicalizeGitHubURL lowercases user and repository names (which are
+// case-insensitive) and removes the ".git" suffix of clone URLs. Repository
+// pages' query parameters (such as "?tab=readme") are removed.
+func canonicalizeGitHubURL(u *url.URL, q url.Values) {
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(parts) >= 1 {
+		parts[0] = strings.ToLower(parts[0])
+	}
+	if len(parts) >= 2 {
+		parts[1] = strings.ToLower(strings.TrimSuffix(parts[1], ".git"))
+	}
+	if len(parts) == 2 {
+		for key := range q {
+			delete(q, key)
+		}
+	}
+	u.Path = cleanURLPath("/" + strings.Join(parts, "/"))
+}
+
+// canonicalizeYouTubeURL turns links to videos (youtu.be/ID, and
+// youtube.com/embed/ID, /shorts/ID, /v/ID, and /live/ID) into
+// youtube.com/watch?v=ID links, without other query parameters (such as
+// start times and playlists).
+func canonicalizeYouTubeURL(u *url.URL, q url.Values) {
+	var id string
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	switch {
+	case u.Host == "youtu.be" && len(parts) == 1 && parts[0] != "":
+		id = parts[0]
+	case len(parts) == 2 && (parts[0] == "embed" || parts[0] == "shorts" || parts[0] == "v" || parts[0] == "live"):
+		id = parts[1]
+	case u.Path == "/watch":
+		id = q.Get("v")
+	}
+	u.Host = "youtube.com"
+	if id == "" {
+		return
+	}
+	u.Path = "/watch"
+	for key := range q {
+		delete(q, key)
+	}
+	q.Set("v", id)
+}
+
+// canonicalizeRedditURL turns links to comment threads (with or without the
+// subreddit and the slug, and redd.it/ID) into reddit.com/comments/ID links,
+// and lowercases subreddit names. Query parameters are removed.
+func canonicalizeRedditURL(u *url.URL, q url.Values) {
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Host == "redd.it" && len(parts) == 1 && parts[0] != "" {
+		parts = []string{"comments", parts[0]}
+	}
+	if len(parts) >= 2 && parts[0] == "r" {
+		parts[1] = strings.ToLower(parts[1])
+		if len(parts) >= 4 && parts[2] == "comments" {
+			parts = parts[2:]
+		}
+	}
+	if len(parts) >= 2 && parts[0] == "comments" {
+		id := strings.ToLower(parts[1])
+		if len(parts) >= 4 {
+			// A link to a comment in the thread.
+			parts = []string{"comments", id, "_", strings.ToLower(parts[3])}
+		} else {
+			parts = []string{"comments", id}
+		}
+	}
+
+	u.Host = "reddit.com"
+	u.Path = cleanURLPath("/" + strings.Join(parts, "/"))
+	for key := range q {
+		delete(q, key)
+	}
+}
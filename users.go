@@ -0,0 +1,226 @@
+package thesrc
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+// A User is a registered user of thesrc.
+type User struct {
+	// ID a unique identifier for this user.
+	ID int `json:",omitempty"`
+
+	// Login is the user's unique username.
+	Login string
+
+	// RegisteredAt is when the user signed up.
+	RegisteredAt time.Time
+
+	// Admin is whether the user may modify and delete other users' posts.
+	Admin bool
+
+	// PasswordHash is the bcrypt hash of the user's password. It is never
+	// sent to API clients.
+	PasswordHash []byte `json:"-"`
+}
+
+// Credentials are the login and password that a user signs up or logs in
+// with.
+type Credentials struct {
+	Login    string
+	Password string
+}
+
+// A Session is an authenticated session for a user, created when the user
+// logs in.
+type Session struct {
+	// Token is the secret that authenticates requests as the session's user.
+	// It is only set in the response to logging in; afterwards, only a hash
+	// of it is stored.
+	Token string `db:"-"`
+
+	// TokenHash is the hex-encoded SHA-256 hash of Token.
+	TokenHash string `json:"-"`
+
+	// UserID is the ID of the user who logged in.
+	UserID int
+
+	// CreatedAt is when the user logged in.
+	CreatedAt time.Time
+}
+
+// UsersService interacts with the user-related endpoints in thesrc's API.
+type UsersService interface {
+	// Get a user by login.
+	Get(login string) (*User, error)
+
+	// Signup registers a new user with the given password. On success,
+	// user.ID is set to the new user's ID.
+	Signup(user *User, password string) error
+
+	// Login checks the user's login and password and, if they are correct,
+	// creates a new session for the user.
+	Login(cred *Credentials) (*Session, error)
+
+	// Logout ends the session identified by token.
+	Logout(token string) error
+
+	// Authenticate returns the user who is authenticated by token, which is
+	// either a session token or an API token.
+	Authenticate(token string) (*User, error)
+}
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrLoginTaken     = errors.New("login is already taken")
+	ErrBadCredentials = errors.New("incorrect login or password")
+	ErrBadToken       = errors.New("invalid authentication token")
+)
+
+type usersService struct{ client *Client }
+
+func (s *usersService) Get(login string) (*User, error) {
+	url, err := s.client.url(router.User, map[string]string{"Login": login}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *User
+	_, err = s.client.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *usersService) Signup(user *User, password string) error {
+	url, err := s.client.url(router.Signup, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), &Credentials{Login: user.Login, Password: password})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(req, &user)
+	return err
+}
+
+func (s *usersService) Login(cred *Credentials) (*Session, error) {
+	url, err := s.client.url(router.LogIn, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("POST", url.String(), cred)
+	if err != nil {
+		return nil, err
+	}
+
+	var sess *Session
+	_, err = s.client.Do(req, &sess)
+	if err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+func (s *usersService) Logout(token string) error {
+	url, err := s.client.url(router.LogOut, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.client.NewRequest("DELETE", url.String(), nil)
+	if err != nil {
+		return err
+	}
+	setBearerToken(req, token)
+
+	_, err = s.client.Do(req, nil)
+	return err
+}
+
+func (s *usersService) Authenticate(token string) (*User, error) {
+	url, err := s.client.url(router.AuthenticatedUser, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	setBearerToken(req, token)
+
+	var user *User
+	_, err = s.client.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// setBearerToken sets the Authorization header of req to authenticate with
+// token.
+func setBearerToken(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+type MockUsersService struct {
+	Get_          func(login string) (*User, error)
+	Signup_       func(user *User, password string) error
+	Login_        func(cred *Credentials) (*Session, error)
+	Logout_       func(token string) error
+	Authenticate_ func(token string) (*User, error)
+}
+
+var _ UsersService = &MockUsersService{}
+
+func (s *MockUsersService) Get(login string) (*User, error) {
+	if s.Get_ == nil {
+		return nil, nil
+	}
+	return s.Get_(login)
+}
+
+func (s *MockUsersService) Signup(user *User, password string) error {
+	if s.Signup_ == nil {
+		return nil
+	}
+	return s.Signup_(user, password)
+}
+
+func (s *MockUsersService) Login(cred *Credentials) (*Session, error) {
+	if s.Login_ == nil {
+		return nil, nil
+	}
+	return s.Login_(cred)
+}
+
+func (s *MockUsersService) Logout(token string) error {
+	if s.Logout_ == nil {
+		return nil
+	}
+	return s.Logout_(token)
+}
+
+func (s *MockUsersService) Authenticate(token string) (*User, error) {
+	if s.Authenticate_ == nil {
+		return nil, nil
+	}
+	return s.Authenticate_(token)
+}
@@ -0,0 +1,154 @@
+package thesrc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/thesrc/router"
+)
+
+func TestUsersService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "u"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.User, map[string]string{"Login": "u"}), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+
+		writeJSON(w, want)
+	})
+
+	user, err := client.Users.Get("u")
+	if err != nil {
+		t.Errorf("Users.Get returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.Get returned %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersService_Signup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "u"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.Signup, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Login":"u","Password":"p"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	user := &User{Login: "u"}
+	err := client.Users.Signup(user, "p")
+	if err != nil {
+		t.Errorf("Users.Signup returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.Signup returned %+v, want %+v", user, want)
+	}
+}
+
+func TestUsersService_Login(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &Session{Token: "t", UserID: 1}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.LogIn, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "POST")
+		testBody(t, r, `{"Login":"u","Password":"p"}`+"\n")
+
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, want)
+	})
+
+	sess, err := client.Users.Login(&Credentials{Login: "u", Password: "p"})
+	if err != nil {
+		t.Errorf("Users.Login returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.CreatedAt)
+	if !reflect.DeepEqual(sess, want) {
+		t.Errorf("Users.Login returned %+v, want %+v", sess, want)
+	}
+}
+
+func TestUsersService_Logout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.LogOut, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "DELETE")
+		testHeader(t, r, "Authorization", "Bearer t")
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.Users.Logout("t")
+	if err != nil {
+		t.Errorf("Users.Logout returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+}
+
+func TestUsersService_Authenticate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	want := &User{ID: 1, Login: "u"}
+
+	var called bool
+	mux.HandleFunc(urlPath(t, router.AuthenticatedUser, nil), func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		testMethod(t, r, "GET")
+		testHeader(t, r, "Authorization", "Bearer t")
+
+		writeJSON(w, want)
+	})
+
+	user, err := client.Users.Authenticate("t")
+	if err != nil {
+		t.Errorf("Users.Authenticate returned error: %v", err)
+	}
+
+	if !called {
+		t.Fatal("!called")
+	}
+
+	normalizeTime(&want.RegisteredAt)
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("Users.Authenticate returned %+v, want %+v", user, want)
+	}
+}
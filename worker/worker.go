@@ -0,0 +1,255 @@
+// Package worker runs background jobs from the datastore's job queue (see
+// datastore.JobsStore), such as classifying newly submitted posts.
+package worker
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/linkcheck"
+)
+
+// A Handler runs a job. If it returns an error, the job is retried later (or
+// given up on, after too many attempts).
+type Handler func(store *datastore.Datastore, job *datastore.Job) error
+
+// DefaultHandlers are the handlers for each kind of job.
+var DefaultHandlers = map[string]Handler{
+	datastore.JobClassifyPost: classifyPost,
+	datastore.JobCheckLink:    checkLink,
+}
+
+// A Pool is a pool of workers that claim jobs from a datastore's job queue
+// and run them. Several pools (in different processes) may share a job
+// queue.
+type Pool struct {
+	Store *datastore.Datastore
+
+	// Workers is the number of jobs to run concurrently (default 1).
+	Workers int
+
+	// PollInterval is how long an idle worker waits before checking for new
+	// jobs (default 5 seconds).
+	PollInterval time.Duration
+
+	// LockFor is how long a worker has exclusive access to a job that it
+	// claimed (default 5 minutes). If the worker hasn't finished the job by
+	// then, another worker may claim it.
+	LockFor time.Duration
+
+	// MaxAttempts is the number of times a job is attempted before it is
+	// given up on (default 5).
+	MaxAttempts int
+
+	// Backoff returns how long to wait before retrying a job that has failed
+	// attempts times (default: 30 seconds, doubling with each attempt, up to
+	// an hour).
+	Backoff func(attempts int) time.Duration
+
+	// Handlers are the handlers for each kind of job (default
+	// DefaultHandlers).
+	Handlers map[string]Handler
+
+	// LinkCheckInterval, if nonzero, is how often posts' links are checked
+	// (to detect dead links). While the pool is running, it periodically
+	// enqueues JobCheckLink jobs for the posts whose links haven't been
+	// checked in this long.
+	LinkCheckInterval time.Duration
+}
+
+// Backoff is the default Pool.Backoff.
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// Run runs p's workers until stop is closed, and then waits for them to
+// finish the jobs they are running.
+func (p *Pool) Run(stop <-chan struct{}) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	pollInterval := p.PollInterval
+	if pollInterval == 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	var wg sync.WaitGroup
+	if p.LinkCheckInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.scheduleLinkChecks(stop)
+		}()
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ran, err := p.RunOne()
+				if err != nil {
+					log.Printf("Error running job: %s. (Continuing...)", err)
+				}
+				if ran && err == nil {
+					select {
+					case <-stop:
+						return
+					default:
+						continue
+					}
+				}
+				select {
+				case <-stop:
+					return
+				case <-time.After(pollInterval):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// linkCheckBatchSize is the maximum number of JobCheckLink jobs that
+// EnqueueLinkChecks enqueues at once, so that link checks don't crowd out
+// other jobs.
+const linkCheckBatchSize = 100
+
+// scheduleLinkChecks enqueues JobCheckLink jobs for posts whose links are due
+// for a check (see EnqueueLinkChecks) every few minutes, until stop is
+// closed.
+func (p *Pool) scheduleLinkChecks(stop <-chan struct{}) {
+	for {
+		if _, err := EnqueueLinkChecks(p.Store, time.Now().Add(-p.LinkCheckInterval)); err != nil {
+			log.Printf("Error scheduling link checks: %s. (Continuing...)", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(5 * time.Minute):
+		}
+	}
+}
+
+// EnqueueLinkChecks enqueues JobCheckLink jobs for up to linkCheckBatchSize
+// posts whose links haven't been checked since checkedBefore. It returns the
+// number of jobs enqueued.
+func EnqueueLinkChecks(store *datastore.Datastore, checkedBefore time.Time) (int, error) {
+	posts, err := store.LinkChecks.ListDue(checkedBefore, linkCheckBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, post := range posts {
+		if err := store.Jobs.Enqueue(&datastore.Job{Kind: datastore.JobCheckLink, PostID: post.ID}); err != nil {
+			return i, err
+		}
+	}
+	return len(posts), nil
+}
+
+// RunOne claims a job and runs it. It returns whether there was a job to
+// run, and any error from the job queue (not from the job itself, which is
+// recorded in the queue).
+func (p *Pool) RunOne() (bool, error) {
+	lockFor := p.LockFor
+	if lockFor == 0 {
+		lockFor = 5 * time.Minute
+	}
+	job, err := p.Store.Jobs.Claim(lockFor)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	handlers := p.Handlers
+	if handlers == nil {
+		handlers = DefaultHandlers
+	}
+	h, present := handlers[job.Kind]
+	if !present {
+		return true, p.Store.Jobs.Fail(job.ID, fmt.Errorf("unknown job kind %q", job.Kind))
+	}
+
+	if jobErr := h(p.Store, job); jobErr != nil {
+		maxAttempts := p.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = 5
+		}
+		if job.Attempts >= maxAttempts {
+			log.Printf("Job %d (%s of post %d) failed %d times; giving up: %s.", job.ID, job.Kind, job.PostID, job.Attempts, jobErr)
+			return true, p.Store.Jobs.Fail(job.ID, jobErr)
+		}
+		backoff := p.Backoff
+		if backoff == nil {
+			backoff = Backoff
+		}
+		return true, p.Store.Jobs.Retry(job.ID, jobErr, time.Now().Add(backoff(job.Attempts)))
+	}
+	return true, p.Store.Jobs.Complete(job.ID)
+}
+
+// classifyPost fetches the job's post's link, stores the link's metadata, and
+// classifies the post with the default classifier.
+func classifyPost(store *datastore.Datastore, job *datastore.Job) error {
+	post, err := store.Posts.Get(job.PostID)
+	if err == thesrc.ErrPostNotFound {
+		// The post was deleted, so there's nothing to do.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if post.LinkURL == "" {
+		return nil
+	}
+
+	page, err := classifier.FetchPage(post.LinkURL)
+	if err != nil {
+		return err
+	}
+	post.Metadata = classifier.ExtractMetadata(page)
+	post.Metadata.FetchedAt = time.Now().UTC()
+
+	if !post.ClassificationConfirmed {
+		res, err := classifier.Get(classifier.DefaultName).Classify(post, page)
+		if err != nil {
+			return err
+		}
+		if res != nil {
+			post.Classification = res.Label
+			post.ClassificationScore = res.Confidence
+			post.Languages = res.Features.Languages
+		}
+	}
+	// Only store the classifier's results, because the post may have
+	// changed (e.g., been voted on) while its link was being fetched.
+	if err := store.Classifications.Record(post); err != thesrc.ErrPostNotFound {
+		return err
+	}
+	return nil
+}
+
+// checkLink checks whether the job's post's link still works and records the
+// result. Broken links are results, not errors, so they aren't retried.
+func checkLink(store *datastore.Datastore, job *datastore.Job) error {
+	post, err := store.Posts.Get(job.PostID)
+	if err == thesrc.ErrPostNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if post.LinkURL == "" {
+		return nil
+	}
+	return store.LinkChecks.Record(linkcheck.Check(post))
+}
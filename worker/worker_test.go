@@ -0,0 +1,222 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sourcegraph.com/sourcegraph/thesrc"
+	"sourcegraph.com/sourcegraph/thesrc/classifier"
+	"sourcegraph.com/sourcegraph/thesrc/datastore"
+	"sourcegraph.com/sourcegraph/thesrc/linkcheck"
+)
+
+func init() {
+	// Allow fetching pages from the (local) test server, and don't cache
+	// them on disk.
+	classifier.Transport = http.DefaultTransport
+	classifier.HTTPCacheDir = ""
+	linkcheck.Transport = http.DefaultTransport
+}
+
+func TestPool_RunOne(t *testing.T) {
+	store := datastore.NewMemoryDatastore()
+	var calls int
+	p := &Pool{
+		Store:       store,
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+		Handlers: map[string]Handler{
+			"ok":   func(*datastore.Datastore, *datastore.Job) error { calls++; return nil },
+			"fail": func(*datastore.Datastore, *datastore.Job) error { calls++; return errors.New("x") },
+		},
+	}
+
+	if ran, err := p.RunOne(); ran || err != nil {
+		t.Errorf("with no jobs, got ran=%v, err=%v, want false and nil", ran, err)
+	}
+
+	for _, kind := range []string{"ok", "fail", "unknown"} {
+		if err := store.Jobs.Enqueue(&datastore.Job{Kind: kind}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if ran, err := p.RunOne(); !ran || err != nil {
+			t.Fatalf("run %d: got ran=%v, err=%v, want true and nil", i, ran, err)
+		}
+	}
+	if ran, _ := p.RunOne(); ran {
+		t.Error("ran a job after all jobs completed or failed")
+	}
+	if want := 3; calls != want {
+		t.Errorf("got %d handler calls, want %d (1 ok and 2 attempts of fail)", calls, want)
+	}
+
+	jobs, err := store.Jobs.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	for _, job := range jobs {
+		if job.Failed {
+			failed = append(failed, job.Kind)
+		}
+	}
+	if len(jobs) != 2 || len(failed) != 2 {
+		t.Errorf("got jobs %+v, want only the failed fail and unknown jobs", jobs)
+	}
+}
+
+func TestPool_Run(t *testing.T) {
+	store := datastore.NewMemoryDatastore()
+	done := make(chan struct{})
+	p := &Pool{
+		Store:        store,
+		Workers:      2,
+		PollInterval: time.Millisecond,
+		Handlers: map[string]Handler{
+			"ok": func(*datastore.Datastore, *datastore.Job) error { done <- struct{}{}; return nil },
+		},
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(stop)
+		close(stopped)
+	}()
+
+	// Jobs enqueued while the pool is running are run.
+	if err := store.Jobs.Enqueue(&datastore.Job{Kind: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not run")
+	}
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after stop was closed")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 10: time.Hour} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d): got %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestClassifyPost(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<head><meta property="og:description" content="d"></head><body><pre class="language-go">`+strings.Repeat("x := 1\n", 100)+`</pre></body>`)
+	}))
+	defer s.Close()
+
+	store := datastore.NewMemoryDatastore()
+	post := &thesrc.Post{LinkURL: s.URL}
+	if _, err := store.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Pool{Store: store}
+	if ran, err := p.RunOne(); !ran || err != nil {
+		t.Fatalf("got ran=%v, err=%v, want true and nil", ran, err)
+	}
+	if jobs, _ := store.Jobs.List(); len(jobs) != 0 {
+		t.Errorf("got jobs %+v after classifying, want none", jobs)
+	}
+
+	post, err := store.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Classification != thesrc.ClassificationCode || post.ClassificationScore <= 0.5 {
+		t.Errorf("got classification %q (score %v), want %s", post.Classification, post.ClassificationScore, thesrc.ClassificationCode)
+	}
+	if len(post.Languages) != 1 || post.Languages[0] != "go" {
+		t.Errorf("got languages %q, want [go]", post.Languages)
+	}
+	if post.Metadata == nil || post.Metadata.Description != "d" || post.Metadata.FinalURL != s.URL {
+		t.Errorf("got metadata %+v, want description %q and final URL %q", post.Metadata, "d", s.URL)
+	}
+}
+
+func TestClassifyPost_voteDuringFetch(t *testing.T) {
+	store := datastore.NewMemoryDatastore()
+	post := &thesrc.Post{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Vote on the post while the worker is fetching its link.
+		if err := store.Posts.Vote(&thesrc.Vote{PostID: post.ID, UserID: 1}); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `<body><pre>`+strings.Repeat("x := 1\n", 100)+`</pre></body>`)
+	}))
+	defer s.Close()
+
+	post.LinkURL = s.URL
+	if _, err := store.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Pool{Store: store}
+	if ran, err := p.RunOne(); !ran || err != nil {
+		t.Fatalf("got ran=%v, err=%v, want true and nil", ran, err)
+	}
+
+	post, err := store.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Score != 1 {
+		t.Errorf("got score %d, want 1 (the vote during classification)", post.Score)
+	}
+	if post.Classification != thesrc.ClassificationCode {
+		t.Errorf("got classification %q, want %s", post.Classification, thesrc.ClassificationCode)
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	store := datastore.NewMemoryDatastore()
+	post := &thesrc.Post{LinkURL: s.URL}
+	if _, err := store.Posts.Submit(post); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Pool{Store: store, Handlers: map[string]Handler{datastore.JobCheckLink: checkLink}}
+	p.RunOne() // the classification job, which has no handler
+
+	if n, err := EnqueueLinkChecks(store, time.Now()); n != 1 || err != nil {
+		t.Fatalf("got %d link check jobs enqueued (err=%v), want 1", n, err)
+	}
+	if n, _ := EnqueueLinkChecks(store, time.Now()); n != 0 {
+		t.Errorf("got %d link check jobs enqueued again, want 0 (the job is pending)", n)
+	}
+	if ran, err := p.RunOne(); !ran || err != nil {
+		t.Fatalf("got ran=%v, err=%v, want true and nil", ran, err)
+	}
+
+	post, err := store.Posts.Get(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.LinkCheck == nil || !post.LinkCheck.Dead || post.LinkCheck.StatusCode != http.StatusNotFound {
+		t.Errorf("got link check %+v, want dead with HTTP 404", post.LinkCheck)
+	}
+	if n, _ := EnqueueLinkChecks(store, time.Now().Add(-time.Hour)); n != 0 {
+		t.Errorf("got %d link check jobs enqueued after checking, want 0", n)
+	}
+}